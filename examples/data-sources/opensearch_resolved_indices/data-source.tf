@@ -0,0 +1,34 @@
+data "opensearch_resolved_indices" "windows" {
+  pattern = "windows-*"
+}
+
+resource "opensearch_sa_detector" "windows" {
+  body = <<EOF
+{
+  "name": "windows-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "Windows event logs",
+        "indices": $${indices},
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+
+  body_vars = {
+    indices = jsonencode(data.opensearch_resolved_indices.windows.indices)
+  }
+}
@@ -0,0 +1,22 @@
+data "opensearch_sa_capabilities" "cluster" {}
+
+# Only created on clusters supporting correlation rules
+resource "opensearch_sa_correlation_rule" "failed_logins" {
+  count = data.opensearch_sa_capabilities.cluster.correlation ? 1 : 0
+
+  name = "failed-logins"
+
+  query {
+    index    = "windows"
+    category = "windows"
+    query    = "EventID:4625"
+    field    = "source.ip"
+  }
+
+  query {
+    index    = "cloudtrail"
+    category = "cloudtrail"
+    query    = "eventName:ConsoleLogin"
+    field    = "sourceIPAddress"
+  }
+}
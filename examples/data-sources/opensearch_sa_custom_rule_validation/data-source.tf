@@ -0,0 +1,13 @@
+data "opensearch_sa_custom_rule_validation" "access_denied" {
+  category = "cloudtrail"
+  index    = "cloudtrail"
+  body     = file("${path.module}/rules/access_denied.yml")
+}
+
+# Fail the plan rather than submitting a rule the cluster would reject.
+check "access_denied_rule" {
+  assert {
+    condition     = data.opensearch_sa_custom_rule_validation.access_denied.valid
+    error_message = join("\n", [for e in data.opensearch_sa_custom_rule_validation.access_denied.errors : "${e.field}: ${e.message}"])
+  }
+}
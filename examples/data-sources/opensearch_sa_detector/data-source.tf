@@ -0,0 +1,41 @@
+data "opensearch_sa_detector" "template" {
+  name = "windows-detector"
+}
+
+# A copy of the detector reading another index
+resource "opensearch_sa_detector" "copy" {
+  body = jsonencode(merge(jsondecode(data.opensearch_sa_detector.template.body), {
+    name = "windows-detector-eu"
+    inputs = [{
+      detector_input = merge(jsondecode(data.opensearch_sa_detector.template.body).inputs[0].detector_input, {
+        indices = ["windows-eu"]
+      })
+    }]
+  }))
+}
+
+# Read access to the findings of the detector type, which the plugin writes to
+# indices named after the detector type
+resource "opensearch_role" "findings_reader" {
+  role_name = "windows-findings-reader"
+
+  index_permissions {
+    index_patterns  = ["${data.opensearch_sa_detector.template.findings_index}*"]
+    allowed_actions = ["read"]
+  }
+}
+
+# A backup of the detector, to be applied to another cluster where the custom
+# rule has another ID
+data "opensearch_sa_detector" "backup" {
+  name = "windows-detector"
+
+  custom_rule_id_map = {
+    "Ayr6sY8BdD9Pn5eg1hfZ" = "bkN7sY8BdD9Pn5egXyQa"
+  }
+}
+
+resource "local_file" "windows_detector_backup" {
+  filename = "${path.module}/backups/windows-detector.json"
+  content  = data.opensearch_sa_detector.backup.export_body
+}
@@ -0,0 +1,8 @@
+data "opensearch_sa_detector_rule_map" "all" {}
+
+output "detector_rules" {
+  value = {
+    for detector in data.opensearch_sa_detector_rule_map.all.detectors :
+    detector.name => [for rule in concat(detector.custom_rules, detector.pre_packaged_rules) : rule.title]
+  }
+}
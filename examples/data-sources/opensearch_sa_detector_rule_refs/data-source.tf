@@ -0,0 +1,25 @@
+data "opensearch_sa_detector_rule_refs" "windows" {
+  rule_ids = [
+    opensearch_sa_custom_rule.suspicious_logon.id,
+    # a pre-packaged rule, by the id of its Sigma document
+    "9c14c9fa-1a63-4a64-8e57-d19280559490",
+  ]
+}
+
+resource "opensearch_sa_detector" "windows" {
+  body = jsonencode({
+    name          = "windows"
+    detector_type = "windows"
+    enabled       = true
+    schedule      = { period = { interval = 1, unit = "MINUTES" } }
+    inputs = [{
+      detector_input = {
+        description        = ""
+        indices            = ["windows"]
+        custom_rules       = data.opensearch_sa_detector_rule_refs.windows.custom_rules
+        pre_packaged_rules = data.opensearch_sa_detector_rule_refs.windows.pre_packaged_rules
+      }
+    }]
+    triggers = []
+  })
+}
@@ -0,0 +1,5 @@
+data "opensearch_sa_detector_types" "all" {}
+
+output "detectors_per_type" {
+  value = { for type in data.opensearch_sa_detector_types.all.types : type.name => type.count }
+}
@@ -0,0 +1,8 @@
+data "opensearch_sa_detectors" "all" {}
+
+# Write the import blocks of every detector of the cluster, then run
+# terraform plan -generate-config-out=detectors.tf
+resource "local_file" "detector_imports" {
+  filename = "${path.module}/imports.tf"
+  content  = data.opensearch_sa_detectors.all.import_blocks
+}
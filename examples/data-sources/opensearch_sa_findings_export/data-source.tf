@@ -0,0 +1,5 @@
+data "opensearch_sa_findings_export" "audit" {
+  detector_id = opensearch_sa_detector.windows.id
+  filename    = "${path.module}/findings.json"
+  start_time  = "2024-06-01T00:00:00Z"
+}
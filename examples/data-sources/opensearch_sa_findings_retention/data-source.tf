@@ -0,0 +1,5 @@
+data "opensearch_sa_findings_retention" "current" {}
+
+output "findings_retention_period" {
+  value = data.opensearch_sa_findings_retention.current.retention_period
+}
@@ -0,0 +1,3 @@
+data "opensearch_sa_prepackaged_rule_by_sigma_id" "root_login" {
+  sigma_id = "8ad1600d-e9dc-4251-b0ee-a65268f29add"
+}
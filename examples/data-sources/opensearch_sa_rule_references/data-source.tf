@@ -0,0 +1,11 @@
+data "opensearch_sa_rule_references" "suspicious_logon" {
+  rule_id = opensearch_sa_custom_rule.suspicious_logon.id
+}
+
+# Fails the plan of a CI check while detectors still use the rule
+check "rule_unused" {
+  assert {
+    condition     = length(data.opensearch_sa_rule_references.suspicious_logon.detector_ids) == 0
+    error_message = "The rule is used by the detectors ${join(", ", [for d in data.opensearch_sa_rule_references.suspicious_logon.detectors : d.name])}."
+  }
+}
@@ -0,0 +1,15 @@
+data "opensearch_sa_sigma_rules" "upstream" {
+  root_path = "${path.module}/sigma/rules"
+
+  categories = {
+    "cloud/aws/cloudtrail" = "cloudtrail"
+    "windows"              = "windows"
+  }
+}
+
+resource "opensearch_sa_custom_rule" "sigma" {
+  for_each = { for rule in data.opensearch_sa_sigma_rules.upstream.rules : rule.path => rule }
+
+  category = each.value.category
+  body     = each.value.body
+}
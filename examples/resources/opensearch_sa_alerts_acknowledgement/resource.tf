@@ -0,0 +1,18 @@
+# Acknowledge specific alerts.
+resource "opensearch_sa_alerts_acknowledgement" "reviewed" {
+  detector_id = opensearch_sa_detector.windows.id
+  alert_ids   = ["opH7sY8BdD9Pn5egMhfZ", "o5H7sY8BdD9Pn5egMhfZ"]
+}
+
+# Acknowledge every active high severity alert raised during a campaign
+# confirmed to be benign. The fixed end_time keeps the alerts matched the same
+# whenever the configuration is applied.
+resource "opensearch_sa_alerts_acknowledgement" "pentest" {
+  detector_id = opensearch_sa_detector.windows.id
+
+  filter {
+    severity   = "1"
+    start_time = "2024-06-03T08:00:00Z"
+    end_time   = "2024-06-07T18:00:00Z"
+  }
+}
@@ -0,0 +1 @@
+terraform import opensearch_sa_correlation_rule.logon_then_cloud Xyr6sY8BdD9Pn5eg1hfZ
@@ -0,0 +1,21 @@
+# Correlate failed Windows logons with CloudTrail activity from the same
+# source address within ten minutes.
+resource "opensearch_sa_correlation_rule" "logon_then_cloud" {
+  name        = "failed-logon-then-cloud-activity"
+  time_window = 600000
+
+  query {
+    index    = "windows"
+    category = "windows"
+    query    = "EventID:4625"
+    field    = "IpAddress"
+  }
+
+  query {
+    index    = "cloudtrail"
+    category = "cloudtrail"
+    field    = "sourceIPAddress"
+  }
+
+  depends_on = [opensearch_sa_detector.windows, opensearch_sa_detector.cloudtrail]
+}
@@ -0,0 +1,4 @@
+terraform import opensearch_sa_detector.windows Ayr6sY8BdD9Pn5eg1hfZ
+
+# Detectors can also be imported by name, as long as no other detector has it
+terraform import opensearch_sa_detector.windows name:windows-detector
@@ -0,0 +1,107 @@
+# The body is compared with the detector read back from the cluster after
+# removing the fields managed by the server (type, user, timestamps, trigger
+# IDs, monitor and index metadata). Authoring the body in the shape below keeps
+# plans empty, including right after an import.
+resource "opensearch_sa_detector" "windows" {
+  body = <<EOF
+{
+  "name": "windows-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "Windows event logs",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": [
+    {
+      "name": "windows-trigger",
+      "severity": "1",
+      "types": ["windows"],
+      "ids": [],
+      "sev_levels": [],
+      "tags": [],
+      "actions": []
+    }
+  ]
+}
+EOF
+}
+
+# Detectors cannot be enabled for a time window only. Restricting the hours and
+# days of schedule_cron runs them during that window instead, here every five
+# minutes during business hours.
+resource "opensearch_sa_detector" "windows_business_hours" {
+  schedule_cron     = "*/5 9-17 * * 1-5"
+  schedule_timezone = "Europe/Paris"
+
+  body = <<EOF
+{
+  "name": "windows-business-hours-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "Windows event logs",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+}
+
+# Trigger criteria can be set through trigger blocks instead of the body. The
+# trigger of the body with the same name keeps its actions.
+resource "opensearch_sa_detector" "windows_critical" {
+  trigger {
+    name          = "critical-rules"
+    severity      = "1"
+    rule_severity = ["high", "critical"]
+  }
+
+  body = <<EOF
+{
+  "name": "windows-critical-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "Windows event logs",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": [
+    {
+      "name": "critical-rules",
+      "actions": []
+    }
+  ]
+}
+EOF
+}
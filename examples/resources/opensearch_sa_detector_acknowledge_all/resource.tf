@@ -0,0 +1,5 @@
+# Acknowledge every active alert of the detector once the incident is closed.
+# Run terraform apply -replace to acknowledge the alerts raised since.
+resource "opensearch_sa_detector_acknowledge_all" "cleanup" {
+  detector_id = opensearch_sa_detector.windows.id
+}
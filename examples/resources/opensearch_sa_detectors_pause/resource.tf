@@ -0,0 +1,5 @@
+# Disable every enabled detector during maintenance. Removing the resource
+# (or running terraform destroy -target) enables them again.
+resource "opensearch_sa_detectors_pause" "maintenance" {
+  count = var.maintenance ? 1 : 0
+}
@@ -0,0 +1 @@
+terraform import opensearch_sa_findings_index_template.cloudtrail opensearch-sap-cloudtrail-findings
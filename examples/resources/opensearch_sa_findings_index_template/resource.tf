@@ -0,0 +1,30 @@
+# Keep the findings indices of CloudTrail detectors on a single node cluster
+resource "opensearch_sa_findings_index_template" "cloudtrail" {
+  log_type = "cloudtrail"
+  settings = jsonencode({
+    number_of_replicas = 0
+  })
+}
+
+resource "opensearch_sa_detector" "cloudtrail" {
+  body = jsonencode({
+    name          = "cloudtrail-detector"
+    detector_type = opensearch_sa_findings_index_template.cloudtrail.log_type
+    enabled       = true
+    schedule = {
+      period = {
+        interval = 1
+        unit     = "MINUTES"
+      }
+    }
+    inputs = [{
+      detector_input = {
+        description        = ""
+        indices            = ["cloudtrail"]
+        custom_rules       = []
+        pre_packaged_rules = []
+      }
+    }]
+    triggers = []
+  })
+}
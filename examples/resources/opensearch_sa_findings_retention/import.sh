@@ -0,0 +1 @@
+terraform import opensearch_sa_findings_retention.this sa-findings-retention
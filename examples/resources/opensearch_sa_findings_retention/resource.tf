@@ -0,0 +1,5 @@
+# Keep findings for 90 days, in daily history indices
+resource "opensearch_sa_findings_retention" "this" {
+  max_age          = "1d"
+  retention_period = "90d"
+}
@@ -0,0 +1 @@
+terraform import opensearch_sa_settings.this sa-settings
@@ -0,0 +1,8 @@
+# Keep alerts for 30 days and only show users the detectors of their backend
+# roles
+resource "opensearch_sa_settings" "this" {
+  settings = {
+    "plugins.security_analytics.alert_history_retention_period" = "30d"
+    "plugins.security_analytics.filter_by_backend_roles"        = "true"
+  }
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/olivere/elastic/uritemplates"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+func dataSourceOpensearchResolvedIndices() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_resolved_indices` resolves index names, aliases, data streams and wildcard patterns to the concrete indices they designate, through the resolve index API, for example to set the `indices` of a security analytics detector input from an index inventory that changes over time. Reading the data source fails when nothing matches.",
+		Read:        dataSourceOpensearchResolvedIndicesRead,
+
+		Schema: map[string]*schema.Schema{
+			"pattern": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "An index name, alias, data stream or wildcard pattern such as `logs-*`, or a comma-separated list of them.",
+			},
+			"expand_wildcards": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "open",
+				Description:  "Which indices wildcard patterns match: `open`, `closed`, `hidden`, `all` or `none`.",
+				ValidateFunc: validation.StringInSlice([]string{"open", "closed", "hidden", "all", "none"}, false),
+			},
+			"indices": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The concrete indices matched, including those behind aliases and the backing indices of data streams, sorted by name.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchResolvedIndicesRead(d *schema.ResourceData, m interface{}) error {
+	pattern := d.Get("pattern").(string)
+
+	path, err := uritemplates.Expand("/_resolve/index/{name}", map[string]string{
+		"name": pattern,
+	})
+	if err != nil {
+		return fmt.Errorf("error building URL path for index resolution: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return err
+	}
+	res, err := osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
+		Method: "GET",
+		Path:   path,
+		Params: url.Values{"expand_wildcards": []string{d.Get("expand_wildcards").(string)}},
+	})
+	// names that are not patterns fail to resolve when they do not exist
+	if elastic7.IsNotFound(err) {
+		return fmt.Errorf("no index matches %q", pattern)
+	}
+	if err != nil {
+		return err
+	}
+
+	indices, err := resolvedIndexNames(res.Body)
+	if err != nil {
+		return err
+	}
+	if len(indices) == 0 {
+		return fmt.Errorf("no index matches %q", pattern)
+	}
+
+	d.SetId(pattern)
+	return d.Set("indices", indices)
+}
+
+// resolvedIndexNames returns the sorted concrete indices of a resolve index
+// response, whether they were matched directly or through an alias or data
+// stream.
+func resolvedIndexNames(body json.RawMessage) ([]string, error) {
+	var resolved struct {
+		Indices []struct {
+			Name string `json:"name"`
+		} `json:"indices"`
+		Aliases []struct {
+			Indices []string `json:"indices"`
+		} `json:"aliases"`
+		DataStreams []struct {
+			BackingIndices []string `json:"backing_indices"`
+		} `json:"data_streams"`
+	}
+	if err := json.Unmarshal(body, &resolved); err != nil {
+		return nil, fmt.Errorf("error unmarshalling resolved indices: %+v: %+v", err, body)
+	}
+
+	seen := make(map[string]bool)
+	for _, index := range resolved.Indices {
+		seen[index.Name] = true
+	}
+	for _, alias := range resolved.Aliases {
+		for _, index := range alias.Indices {
+			seen[index] = true
+		}
+	}
+	for _, dataStream := range resolved.DataStreams {
+		for _, index := range dataStream.BackingIndices {
+			seen[index] = true
+		}
+	}
+
+	indices := make([]string, 0, len(seen))
+	for index := range seen {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchDataSourceResolvedIndices(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchDataSourceResolvedIndices,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.opensearch_resolved_indices.logs", "indices.#", "2"),
+					resource.TestCheckResourceAttr("data.opensearch_resolved_indices.logs", "indices.0", "resolved-logs-a"),
+					resource.TestCheckResourceAttr("data.opensearch_resolved_indices.logs", "indices.1", "resolved-logs-b"),
+				),
+			},
+			{
+				Config:      testAccOpensearchDataSourceResolvedIndicesNoMatch,
+				ExpectError: regexp.MustCompile(`no index matches "resolved-missing-\*"`),
+			},
+		},
+	})
+}
+
+func TestResolvedIndexNames(t *testing.T) {
+	indices, err := resolvedIndexNames([]byte(`{
+  "indices": [{"name": "logs-b", "attributes": ["open"]}, {"name": "logs-a", "aliases": ["logs"], "attributes": ["open"]}],
+  "aliases": [{"name": "logs", "indices": ["logs-a", "logs-c"]}],
+  "data_streams": [{"name": "events", "backing_indices": [".ds-events-000001"], "timestamp_field": "@timestamp"}]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".ds-events-000001", "logs-a", "logs-b", "logs-c"}
+	if !reflect.DeepEqual(indices, expected) {
+		t.Errorf("expected %v, got %v", expected, indices)
+	}
+}
+
+func TestResolvedIndicesRead(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_resolve/index/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"indices":[{"name":"logs-a"}],"aliases":[],"data_streams":[]}`))
+	}))
+	defer server.Close()
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchResolvedIndices().Schema, map[string]interface{}{
+		"pattern": "logs-*",
+	})
+	if err := dataSourceOpensearchResolvedIndicesRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	if requestURI != "/_resolve/index/logs-%2A?expand_wildcards=open" {
+		t.Errorf("unexpected request %s", requestURI)
+	}
+	if indices := d.Get("indices").([]interface{}); !reflect.DeepEqual(indices, []interface{}{"logs-a"}) {
+		t.Errorf("expected logs-a, got %v", indices)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceOpensearchResolvedIndices().Schema, map[string]interface{}{
+		"pattern": "missing",
+	})
+	if err := dataSourceOpensearchResolvedIndicesRead(d, conf); err == nil || err.Error() != `no index matches "missing"` {
+		t.Errorf("expected no index to match, got %v", err)
+	}
+}
+
+var testAccOpensearchDataSourceResolvedIndices = `
+resource "opensearch_index" "resolved_a" {
+  name               = "resolved-logs-a"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_index" "resolved_b" {
+  name               = "resolved-logs-b"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+data "opensearch_resolved_indices" "logs" {
+  pattern = "resolved-logs-*"
+
+  depends_on = [opensearch_index.resolved_a, opensearch_index.resolved_b]
+}
+`
+
+var testAccOpensearchDataSourceResolvedIndicesNoMatch = `
+data "opensearch_resolved_indices" "missing" {
+  pattern = "resolved-missing-*"
+}
+`
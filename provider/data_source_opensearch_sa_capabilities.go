@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	// the component name the security analytics plugin is listed with
+	saPluginComponent = "opensearch-security-analytics"
+	// the versions the correlation engine and custom log types were released in
+	saCorrelationMinVersion    = "2.7.0"
+	saCustomLogTypesMinVersion = "2.11.0"
+)
+
+func dataSourceOpensearchSaCapabilities() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_capabilities` tells which security analytics features the cluster supports, from the plugins installed on its nodes and its version, for example to create resources only on clusters supporting them with `count`. The version is the one detected by the provider, or its `opensearch_version` option. Versions that cannot be parsed are assumed to support every feature.",
+		Read:        dataSourceOpensearchSaCapabilitiesRead,
+
+		Schema: map[string]*schema.Schema{
+			"version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The version of the cluster.",
+			},
+			"plugins": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The components of the plugins installed on the nodes of the cluster, such as `opensearch-security-analytics`.",
+			},
+			"security_analytics": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the security analytics plugin is installed. Every other feature requires it.",
+			},
+			"threat_intel": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether detectors can enable threat intelligence (`threat_intel_enabled`), from OpenSearch " + saThreatIntelMinVersion + ".",
+			},
+			"correlation": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether correlation rules, such as `opensearch_sa_correlation_rule`, are supported, from OpenSearch " + saCorrelationMinVersion + ".",
+			},
+			"custom_log_types": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether custom log types can be defined, from OpenSearch " + saCustomLogTypesMinVersion + ".",
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaCapabilitiesRead(d *schema.ResourceData, m interface{}) error {
+	conf := m.(*ProviderConf)
+	plugins, err := resourceOpensearchPluginComponents(m)
+	if err != nil {
+		return fmt.Errorf("error listing plugins: %+v", err)
+	}
+
+	installed := containsString(plugins, saPluginComponent)
+	d.SetId(hashSum(conf.osVersion + ":" + strings.Join(plugins, ",")))
+	ds := &resourceDataSetter{d: d}
+	ds.set("version", conf.osVersion)
+	ds.set("plugins", plugins)
+	ds.set("security_analytics", installed)
+	ds.set("threat_intel", installed && saThreatIntelSupported(conf.osVersion))
+	ds.set("correlation", installed && saVersionAtLeast(conf.osVersion, saCorrelationMinVersion))
+	ds.set("custom_log_types", installed && saVersionAtLeast(conf.osVersion, saCustomLogTypesMinVersion))
+	return ds.err
+}
+
+// resourceOpensearchPluginComponents lists the distinct components of the
+// plugins installed on the nodes of the cluster, ordered by name.
+func resourceOpensearchPluginComponents(m interface{}) ([]string, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"format": []string{"json"}}
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", "/_cat/plugins", params, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Component string `json:"component"`
+	}
+	if err := json.Unmarshal(res.Body, &rows); err != nil {
+		return nil, fmt.Errorf("error unmarshalling plugins: %+v", err)
+	}
+
+	components := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if !containsString(components, row.Component) {
+			components = append(components, row.Component)
+		}
+	}
+	sort.Strings(components)
+	return components, nil
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchDataSourceSaCapabilities(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchDataSourceSaCapabilities,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.opensearch_sa_capabilities.cluster", "version"),
+					resource.TestCheckTypeSetElemAttr("data.opensearch_sa_capabilities.cluster", "plugins.*", saPluginComponent),
+					resource.TestCheckResourceAttr("data.opensearch_sa_capabilities.cluster", "security_analytics", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceSaCapabilities(t *testing.T) {
+	plugins := `[
+  {"name": "node-1", "component": "opensearch-security-analytics", "version": "2.11.0.0"},
+  {"name": "node-2", "component": "opensearch-security-analytics", "version": "2.11.0.0"},
+  {"name": "node-1", "component": "opensearch-alerting", "version": "2.11.0.0"}
+]`
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: plugins})
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaCapabilities().Schema, map[string]interface{}{})
+	if err := dataSourceOpensearchSaCapabilitiesRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	if request := cluster.lastRequest(t); request.Path != "/_cat/plugins" || request.Query.Get("format") != "json" {
+		t.Errorf("unexpected request %s?%s", request.Path, request.Query.Encode())
+	}
+
+	if d.Get("version").(string) != "2.11.0" {
+		t.Errorf("expected version 2.11.0, got %v", d.Get("version"))
+	}
+	if n := d.Get("plugins").(*schema.Set).Len(); n != 2 {
+		t.Errorf("expected 2 distinct plugins, got %d", n)
+	}
+	for attr, expected := range map[string]bool{
+		"security_analytics": true,
+		"threat_intel":       false,
+		"correlation":        true,
+		"custom_log_types":   true,
+	} {
+		if d.Get(attr).(bool) != expected {
+			t.Errorf("expected %s to be %t", attr, expected)
+		}
+	}
+
+	// the features require the plugin whatever the version
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `[{"name": "node-1", "component": "opensearch-alerting", "version": "2.12.0.0"}]`})
+	conf.osVersion = "2.12.0"
+	d = schema.TestResourceDataRaw(t, dataSourceOpensearchSaCapabilities().Schema, map[string]interface{}{})
+	if err := dataSourceOpensearchSaCapabilitiesRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range []string{"security_analytics", "threat_intel", "correlation", "custom_log_types"} {
+		if d.Get(attr).(bool) {
+			t.Errorf("expected %s to be false without the plugin", attr)
+		}
+	}
+}
+
+var testAccOpensearchDataSourceSaCapabilities = `
+data "opensearch_sa_capabilities" "cluster" {}
+`
@@ -0,0 +1,277 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v2"
+)
+
+// saSigmaModifiers are the value modifiers the Sigma compiler of the
+// security analytics plugin supports. The list is maintained by the provider,
+// it is not read from the cluster and must follow the plugin by hand.
+var saSigmaModifiers = map[string]bool{
+	"all":          true,
+	"base64":       true,
+	"base64offset": true,
+	"cidr":         true,
+	"contains":     true,
+	"endswith":     true,
+	"gt":           true,
+	"gte":          true,
+	"lt":           true,
+	"lte":          true,
+	"re":           true,
+	"startswith":   true,
+	"utf16":        true,
+	"utf16be":      true,
+	"utf16le":      true,
+	"wide":         true,
+	"windash":      true,
+}
+
+var saSigmaLevels = []string{"informational", "low", "medium", "high", "critical"}
+
+var saSigmaStatuses = []string{"stable", "test", "experimental", "deprecated", "unsupported"}
+
+// the words of a detection condition that do not name selections, in lower
+// case since conditions are not case sensitive
+var saSigmaConditionKeywords = map[string]bool{"and": true, "or": true, "not": true, "of": true, "them": true, "all": true}
+
+var saSigmaConditionIdentifier = regexp.MustCompile(`[A-Za-z0-9_*]+`)
+
+var saRuleProblemResource = &schema.Resource{
+	Schema: map[string]*schema.Schema{
+		"field": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The path of the offending part of the rule, such as `detection.selection.CommandLine|contains`, or the name of the offending field. Empty for problems with the document as a whole.",
+		},
+		"message": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The description of the problem.",
+		},
+	},
+}
+
+func dataSourceOpensearchSaCustomRuleValidation() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_custom_rule_validation` checks a Sigma rule before it is submitted as an `opensearch_sa_custom_rule`, without creating it. The security analytics plugin only compiles rule bodies when they are created and has no endpoint to compile one without creating it, so the rule is never sent to the cluster: it is linted by the provider against the requirements of the plugin, namely required sections, value modifiers, condition identifiers, level and status. The supported value modifiers are a list maintained by the provider, which may lag behind the plugin of the cluster. `errors` and `warnings` are advisory findings of this check, not a verdict of the cluster's Sigma compiler: a rule without errors may still be rejected when it is created. When `index` is set, the fields of the detection are also compared with the field aliases the cluster defines for `category` on that index.",
+		Read:        dataSourceOpensearchSaCustomRuleValidationRead,
+
+		Schema: map[string]*schema.Schema{
+			"body": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The Sigma rule document to validate.",
+			},
+			"category": {
+				Type:         schema.TypeString,
+				Required:     true,
+				Description:  "The category the rule would be created in.",
+				ValidateFunc: saDetectorRuleSchema["category"].ValidateFunc,
+			},
+			"index": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "An index the rule would be evaluated over. The fields of the detection that are not field aliases of `category` for this index are reported as warnings.",
+			},
+			"valid": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether no error was found.",
+			},
+			"errors": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The problems the provider expects the plugin to reject the rule for.",
+				Elem:        saRuleProblemResource,
+			},
+			"warnings": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The fields the rule would not match in documents of `index`.",
+				Elem:        saRuleProblemResource,
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaCustomRuleValidationRead(d *schema.ResourceData, m interface{}) error {
+	body := d.Get("body").(string)
+	category := d.Get("category").(string)
+	index := d.Get("index").(string)
+
+	errs := saValidateSigmaRule(body)
+	warnings := make([]saRuleProblem, 0)
+	if index != "" {
+		aliases, err := resourceOpensearchSaFieldAliases(index, category, m)
+		if err != nil {
+			return fmt.Errorf("error fetching the field aliases of log type %s: %+v", category, err)
+		}
+		for _, field := range sigmaRuleFields(body) {
+			if !aliases[field] {
+				warnings = append(warnings, saRuleProblem{
+					Field:   field,
+					Message: fmt.Sprintf("not a field alias of log type %q for index %s", category, index),
+				})
+			}
+		}
+	}
+
+	d.SetId(hashSum(strings.Join([]string{body, category, index}, "\x00")))
+	ds := &resourceDataSetter{d: d}
+	ds.set("valid", len(errs) == 0)
+	ds.set("errors", flattenSaRuleProblems(errs))
+	ds.set("warnings", flattenSaRuleProblems(warnings))
+	return ds.err
+}
+
+// saRuleProblem is a problem found in a Sigma rule document.
+type saRuleProblem struct {
+	Field   string
+	Message string
+}
+
+func flattenSaRuleProblems(problems []saRuleProblem) []interface{} {
+	flattened := make([]interface{}, 0, len(problems))
+	for _, problem := range problems {
+		flattened = append(flattened, map[string]interface{}{
+			"field":   problem.Field,
+			"message": problem.Message,
+		})
+	}
+	return flattened
+}
+
+// saValidateSigmaRule returns the problems of a Sigma rule document that
+// would make the security analytics plugin reject it, in document order.
+func saValidateSigmaRule(body string) []saRuleProblem {
+	problems := make([]saRuleProblem, 0)
+	add := func(field, format string, a ...interface{}) {
+		problems = append(problems, saRuleProblem{Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+
+	var rule map[interface{}]interface{}
+	if err := yaml.Unmarshal([]byte(body), &rule); err != nil {
+		add("", "the rule is not a YAML mapping: %v", err)
+		return problems
+	}
+
+	if title, _ := rule["title"].(string); title == "" {
+		add("title", "a title is required")
+	}
+	if _, ok := rule["logsource"].(map[interface{}]interface{}); !ok {
+		add("logsource", "a logsource mapping is required")
+	}
+	if level, ok := rule["level"]; ok && !saSigmaOneOf(level, saSigmaLevels) {
+		add("level", "expected one of %s, got %v", strings.Join(saSigmaLevels, ", "), level)
+	}
+	if status, ok := rule["status"]; ok && !saSigmaOneOf(status, saSigmaStatuses) {
+		add("status", "expected one of %s, got %v", strings.Join(saSigmaStatuses, ", "), status)
+	}
+
+	detection, ok := rule["detection"].(map[interface{}]interface{})
+	if !ok {
+		add("detection", "a detection mapping is required")
+		return problems
+	}
+
+	selections := make([]string, 0, len(detection))
+	for key := range detection {
+		if name, ok := key.(string); ok && name != "condition" && name != "timeframe" {
+			selections = append(selections, name)
+		}
+	}
+	sort.Strings(selections)
+
+	for _, name := range selections {
+		saValidateSigmaSelection("detection."+name, detection[name], add)
+	}
+
+	var conditions []string
+	switch condition := detection["condition"].(type) {
+	case string:
+		conditions = []string{condition}
+	case []interface{}:
+		for _, c := range condition {
+			if s, ok := c.(string); ok {
+				conditions = append(conditions, s)
+			}
+		}
+	}
+	if len(conditions) == 0 {
+		add("detection.condition", "a condition is required")
+	}
+	for _, condition := range conditions {
+		// aggregations after a pipe, such as count() > 5, name no selection
+		condition = strings.SplitN(condition, "|", 2)[0]
+		for _, identifier := range saSigmaConditionIdentifier.FindAllString(condition, -1) {
+			if saSigmaConditionKeywords[strings.ToLower(identifier)] || strings.Trim(identifier, "0123456789") == "" {
+				continue
+			}
+			if !saSigmaSelectionDefined(identifier, selections) {
+				add("detection.condition", "%q does not name a selection of the detection", identifier)
+			}
+		}
+	}
+
+	return problems
+}
+
+// saValidateSigmaSelection checks the value modifiers of the fields matched
+// by a selection, which is either a mapping of fields, a list of such
+// mappings or a list of keywords.
+func saValidateSigmaSelection(path string, selection interface{}, add func(field, format string, a ...interface{})) {
+	switch selection := selection.(type) {
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(selection))
+		for key := range selection {
+			keys = append(keys, fmt.Sprint(key))
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, modifier := range strings.Split(key, "|")[1:] {
+				if !saSigmaModifiers[modifier] {
+					add(path+"."+key, "unsupported value modifier %q", modifier)
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range selection {
+			if _, ok := item.(map[interface{}]interface{}); ok {
+				saValidateSigmaSelection(fmt.Sprintf("%s.%d", path, i), item, add)
+			}
+		}
+	case nil:
+		add(path, "the selection is empty")
+	}
+}
+
+// saSigmaSelectionDefined reports whether a condition identifier, which may
+// end with a * wildcard, matches a selection.
+func saSigmaSelectionDefined(identifier string, selections []string) bool {
+	for _, selection := range selections {
+		if prefix := strings.TrimSuffix(identifier, "*"); prefix != identifier && strings.HasPrefix(selection, prefix) || selection == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+func saSigmaOneOf(value interface{}, allowed []string) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	for _, a := range allowed {
+		if s == a {
+			return true
+		}
+	}
+	return false
+}
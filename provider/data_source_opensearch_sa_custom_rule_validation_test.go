@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOpensearchDataSourceSaCustomRuleValidation(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchDataSourceSaCustomRuleValidation,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.opensearch_sa_custom_rule_validation.valid", "valid", "true"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_custom_rule_validation.valid", "errors.#", "0"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_custom_rule_validation.invalid", "valid", "false"),
+					resource.TestCheckTypeSetElemNestedAttrs("data.opensearch_sa_custom_rule_validation.invalid", "errors.*", map[string]string{
+						"field":   "detection.selection.errorCode|contanis",
+						"message": `unsupported value modifier "contanis"`,
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestSaValidateSigmaRule(t *testing.T) {
+	valid := `title: Test
+logsource:
+  product: cloudtrail
+status: test
+level: high
+detection:
+  selection:
+    eventSource|endswith: iam.amazonaws.com
+  filter_1:
+    - userName|re: '^admin'
+    - userName: root
+  keywords:
+    - AccessDenied
+  condition: (selection or keywords) and not 1 of filter_* | count() > 5
+`
+	if problems := saValidateSigmaRule(valid); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	invalid := `logsource: cloudtrail
+level: severe
+detection:
+  selection:
+    eventSource|contanis|all: iam.amazonaws.com
+  filters:
+    - userName|startwith: admin
+  empty:
+  condition: selection and not filter
+`
+	expected := []saRuleProblem{
+		{Field: "title", Message: "a title is required"},
+		{Field: "logsource", Message: "a logsource mapping is required"},
+		{Field: "level", Message: "expected one of informational, low, medium, high, critical, got severe"},
+		{Field: "detection.empty", Message: "the selection is empty"},
+		{Field: "detection.filters.0.userName|startwith", Message: `unsupported value modifier "startwith"`},
+		{Field: "detection.selection.eventSource|contanis|all", Message: `unsupported value modifier "contanis"`},
+		{Field: "detection.condition", Message: `"filter" does not name a selection of the detection`},
+	}
+	if problems := saValidateSigmaRule(invalid); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected %v, got %v", expected, problems)
+	}
+
+	// condition keywords are not case sensitive
+	upper := strings.Replace(valid, "(selection or keywords) and not 1 of filter_*", "(selection OR keywords) AND NOT 1 OF filter_*", 1)
+	if problems := saValidateSigmaRule(upper); len(problems) != 0 {
+		t.Errorf("expected no problems with upper case keywords, got %v", problems)
+	}
+
+	if problems := saValidateSigmaRule("title: [unterminated"); len(problems) != 1 || problems[0].Field != "" {
+		t.Errorf("expected a single problem with the document, got %v", problems)
+	}
+	if problems := saValidateSigmaRule("title: Test\nlogsource: {}\ndetection:\n  selection:\n    a: b\n"); len(problems) != 1 || problems[0].Field != "detection.condition" {
+		t.Errorf("expected a missing condition, got %v", problems)
+	}
+}
+
+var testAccOpensearchDataSourceSaCustomRuleValidation = `
+data "opensearch_sa_custom_rule_validation" "valid" {
+  category = "cloudtrail"
+  body     = <<EOF
+title: Test AWS CloudTrail IAM Access Denied Events
+logsource:
+  product: cloudtrail
+level: high
+status: experimental
+detection:
+  condition: selection
+  selection:
+    eventSource:
+      - iam.amazonaws.com
+    errorCode|contains:
+      - AccessDenied
+EOF
+}
+
+data "opensearch_sa_custom_rule_validation" "invalid" {
+  category = "cloudtrail"
+  body     = <<EOF
+title: Test AWS CloudTrail IAM Access Denied Events
+logsource:
+  product: cloudtrail
+detection:
+  condition: selection
+  selection:
+    errorCode|contanis:
+      - AccessDenied
+EOF
+}
+`
@@ -0,0 +1,297 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceOpensearchSaDetector() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_detector` reads an existing security analytics detector, by ID or by name. Its `body` has the fields managed by the server, such as trigger IDs and timestamps, removed, so that it can be used as the starting point of another `opensearch_sa_detector`, for example with `jsonencode(merge(jsondecode(data.opensearch_sa_detector.template.body), { name = \"copy\" }))`.",
+		Read:        dataSourceOpensearchSaDetectorRead,
+
+		Schema: map[string]*schema.Schema{
+			"detector_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"detector_id", "name"},
+				Description:  "The ID of the detector.",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"detector_id", "name"},
+				Description:  "The name of the detector. The lookup fails unless exactly one detector has this name.",
+			},
+			"detector_type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The type (log type) of the detector.",
+			},
+			"body": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The detector document, as JSON, without the fields managed by the server.",
+			},
+			"custom_rule_id_map": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Replacements of the custom rule IDs referenced by `export_body`, by ID on this cluster, such as the IDs of the same rules on the cluster the detector is exported to. Custom rule IDs are assigned by each cluster.",
+			},
+			"custom_rule_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the custom rules referenced by the detector on this cluster, which must exist on the cluster `export_body` is applied to.",
+			},
+			"export_body": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The detector document as indented JSON, for backups or to recreate the detector on another cluster, for example by writing it to a file with `local_file`. On top of the fields stripped from `body`, the IDs of trigger actions are removed and the custom rule IDs are replaced according to `custom_rule_id_map`.",
+			},
+			"findings_index": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The alias the detector writes its findings through. The security analytics plugin derives it from the detector type, so all detectors of a type share it and it cannot be chosen per detector.",
+			},
+			"findings_index_pattern": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The pattern of the indices holding the findings of the detector type, for example to grant access to them.",
+			},
+			"alert_index": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The index holding the active alerts of the detector type.",
+			},
+			"alert_history_index": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The alias completed alerts of the detector type are moved to.",
+			},
+			"alert_history_index_pattern": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The pattern of the indices holding the completed alerts of the detector type.",
+			},
+			"triggers": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The alert triggers of the detector, as configured in its body.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the trigger.",
+						},
+						"severity": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The severity of the alerts raised by the trigger, from `1` (highest) to `5`.",
+						},
+						"log_types": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The log types whose findings fire the trigger (`types` in the body).",
+						},
+						"rule_ids": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The IDs of the rules whose findings fire the trigger (`ids` in the body).",
+						},
+						"rule_severity_levels": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The rule severity levels whose findings fire the trigger (`sev_levels` in the body).",
+						},
+						"tags": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The rule tags whose findings fire the trigger.",
+						},
+						"actions": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The notifications sent when the trigger fires.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"name": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The name of the action.",
+									},
+									"destination_id": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The ID of the notification channel the action sends to.",
+									},
+									"subject": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The source of the subject template of the notification.",
+									},
+									"message": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The source of the message template of the notification.",
+									},
+									"throttle_enabled": {
+										Type:        schema.TypeBool,
+										Computed:    true,
+										Description: "Whether notifications of the action are throttled.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaDetectorRead(d *schema.ResourceData, m interface{}) error {
+	var detector *SaDetectorResponse
+	if id := d.Get("detector_id").(string); id != "" {
+		var err error
+		detector, err = resourceOpensearchSaDetectorSearchWithOptions(id, saDetectorSearchOptions{}, m)
+		if err != nil {
+			return fmt.Errorf("error reading detector %s: %+v", id, err)
+		}
+	} else {
+		var err error
+		detector, err = resourceOpensearchSaDetectorByName(d.Get("name").(string), m)
+		if err != nil {
+			return err
+		}
+	}
+
+	body, err := json.Marshal(detector.Detector)
+	if err != nil {
+		return fmt.Errorf("error marshalling detector body: %+v", err)
+	}
+
+	d.SetId(detector.ID)
+	ds := &resourceDataSetter{d: d}
+	ds.set("detector_id", detector.ID)
+	ds.set("name", detector.Detector["name"])
+	ds.set("detector_type", detector.Detector["detector_type"])
+	ds.set("body", string(body))
+	idMap := make(map[string]string)
+	for id, replacement := range d.Get("custom_rule_id_map").(map[string]interface{}) {
+		idMap[id] = replacement.(string)
+	}
+	exported, err := saDetectorExportBody(detector.Detector, idMap)
+	if err != nil {
+		return err
+	}
+	ds.set("export_body", exported)
+	ds.set("custom_rule_ids", saDetectorCustomRuleIDs(detector.Detector))
+	// the indices are server-managed fields, stripped from the body
+	for _, field := range []string{"findings_index", "findings_index_pattern", "alert_index", "alert_history_index", "alert_history_index_pattern"} {
+		value, _ := detector.ServerFields[field].(string)
+		ds.set(field, value)
+	}
+	ds.set("triggers", flattenSaDetectorTriggers(detector.Detector))
+	return ds.err
+}
+
+// saDetectorExportBody returns a copy of a detector document, with its server
+// fields already stripped, without the IDs of its trigger actions and with
+// the custom rules of idMap replaced, as indented JSON.
+func saDetectorExportBody(detector map[string]interface{}, idMap map[string]string) (string, error) {
+	encoded, err := json.Marshal(detector)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling detector body: %+v", err)
+	}
+	var exported map[string]interface{}
+	if err := json.Unmarshal(encoded, &exported); err != nil {
+		return "", fmt.Errorf("error unmarshalling detector body: %+v", err)
+	}
+
+	for _, trigger := range saDetectorTriggers(exported) {
+		for _, action := range saDetectorTriggerActions(trigger) {
+			delete(action, "id")
+		}
+		// triggers may select custom rules by ID
+		if ids, ok := trigger["ids"].([]interface{}); ok {
+			for i, id := range ids {
+				if replacement, ok := idMap[fmt.Sprint(id)]; ok {
+					ids[i] = replacement
+				}
+			}
+		}
+	}
+	for _, detectorInput := range saDetectorInputs(exported) {
+		rules, _ := detectorInput["custom_rules"].([]interface{})
+		for _, r := range rules {
+			rule, _ := r.(map[string]interface{})
+			if replacement, ok := idMap[fmt.Sprint(rule["id"])]; ok {
+				rule["id"] = replacement
+			}
+		}
+	}
+
+	indented, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling detector body: %+v", err)
+	}
+	return string(indented), nil
+}
+
+// flattenSaDetectorTriggers breaks the triggers of a detector document out
+// into the typed fields of the triggers attribute. Missing or malformed
+// fields are left empty.
+func flattenSaDetectorTriggers(detector map[string]interface{}) []interface{} {
+	triggers, _ := detector["triggers"].([]interface{})
+	flattened := make([]interface{}, 0, len(triggers))
+	for _, raw := range triggers {
+		trigger, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		rawActions, _ := trigger["actions"].([]interface{})
+		actions := make([]interface{}, 0, len(rawActions))
+		for _, raw := range rawActions {
+			action, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			subject, _ := action["subject_template"].(map[string]interface{})
+			message, _ := action["message_template"].(map[string]interface{})
+			actions = append(actions, map[string]interface{}{
+				"name":             action["name"],
+				"destination_id":   action["destination_id"],
+				"subject":          subject["source"],
+				"message":          message["source"],
+				"throttle_enabled": action["throttle_enabled"],
+			})
+		}
+
+		types, _ := trigger["types"].([]interface{})
+		ruleIDs, _ := trigger["ids"].([]interface{})
+		sevLevels, _ := trigger["sev_levels"].([]interface{})
+		tags, _ := trigger["tags"].([]interface{})
+		flattened = append(flattened, map[string]interface{}{
+			"name":                 trigger["name"],
+			"severity":             trigger["severity"],
+			"log_types":            expandStringList(types),
+			"rule_ids":             expandStringList(ruleIDs),
+			"rule_severity_levels": expandStringList(sevLevels),
+			"tags":                 expandStringList(tags),
+			"actions":              actions,
+		})
+	}
+	return flattened
+}
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// the number of rule IDs resolved per search, and the number of those
+// searches running at the same time
+const (
+	saRuleTitleBatchSize   = 100
+	saRuleTitleConcurrency = 4
+)
+
+var saDetectorRuleMapRuleSchema = &schema.Resource{
+	Schema: map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The ID of the rule.",
+		},
+		"title": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The title of the rule, empty if it was not resolved.",
+		},
+	},
+}
+
+func dataSourceOpensearchSaDetectorRuleMap() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_detector_rule_map` lists every security analytics detector of the cluster along with the custom and pre-packaged rules it uses.",
+		Read:        dataSourceOpensearchSaDetectorRuleMapRead,
+
+		Schema: map[string]*schema.Schema{
+			"resolve_titles": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Look up the title of every referenced rule.",
+			},
+			"detectors": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The detectors of the cluster, ordered by ID.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID of the detector.",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the detector.",
+						},
+						"detector_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The type (log type) of the detector.",
+						},
+						"custom_rules": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The custom rules used by the detector.",
+							Elem:        saDetectorRuleMapRuleSchema,
+						},
+						"pre_packaged_rules": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The pre-packaged rules used by the detector.",
+							Elem:        saDetectorRuleMapRuleSchema,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// saDetectorRuleMapEntry is the part of a detector kept while paging through
+// the detectors of the cluster.
+type saDetectorRuleMapEntry struct {
+	id               string
+	name             string
+	detectorType     string
+	customRules      []string
+	prePackagedRules []string
+}
+
+func dataSourceOpensearchSaDetectorRuleMapRead(d *schema.ResourceData, m interface{}) error {
+	entries := make([]saDetectorRuleMapEntry, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		name, _ := detector.Detector["name"].(string)
+		detectorType, _ := detector.Detector["detector_type"].(string)
+		entries = append(entries, saDetectorRuleMapEntry{
+			id:               detector.ID,
+			name:             name,
+			detectorType:     detectorType,
+			customRules:      saDetectorRuleIDs(detector.Detector, "custom_rules"),
+			prePackagedRules: saDetectorRuleIDs(detector.Detector, "pre_packaged_rules"),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing detectors: %+v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	customTitles := map[string]string{}
+	prePackagedTitles := map[string]string{}
+	if d.Get("resolve_titles").(bool) {
+		var customIDs, prePackagedIDs []string
+		for _, entry := range entries {
+			customIDs = append(customIDs, entry.customRules...)
+			prePackagedIDs = append(prePackagedIDs, entry.prePackagedRules...)
+		}
+
+		if customTitles, err = resourceOpensearchSaRuleTitles(customIDs, false, m); err != nil {
+			return err
+		}
+		if prePackagedTitles, err = resourceOpensearchSaRuleTitles(prePackagedIDs, true, m); err != nil {
+			return err
+		}
+	}
+
+	detectors := make([]map[string]interface{}, 0, len(entries))
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		detectors = append(detectors, map[string]interface{}{
+			"id":                 entry.id,
+			"name":               entry.name,
+			"detector_type":      entry.detectorType,
+			"custom_rules":       flattenSaDetectorRuleMapRules(entry.customRules, customTitles),
+			"pre_packaged_rules": flattenSaDetectorRuleMapRules(entry.prePackagedRules, prePackagedTitles),
+		})
+		ids = append(ids, entry.id)
+	}
+
+	d.SetId(hashSum(strings.Join(ids, ",")))
+	return d.Set("detectors", detectors)
+}
+
+func flattenSaDetectorRuleMapRules(ids []string, titles map[string]string) []map[string]interface{} {
+	rules := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		rules = append(rules, map[string]interface{}{
+			"id":    id,
+			"title": titles[id],
+		})
+	}
+	return rules
+}
+
+// resourceOpensearchSaRuleTitles returns the titles of the given custom or
+// pre-packaged rules, keyed by rule ID. Rules that cannot be found are left
+// out. The IDs are looked up in batches, several of them at the same time.
+func resourceOpensearchSaRuleTitles(ids []string, prePackaged bool, m interface{}) (map[string]string, error) {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	path := joinURLPath(saAPIPath, "rules/_search")
+	params := url.Values{"pre_packaged": []string{strconv.FormatBool(prePackaged)}}
+	if !prePackaged {
+		var err error
+		path, params, err = saSearchPath(m.(*ProviderConf).saCustomRulesIndex, path, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mu sync.Mutex
+	titles := make(map[string]string, len(unique))
+	batches := (len(unique) + saRuleTitleBatchSize - 1) / saRuleTitleBatchSize
+	err := forEachConcurrently(batches, saRuleTitleConcurrency, func(i int) error {
+		end := (i + 1) * saRuleTitleBatchSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		batch := unique[i*saRuleTitleBatchSize : end]
+
+		result, err := saSearch(path, params, map[string]interface{}{
+			"size": len(batch),
+			"query": map[string]interface{}{
+				"ids": map[string]interface{}{
+					"values": batch,
+				},
+			},
+		}, "rule", m)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, hit := range result.Hits {
+			title, _ := hit.Source["title"].(string)
+			titles[hit.ID] = title
+		}
+		return nil
+	})
+
+	return titles, err
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchDataSourceSaDetectorRuleMap(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchDataSourceSaDetectorRuleMap,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs("data.opensearch_sa_detector_rule_map.test", "detectors.*", map[string]string{
+						"name":                 "test-rule-map-detector",
+						"detector_type":        "cloudtrail",
+						"custom_rules.#":       "1",
+						"custom_rules.0.title": "Test Rule Map Access Denied Events",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestForEachConcurrently(t *testing.T) {
+	var calls int32
+	err := forEachConcurrently(10, 3, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 7 {
+			return fmt.Errorf("failed %d", i)
+		}
+		return nil
+	})
+	if calls != 10 {
+		t.Errorf("expected 10 calls, got %d", calls)
+	}
+	if err == nil || err.Error() != "failed 7" {
+		t.Errorf("expected the error of the failing call, got %v", err)
+	}
+
+	if err := forEachConcurrently(0, 3, func(i int) error { return nil }); err != nil {
+		t.Errorf("expected no error without items, got %v", err)
+	}
+}
+
+var testAccOpensearchDataSourceSaDetectorRuleMap = `
+resource "opensearch_index" "cloudtrail" {
+  name               = "cloudtrail"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_custom_rule" "test" {
+  category = "cloudtrail"
+  body     = <<EOF
+title: Test Rule Map Access Denied Events
+id: 5f8c2d4e-1b0a-4c6e-9d3f-7a2b1c0d9e8f
+description: Detects AWS CloudTrail events where users receive an Access Denied error.
+logsource:
+  product: cloudtrail
+level: high
+status: experimental
+detection:
+  condition: selection
+  selection:
+    errorCode:
+      - AccessDenied
+EOF
+}
+
+resource "opensearch_sa_detector" "test" {
+  body = <<EOF
+{
+  "name": "test-rule-map-detector",
+  "detector_type": "cloudtrail",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["cloudtrail"],
+        "custom_rules": [{"id": "${opensearch_sa_custom_rule.test.id}"}],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.cloudtrail]
+}
+
+data "opensearch_sa_detector_rule_map" "test" {
+  depends_on = [opensearch_sa_detector.test]
+}
+`
+
+func TestSaDetectorRuleMapNoDetectors(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaDetectorRuleMap().Schema, map[string]interface{}{"resolve_titles": true})
+	if err := dataSourceOpensearchSaDetectorRuleMapRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	if detectors := d.Get("detectors").([]interface{}); len(detectors) != 0 {
+		t.Errorf("expected no detectors before any is created, got %v", detectors)
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+var saDetectorRuleRefSchema = &schema.Resource{
+	Schema: map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The ID of the rule document.",
+		},
+	},
+}
+
+func dataSourceOpensearchSaDetectorRuleRefs() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_detector_rule_refs` sorts a list of rules into the custom and pre-packaged rules of a detector input, so that it is not necessary to know which kind each rule is. The `custom_rules` and `pre_packaged_rules` attributes have the structure of the matching fields of a detector input, for example `custom_rules = data.opensearch_sa_detector_rule_refs.rules.custom_rules` within a `jsonencode`d body.",
+		Read:        dataSourceOpensearchSaDetectorRuleRefsRead,
+
+		Schema: map[string]*schema.Schema{
+			"rule_ids": {
+				Type:        schema.TypeList,
+				Required:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The rules, each given by the ID of its rule document or by the `id` declared by its Sigma document. The lookup fails unless each of them matches exactly one rule.",
+			},
+			"custom_rules": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The custom rules among `rule_ids`, in the order given.",
+				Elem:        saDetectorRuleRefSchema,
+			},
+			"pre_packaged_rules": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The pre-packaged rules among `rule_ids`, in the order given.",
+				Elem:        saDetectorRuleRefSchema,
+			},
+		},
+	}
+}
+
+// saDetectorRuleRef is the rule document a configured ID resolved to.
+type saDetectorRuleRef struct {
+	id          string
+	prePackaged bool
+	// the reason the ID could not be resolved, if any
+	err string
+}
+
+func dataSourceOpensearchSaDetectorRuleRefsRead(d *schema.ResourceData, m interface{}) error {
+	seen := map[string]bool{}
+	ids := make([]string, 0)
+	for _, id := range expandStringList(d.Get("rule_ids").([]interface{})) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	refs := make([]saDetectorRuleRef, len(ids))
+	err := forEachConcurrently(len(ids), saRuleTitleConcurrency, func(i int) error {
+		// the custom rules index only exists once a custom rule is created
+		custom, err := resourceOpensearchSaRulesBySigmaID(ids[i], false, m)
+		if err != nil && !IsSearchNotFound(err) {
+			return fmt.Errorf("error searching custom rules for %s: %+v", ids[i], err)
+		}
+		prePackaged, err := resourceOpensearchSaRulesBySigmaID(ids[i], true, m)
+		if err != nil {
+			return fmt.Errorf("error searching pre-packaged rules for %s: %+v", ids[i], err)
+		}
+		refs[i] = saClassifyDetectorRule(ids[i], custom, prePackaged)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	customRules := make([]map[string]interface{}, 0)
+	prePackagedRules := make([]map[string]interface{}, 0)
+	for _, ref := range refs {
+		switch {
+		case ref.err != "":
+			problems = append(problems, ref.err)
+		case ref.prePackaged:
+			prePackagedRules = append(prePackagedRules, map[string]interface{}{"id": ref.id})
+		default:
+			customRules = append(customRules, map[string]interface{}{"id": ref.id})
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("error resolving rules: %s", strings.Join(problems, "; "))
+	}
+
+	d.SetId(hashSum(strings.Join(ids, ",")))
+	ds := &resourceDataSetter{d: d}
+	ds.set("custom_rules", customRules)
+	ds.set("pre_packaged_rules", prePackagedRules)
+	return ds.err
+}
+
+// saClassifyDetectorRule picks the rule an ID refers to among the custom and
+// pre-packaged rules it matched.
+func saClassifyDetectorRule(id string, custom, prePackaged []*SaDetectorRuleResponse) saDetectorRuleRef {
+	matches := make([]string, 0, len(custom)+len(prePackaged))
+	for _, rule := range custom {
+		matches = append(matches, "custom rule "+rule.ID)
+	}
+	for _, rule := range prePackaged {
+		matches = append(matches, "pre-packaged rule "+rule.ID)
+	}
+
+	switch {
+	case len(matches) == 0:
+		return saDetectorRuleRef{err: fmt.Sprintf("no rule found with ID or Sigma id %s", id)}
+	case len(matches) > 1:
+		return saDetectorRuleRef{err: fmt.Sprintf("%s matches several rules: %s", id, strings.Join(matches, ", "))}
+	case len(custom) == 1:
+		return saDetectorRuleRef{id: custom[0].ID}
+	default:
+		return saDetectorRuleRef{id: prePackaged[0].ID, prePackaged: true}
+	}
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOpensearchDataSourceSaDetectorRuleRefs(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccOpensearchDataSourceSaDetectorRuleRefsMissing,
+				ExpectError: regexp.MustCompile("no rule found with ID or Sigma id 00000000-0000-0000-0000-000000000000"),
+			},
+		},
+	})
+}
+
+func TestSaClassifyDetectorRule(t *testing.T) {
+	custom := []*SaDetectorRuleResponse{{ID: "c1"}}
+	prePackaged := []*SaDetectorRuleResponse{{ID: "p1"}}
+
+	if ref := saClassifyDetectorRule("x", custom, nil); ref != (saDetectorRuleRef{id: "c1"}) {
+		t.Errorf("expected custom rule c1, got %+v", ref)
+	}
+	if ref := saClassifyDetectorRule("x", nil, prePackaged); ref != (saDetectorRuleRef{id: "p1", prePackaged: true}) {
+		t.Errorf("expected pre-packaged rule p1, got %+v", ref)
+	}
+	if ref := saClassifyDetectorRule("x", nil, nil); ref.err != "no rule found with ID or Sigma id x" {
+		t.Errorf("expected a not found error, got %+v", ref)
+	}
+	if ref := saClassifyDetectorRule("x", custom, prePackaged); ref.err != "x matches several rules: custom rule c1, pre-packaged rule p1" {
+		t.Errorf("expected an ambiguity error, got %+v", ref)
+	}
+}
+
+var testAccOpensearchDataSourceSaDetectorRuleRefsMissing = `
+data "opensearch_sa_detector_rule_refs" "missing" {
+  rule_ids = ["00000000-0000-0000-0000-000000000000"]
+}
+`
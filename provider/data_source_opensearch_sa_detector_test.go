@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchDataSourceSaDetector(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetector + testAccOpensearchDataSourceSaDetector,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.opensearch_sa_detector.by_name", "id", "opensearch_sa_detector.test_detector", "id"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_name", "detector_type", "windows"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_id", "name", "test-detector"),
+					resource.TestCheckResourceAttrPair("data.opensearch_sa_detector.by_id", "body", "data.opensearch_sa_detector.by_name", "body"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_id", "triggers.#", "1"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_id", "triggers.0.name", "test-trigger"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_id", "triggers.0.log_types.0", "windows"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_detector.by_id", "findings_index", saFindingsIndexAlias("windows")),
+					resource.TestCheckResourceAttrSet("data.opensearch_sa_detector.by_id", "export_body"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceSaDetectorIndices(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":1,"_source":{"detector":{
+  "name": "test",
+  "detector_type": "windows",
+  "findings_index": ".opensearch-sap-windows-findings",
+  "findings_index_pattern": "<.opensearch-sap-windows-findings-{now/d}-1>",
+  "alert_index": ".opensearch-sap-windows-alerts",
+  "alert_history_index": ".opensearch-sap-windows-alerts-history",
+  "alert_history_index_pattern": "<.opensearch-sap-windows-alerts-history-{now/d}-1>"
+}}}]}}`})
+
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaDetector().Schema, map[string]interface{}{
+		"detector_id": "d1",
+	})
+	if err := dataSourceOpensearchSaDetectorRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	for field, expected := range map[string]string{
+		"findings_index":         ".opensearch-sap-windows-findings",
+		"findings_index_pattern": "<.opensearch-sap-windows-findings-{now/d}-1>",
+		"alert_index":            ".opensearch-sap-windows-alerts",
+		"alert_history_index":    ".opensearch-sap-windows-alerts-history",
+	} {
+		if got := d.Get(field).(string); got != expected {
+			t.Errorf("expected %s %q, got %q", field, expected, got)
+		}
+	}
+	if body := d.Get("body").(string); body != `{"detector_type":"windows","name":"test"}` {
+		t.Errorf("expected the indices to be stripped from the body, got %s", body)
+	}
+}
+
+func TestSaDetectorExportBody(t *testing.T) {
+	detector := map[string]interface{}{
+		"name": "test",
+		"inputs": []interface{}{map[string]interface{}{"detector_input": map[string]interface{}{
+			"custom_rules": []interface{}{map[string]interface{}{"id": "r1"}, map[string]interface{}{"id": "r2"}},
+		}}},
+		"triggers": []interface{}{map[string]interface{}{
+			"name":    "t1",
+			"ids":     []interface{}{"r1"},
+			"actions": []interface{}{map[string]interface{}{"id": "a1", "name": "page"}},
+		}},
+	}
+	exported, err := saDetectorExportBody(detector, map[string]string{"r1": "x1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "inputs": [
+    {
+      "detector_input": {
+        "custom_rules": [
+          {
+            "id": "x1"
+          },
+          {
+            "id": "r2"
+          }
+        ]
+      }
+    }
+  ],
+  "name": "test",
+  "triggers": [
+    {
+      "actions": [
+        {
+          "name": "page"
+        }
+      ],
+      "ids": [
+        "x1"
+      ],
+      "name": "t1"
+    }
+  ]
+}`
+	if exported != expected {
+		t.Errorf("expected %s, got %s", expected, exported)
+	}
+	if saDetectorCustomRuleIDs(detector)[0] != "r1" {
+		t.Error("expected the detector itself to be left unchanged")
+	}
+}
+
+func TestFlattenSaDetectorTriggers(t *testing.T) {
+	if triggers := flattenSaDetectorTriggers(map[string]interface{}{}); len(triggers) != 0 {
+		t.Errorf("expected no triggers, got %v", triggers)
+	}
+
+	detector := map[string]interface{}{
+		"triggers": []interface{}{
+			map[string]interface{}{
+				"name":       "t1",
+				"severity":   "2",
+				"types":      []interface{}{"windows"},
+				"sev_levels": []interface{}{"high"},
+				"actions": []interface{}{
+					map[string]interface{}{
+						"name":             "notify",
+						"destination_id":   "channel",
+						"subject_template": map[string]interface{}{"source": "Alert"},
+						"throttle_enabled": true,
+					},
+				},
+			},
+		},
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"name":                 "t1",
+			"severity":             "2",
+			"log_types":            []string{"windows"},
+			"rule_ids":             []string{},
+			"rule_severity_levels": []string{"high"},
+			"tags":                 []string{},
+			"actions": []interface{}{
+				map[string]interface{}{
+					"name":             "notify",
+					"destination_id":   "channel",
+					"subject":          "Alert",
+					"message":          nil,
+					"throttle_enabled": true,
+				},
+			},
+		},
+	}
+	if triggers := flattenSaDetectorTriggers(detector); !reflect.DeepEqual(triggers, expected) {
+		t.Errorf("expected %v, got %v", expected, triggers)
+	}
+}
+
+var testAccOpensearchDataSourceSaDetector = `
+data "opensearch_sa_detector" "by_name" {
+  name = "test-detector"
+
+  depends_on = [opensearch_sa_detector.test_detector]
+}
+
+data "opensearch_sa_detector" "by_id" {
+  detector_id = opensearch_sa_detector.test_detector.id
+}
+`
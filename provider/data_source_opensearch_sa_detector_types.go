@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// the most distinct detector types returned, well above the number of log
+// types a cluster defines
+const saDetectorTypesMaxBuckets = 1000
+
+func dataSourceOpensearchSaDetectorTypes() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_detector_types` lists the distinct `detector_type` (log type) values of the security analytics detectors of the cluster, along with the number of detectors of each type. The types are counted with a terms aggregation, so detectors are not read one by one.",
+		Read:        dataSourceOpensearchSaDetectorTypesRead,
+
+		Schema: map[string]*schema.Schema{
+			"types": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The detector types, ordered by name.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The detector type.",
+						},
+						"count": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "The number of detectors of the type.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaDetectorTypesRead(d *schema.ResourceData, m interface{}) error {
+	types, err := resourceOpensearchSaDetectorTypes(m)
+	if err != nil {
+		return err
+	}
+	return dataSourceOpensearchSaDetectorTypesSet(d, types)
+}
+
+// resourceOpensearchSaDetectorTypes counts the detectors of the cluster by
+// detector type, ordered by type.
+func resourceOpensearchSaDetectorTypes(m interface{}) ([]interface{}, error) {
+	query, err := json.Marshal(map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"detector_types": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": "detector.detector_type",
+					"size":  saDetectorTypesMaxBuckets,
+					"order": map[string]interface{}{"_key": "asc"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling query body: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+	path, params, err := saSearchPath(m.(*ProviderConf).saDetectorsIndex, joinURLPath(saAPIPath, "detectors/_search"), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("POST", path, params, string(query)))
+	// no detector has been created yet
+	if IsSearchNotFound(err) {
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return saDetectorTypeBuckets(res.Body)
+}
+
+func dataSourceOpensearchSaDetectorTypesSet(d *schema.ResourceData, types []interface{}) error {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.(map[string]interface{})["name"].(string))
+	}
+	d.SetId(hashSum(strings.Join(names, ",")))
+	return d.Set("types", types)
+}
+
+// saDetectorTypeBuckets decodes the buckets of the detector_types terms
+// aggregation of a search response.
+func saDetectorTypeBuckets(body json.RawMessage) ([]interface{}, error) {
+	var result struct {
+		Aggregations struct {
+			DetectorTypes *struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"detector_types"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error unmarshalling search result: %+v", err)
+	}
+	// the aggregation is missing from responses over no index at all
+	if result.Aggregations.DetectorTypes == nil {
+		return []interface{}{}, nil
+	}
+
+	types := make([]interface{}, 0, len(result.Aggregations.DetectorTypes.Buckets))
+	for _, bucket := range result.Aggregations.DetectorTypes.Buckets {
+		types = append(types, map[string]interface{}{
+			"name":  bucket.Key,
+			"count": bucket.DocCount,
+		})
+	}
+	return types, nil
+}
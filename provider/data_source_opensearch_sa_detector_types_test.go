@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOpensearchDataSourceSaDetectorTypes(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetector + testAccOpensearchDataSourceSaDetectorTypes,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs("data.opensearch_sa_detector_types.all", "types.*", map[string]string{
+						"name":  "windows",
+						"count": "1",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestSaDetectorTypeBuckets(t *testing.T) {
+	types, err := saDetectorTypeBuckets([]byte(`{"hits": {"total": {"value": 3}, "hits": []}, "aggregations": {"detector_types": {"buckets": [
+  {"key": "cloudtrail", "doc_count": 1},
+  {"key": "windows", "doc_count": 2}
+]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"name": "cloudtrail", "count": 1},
+		map[string]interface{}{"name": "windows", "count": 2},
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+
+	types, err = saDetectorTypeBuckets([]byte(`{"hits": {"total": {"value": 0}, "hits": []}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 0 {
+		t.Errorf("expected no types without the aggregation, got %v", types)
+	}
+}
+
+var testAccOpensearchDataSourceSaDetectorTypes = `
+data "opensearch_sa_detector_types" "all" {
+  depends_on = [opensearch_sa_detector.test_detector]
+}
+`
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// the characters not allowed in Terraform resource names
+var saResourceNameInvalidCharsRegexp = regexp.MustCompile(`[^a-z0-9_]+`)
+
+func dataSourceOpensearchSaDetectors() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_detectors` lists the security analytics detectors of the cluster, to adopt an existing deployment. `import_blocks` holds an `import` block (Terraform 1.5 and later) for each detector, which can be written to a file and completed with `terraform plan -generate-config-out`. Imported detectors store their body normalized, so the plan that follows the import is clean.",
+		Read:        dataSourceOpensearchSaDetectorsRead,
+
+		Schema: map[string]*schema.Schema{
+			"detectors": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The detectors, ordered by name.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID of the detector.",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the detector.",
+						},
+						"detector_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The type (log type) of the detector.",
+						},
+						"enabled": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the detector is enabled.",
+						},
+						"resource_name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "A Terraform resource name derived from the detector name, unique among the detectors listed.",
+						},
+					},
+				},
+			},
+			"import_blocks": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "An `import` block for each detector, importing it into `opensearch_sa_detector.<resource_name>`.",
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaDetectorsRead(d *schema.ResourceData, m interface{}) error {
+	detectors := make([]map[string]interface{}, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		name, _ := detector.Detector["name"].(string)
+		detectorType, _ := detector.Detector["detector_type"].(string)
+		enabled, _ := detector.Detector["enabled"].(bool)
+		detectors = append(detectors, map[string]interface{}{
+			"id":            detector.ID,
+			"name":          name,
+			"detector_type": detectorType,
+			"enabled":       enabled,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing detectors: %+v", err)
+	}
+	sort.Slice(detectors, func(i, j int) bool {
+		if detectors[i]["name"] != detectors[j]["name"] {
+			return detectors[i]["name"].(string) < detectors[j]["name"].(string)
+		}
+		return detectors[i]["id"].(string) < detectors[j]["id"].(string)
+	})
+
+	var blocks strings.Builder
+	ids := make([]string, 0, len(detectors))
+	taken := make(map[string]bool, len(detectors))
+	for _, detector := range detectors {
+		resourceName := saResourceName(detector["name"].(string), taken)
+		detector["resource_name"] = resourceName
+		ids = append(ids, detector["id"].(string))
+
+		if blocks.Len() > 0 {
+			blocks.WriteString("\n")
+		}
+		fmt.Fprintf(&blocks, "import {\n  to = opensearch_sa_detector.%s\n  id = %q\n}\n", resourceName, detector["id"])
+	}
+
+	d.SetId(hashSum(strings.Join(ids, ",")))
+	ds := &resourceDataSetter{d: d}
+	ds.set("detectors", detectors)
+	ds.set("import_blocks", blocks.String())
+	return ds.err
+}
+
+// saResourceName turns name into a valid Terraform resource name not yet in
+// taken, and records it there.
+func saResourceName(name string, taken map[string]bool) string {
+	base := strings.Trim(saResourceNameInvalidCharsRegexp.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if base == "" || (base[0] >= '0' && base[0] <= '9') {
+		base = "detector_" + base
+	}
+	base = strings.TrimSuffix(base, "_")
+
+	resourceName := base
+	for i := 2; taken[resourceName]; i++ {
+		resourceName = fmt.Sprintf("%s_%d", base, i)
+	}
+	taken[resourceName] = true
+	return resourceName
+}
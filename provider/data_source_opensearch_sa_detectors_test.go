@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchDataSourceSaDetectors(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetector + testAccOpensearchDataSourceSaDetectors,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs("data.opensearch_sa_detectors.all", "detectors.*", map[string]string{
+						"name":          "test-detector",
+						"detector_type": "windows",
+						"resource_name": "test_detector",
+					}),
+					resource.TestMatchResourceAttr("data.opensearch_sa_detectors.all", "import_blocks", regexp.MustCompile(`to = opensearch_sa_detector\.test_detector\n`)),
+				),
+			},
+		},
+	})
+}
+
+func TestSaResourceName(t *testing.T) {
+	taken := map[string]bool{}
+	for _, c := range []struct {
+		name     string
+		expected string
+	}{
+		{"windows-detector", "windows_detector"},
+		{"Windows Detector", "windows_detector_2"},
+		{"2024 audit", "detector_2024_audit"},
+		{"--", "detector"},
+		{"café", "caf"},
+	} {
+		if resourceName := saResourceName(c.name, taken); resourceName != c.expected {
+			t.Errorf("expected %q for %q, got %q", c.expected, c.name, resourceName)
+		}
+	}
+}
+
+var testAccOpensearchDataSourceSaDetectors = `
+data "opensearch_sa_detectors" "all" {
+  depends_on = [opensearch_sa_detector.test_detector]
+}
+`
+
+func TestSaDetectorsNoDetectors(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaDetectors().Schema, map[string]interface{}{})
+	if err := dataSourceOpensearchSaDetectorsRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	if detectors := d.Get("detectors").([]interface{}); len(detectors) != 0 {
+		t.Errorf("expected no detectors before any is created, got %v", detectors)
+	}
+	if blocks := d.Get("import_blocks").(string); blocks != "" {
+		t.Errorf("expected no import blocks, got %q", blocks)
+	}
+}
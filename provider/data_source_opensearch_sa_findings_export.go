@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+func dataSourceOpensearchSaFindingsExport() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_findings_export` pages through the findings of a security analytics detector and writes them to a local JSON file.",
+		Read:        dataSourceOpensearchSaFindingsExportRead,
+
+		Schema: map[string]*schema.Schema{
+			"detector_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The ID of the detector to export findings for.",
+			},
+			"filename": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path of the file the findings are written to, as a JSON array.",
+			},
+			"start_time": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsRFC3339Time,
+				Description:  "Only export findings at or after this time (RFC 3339).",
+			},
+			"end_time": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsRFC3339Time,
+				Description:  "Only export findings at or before this time (RFC 3339).",
+			},
+			"page_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      100,
+				ValidateFunc: validation.IntBetween(1, 10000),
+				Description:  "The number of findings requested per page.",
+			},
+			"findings_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of findings written to the file.",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The SHA-256 checksum of the written file.",
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaFindingsExportRead(d *schema.ResourceData, m interface{}) error {
+	detectorID := d.Get("detector_id").(string)
+
+	params := url.Values{}
+	params.Set("detector_id", detectorID)
+	for attr, param := range map[string]string{"start_time": "startTime", "end_time": "endTime"} {
+		if v, ok := d.GetOk(attr); ok {
+			t, err := time.Parse(time.RFC3339, v.(string))
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %+v", attr, err)
+			}
+			params.Set(param, strconv.FormatInt(t.UnixMilli(), 10))
+		}
+	}
+
+	findings, err := resourceOpensearchSaFindingsSearch(params, d.Get("page_size").(int), m)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling findings: %+v", err)
+	}
+
+	filename := d.Get("filename").(string)
+	if err := os.WriteFile(filename, contents, 0644); err != nil {
+		return fmt.Errorf("error writing findings to %s: %+v", filename, err)
+	}
+	log.Printf("[INFO] Wrote %d findings of detector %s to %s", len(findings), detectorID, filename)
+
+	d.SetId(detectorID)
+	ds := &resourceDataSetter{d: d}
+	ds.set("findings_count", len(findings))
+	ds.set("sha256", fmt.Sprintf("%x", sha256.Sum256(contents)))
+	return ds.err
+}
+
+// resourceOpensearchSaFindingsSearch collects all findings matching params,
+// requesting pageSize findings at a time until the reported total is reached.
+func resourceOpensearchSaFindingsSearch(params url.Values, pageSize int, m interface{}) ([]json.RawMessage, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]json.RawMessage, 0)
+	for {
+		pageParams := url.Values{}
+		for k, v := range params {
+			pageParams[k] = v
+		}
+		pageParams.Set("startIndex", strconv.Itoa(len(findings)))
+		pageParams.Set("size", strconv.Itoa(pageSize))
+
+		var res *elastic7.Response
+		res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", joinURLPath(saAPIPath, "findings/_search"), pageParams, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		var page SaFindingsResponse
+		if err := json.Unmarshal(res.Body, &page); err != nil {
+			return nil, fmt.Errorf("error unmarshalling findings: %+v", err)
+		}
+
+		findings = append(findings, page.Findings...)
+		if len(page.Findings) == 0 || len(findings) >= page.TotalFindings {
+			return findings, nil
+		}
+	}
+}
+
+type SaFindingsResponse struct {
+	TotalFindings int               `json:"total_findings"`
+	Findings      []json.RawMessage `json:"findings"`
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOpensearchDataSourceSaFindingsExport(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "findings.json")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccOpensearchDataSourceSaFindingsExport, filename),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.opensearch_sa_findings_export.test", "findings_count", "0"),
+					resource.TestCheckResourceAttrSet("data.opensearch_sa_findings_export.test", "sha256"),
+				),
+			},
+		},
+	})
+}
+
+// WaitForSaFindings polls the findings of a detector every interval until it
+// has at least one, and returns their number. Detectors only search their
+// indices when they run, so acceptance tests seeding documents use it instead
+// of sleeping for a fixed time.
+func WaitForSaFindings(detectorID string, timeout, interval time.Duration, m interface{}) (int, error) {
+	params := url.Values{}
+	params.Set("detector_id", detectorID)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		findings, err := resourceOpensearchSaFindingsSearch(params, 100, m)
+		if err != nil && !IsSearchNotFound(err) {
+			return 0, err
+		}
+		if len(findings) > 0 {
+			return len(findings), nil
+		}
+
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("detector %s has no findings after %s", detectorID, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// testCheckOpensearchSaFindingsExist waits for the detector resource name to
+// have findings.
+func testCheckOpensearchSaFindingsExist(name string, timeout, interval time.Duration) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("Not found: %s", name)
+		}
+
+		_, err := WaitForSaFindings(rs.Primary.ID, timeout, interval, testAccOpendistroProvider.Meta())
+		return err
+	}
+}
+
+func TestWaitForSaFindings(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		// the findings index does not exist before the first run
+		saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_findings":0,"findings":[]}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_findings":2,"findings":[{"id":"f1"},{"id":"f2"}]}`},
+	)
+
+	count, err := WaitForSaFindings("d1", time.Minute, time.Millisecond, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 findings, got %d", count)
+	}
+	if request := cluster.lastRequest(t); request.Path != "/_plugins/_security_analytics/findings/_search" || request.Query.Get("detector_id") != "d1" {
+		t.Errorf("unexpected request %+v", request)
+	}
+
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"total_findings":0,"findings":[]}`})
+	if _, err := WaitForSaFindings("d1", 10*time.Millisecond, time.Millisecond, conf); err == nil {
+		t.Error("expected an error once the timeout has elapsed")
+	}
+}
+
+var testAccOpensearchDataSourceSaFindingsExport = `
+resource "opensearch_index" "windows" {
+  name               = "windows"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "test" {
+  body = <<EOF
+{
+  "name": "test-findings-export-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.windows]
+}
+
+data "opensearch_sa_findings_export" "test" {
+  detector_id = opensearch_sa_detector.test.id
+  filename    = "%s"
+}
+`
@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceOpensearchSaFindingsRetention() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_findings_retention` reads how the security analytics plugin rolls over and retains the history indices of findings, as in effect on the cluster. Use the `opensearch_sa_findings_retention` resource to manage these settings.",
+		Read:        dataSourceOpensearchSaFindingsRetentionRead,
+		Schema:      saFindingsRetentionSchema(true),
+	}
+}
+
+func dataSourceOpensearchSaFindingsRetentionRead(d *schema.ResourceData, m interface{}) error {
+	d.SetId("sa-findings-retention")
+	return resourceOpensearchSaFindingsRetentionSetState(d, m)
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceOpensearchSaPrepackagedRuleBySigmaID() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_prepackaged_rule_by_sigma_id` resolves a pre-packaged security analytics rule from its Sigma `id` to the ID of the rule document on the cluster.",
+		Read:        dataSourceOpensearchSaPrepackagedRuleBySigmaIDRead,
+
+		Schema: map[string]*schema.Schema{
+			"sigma_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The `id` of the Sigma rule.",
+			},
+			"rule_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the rule document, as referenced in the `pre_packaged_rules` of a detector input.",
+			},
+			"title": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The title of the rule.",
+			},
+			"category": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The category (log type) of the rule.",
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaPrepackagedRuleBySigmaIDRead(d *schema.ResourceData, m interface{}) error {
+	sigmaID := d.Get("sigma_id").(string)
+
+	rule, err := resourceOpensearchSaPrepackagedRuleBySigmaID(sigmaID, m)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(rule.ID)
+	ds := &resourceDataSetter{d: d}
+	ds.set("rule_id", rule.ID)
+	ds.set("title", rule.Rule["title"])
+	ds.set("category", rule.Rule["category"])
+	return ds.err
+}
+
+// resourceOpensearchSaPrepackagedRuleBySigmaID searches the pre-packaged
+// rules for the one whose Sigma document declares the given id.
+func resourceOpensearchSaPrepackagedRuleBySigmaID(sigmaID string, m interface{}) (*SaDetectorRuleResponse, error) {
+	rules, err := resourceOpensearchSaRulesBySigmaID(sigmaID, true, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no pre-packaged rule found with Sigma id %s", sigmaID)
+	}
+	return rules[0], nil
+}
+
+// resourceOpensearchSaRulesBySigmaID returns the custom or pre-packaged rules
+// whose Sigma document declares the given id. Pre-packaged rules are looked
+// up once per provider, custom rules on every call since they may be created
+// during the apply.
+func resourceOpensearchSaRulesBySigmaID(sigmaID string, prePackaged bool, m interface{}) ([]*SaDetectorRuleResponse, error) {
+	if !prePackaged {
+		return resourceOpensearchSaRulesSearchBySigmaID(sigmaID, false, m)
+	}
+
+	cache := &m.(*ProviderConf).saPrepackagedRules
+	if rules, ok := cache.Load(sigmaID); ok {
+		return rules.([]*SaDetectorRuleResponse), nil
+	}
+	rules, err := resourceOpensearchSaRulesSearchBySigmaID(sigmaID, true, m)
+	if err != nil {
+		return nil, err
+	}
+	// concurrent lookups of the same id keep the first result stored
+	cached, _ := cache.LoadOrStore(sigmaID, rules)
+	return cached.([]*SaDetectorRuleResponse), nil
+}
+
+func resourceOpensearchSaRulesSearchBySigmaID(sigmaID string, prePackaged bool, m interface{}) ([]*SaDetectorRuleResponse, error) {
+	query := map[string]interface{}{
+		"size": 10,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{
+						"ids": map[string]interface{}{
+							"values": []string{sigmaID},
+						},
+					},
+					map[string]interface{}{
+						"nested": map[string]interface{}{
+							"path": "rule",
+							"query": map[string]interface{}{
+								"match_phrase": map[string]interface{}{
+									"rule.rule": sigmaID,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	path := joinURLPath(saAPIPath, "rules/_search")
+	params := url.Values{"pre_packaged": []string{strconv.FormatBool(prePackaged)}}
+	if !prePackaged {
+		var err error
+		path, params, err = saSearchPath(m.(*ProviderConf).saCustomRulesIndex, path, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := saSearch(path, params, query, "rule", m)
+	if err != nil {
+		return nil, err
+	}
+
+	// the phrase query may also match rules mentioning the id elsewhere, so
+	// compare with the id declared by each Sigma document
+	rules := make([]*SaDetectorRuleResponse, 0)
+	for _, hit := range result.Hits {
+		if hit.ID == sigmaID || sigmaRuleID(hit.Source["rule"]) == sigmaID {
+			rules = append(rules, &SaDetectorRuleResponse{
+				ID:      hit.ID,
+				Version: hit.Version,
+				Rule:    hit.Source,
+			})
+		}
+	}
+
+	return rules, nil
+}
+
+// sigmaRuleID returns the id declared by a Sigma rule document, or an empty
+// string if it cannot be parsed.
+func sigmaRuleID(body interface{}) string {
+	return parseSigmaRuleHeader(body).ID
+}
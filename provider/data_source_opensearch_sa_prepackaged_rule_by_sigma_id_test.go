@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOpensearchDataSourceSaPrepackagedRuleBySigmaID_notFound(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccOpensearchDataSourceSaPrepackagedRuleBySigmaIDMissing,
+				ExpectError: regexp.MustCompile("no pre-packaged rule found with Sigma id"),
+			},
+		},
+	})
+}
+
+func TestSaPrepackagedRuleCache(t *testing.T) {
+	sigmaID := "cb411bfe-e9f9-4eda-8276-414fe842261d"
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"p1","_version":1,"_source":{"rule":{"category":"windows","rule":"title: Test\nid: ` + sigmaID + `\n"}}}]}}`})
+
+	// the cache is shared by the concurrent operations of the provider
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rule, err := resourceOpensearchSaPrepackagedRuleBySigmaID(sigmaID, conf)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if rule.ID != "p1" {
+				t.Errorf("expected rule p1, got %s", rule.ID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	searches := func() int {
+		cluster.mu.Lock()
+		defer cluster.mu.Unlock()
+		return len(cluster.requests)
+	}
+	searched := searches()
+	if _, err := resourceOpensearchSaPrepackagedRuleBySigmaID(sigmaID, conf); err != nil {
+		t.Fatal(err)
+	}
+	if searches() != searched {
+		t.Errorf("expected the cached rule to be reused, got %d more searches", searches()-searched)
+	}
+
+	// custom rules are searched on every lookup
+	if _, err := resourceOpensearchSaRulesBySigmaID(sigmaID, false, conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resourceOpensearchSaRulesBySigmaID(sigmaID, false, conf); err != nil {
+		t.Fatal(err)
+	}
+	if searches() != searched+2 {
+		t.Errorf("expected custom rules not to be cached, got %d searches", searches()-searched)
+	}
+}
+
+func TestSigmaRuleID(t *testing.T) {
+	body := "title: Test\nid: cb411bfe-e9f9-4eda-8276-414fe842261d\nlevel: high\n"
+	if id := sigmaRuleID(body); id != "cb411bfe-e9f9-4eda-8276-414fe842261d" {
+		t.Errorf("expected Sigma id to be parsed, got %q", id)
+	}
+	if id := sigmaRuleID("{not: [yaml"); id != "" {
+		t.Errorf("expected empty id for invalid YAML, got %q", id)
+	}
+}
+
+var testAccOpensearchDataSourceSaPrepackagedRuleBySigmaIDMissing = `
+data "opensearch_sa_prepackaged_rule_by_sigma_id" "test" {
+  sigma_id = "00000000-0000-0000-0000-000000000000"
+}
+`
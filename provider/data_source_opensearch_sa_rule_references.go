@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceOpensearchSaRuleReferences() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_rule_references` lists the security analytics detectors referencing a rule in any of their inputs, for example to check that a custom rule is unused before deleting it. Every detector of the cluster is read, a page at a time.",
+		Read:        dataSourceOpensearchSaRuleReferencesRead,
+
+		Schema: map[string]*schema.Schema{
+			"rule_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The ID of the rule document, either a custom or a pre-packaged rule.",
+			},
+			"detector_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the detectors referencing the rule, in the order of `detectors`.",
+			},
+			"detectors": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The detectors referencing the rule, in the order of the detector search.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID of the detector.",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the detector.",
+						},
+						"custom": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the detector references the rule as a custom rule rather than as a pre-packaged one.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaRuleReferencesRead(d *schema.ResourceData, m interface{}) error {
+	ruleID := d.Get("rule_id").(string)
+
+	ids := make([]string, 0)
+	detectors := make([]map[string]interface{}, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		custom := containsString(saDetectorCustomRuleIDs(detector.Detector), ruleID)
+		if !custom && !containsString(saDetectorRuleIDs(detector.Detector, "pre_packaged_rules"), ruleID) {
+			return nil
+		}
+		name, _ := detector.Detector["name"].(string)
+		ids = append(ids, detector.ID)
+		detectors = append(detectors, map[string]interface{}{
+			"id":     detector.ID,
+			"name":   name,
+			"custom": custom,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error listing detectors: %+v", err)
+	}
+
+	d.SetId(ruleID)
+	ds := &resourceDataSetter{d: d}
+	ds.set("detector_ids", ids)
+	ds.set("detectors", detectors)
+	return ds.err
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestSaRuleReferences(t *testing.T) {
+	detectors := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":3},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"a","inputs":[{"detector_input":{"custom_rules":[{"id":"r1"}],"pre_packaged_rules":[]}}]}}},
+  {"_id":"d2","_source":{"detector":{"name":"b","inputs":[{"detector_input":{"custom_rules":[{"id":"r2"}],"pre_packaged_rules":[]}}]}}},
+  {"_id":"d3","_source":{"detector":{"name":"c","inputs":[{"detector_input":{"custom_rules":[],"pre_packaged_rules":[{"id":"r1"}]}}]}}}
+]}}`}
+
+	_, conf := newSaFakeCluster(t, detectors)
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaRuleReferences().Schema, map[string]interface{}{"rule_id": "r1"})
+	if err := dataSourceOpensearchSaRuleReferencesRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := d.Get("detector_ids").([]interface{}); !reflect.DeepEqual(ids, []interface{}{"d1", "d3"}) {
+		t.Errorf("expected detectors d1 and d3, got %v", ids)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": "d1", "name": "a", "custom": true},
+		map[string]interface{}{"id": "d3", "name": "c", "custom": false},
+	}
+	if refs := d.Get("detectors").([]interface{}); !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected %v, got %v", expected, refs)
+	}
+
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	d = schema.TestResourceDataRaw(t, dataSourceOpensearchSaRuleReferences().Schema, map[string]interface{}{"rule_id": "r1"})
+	if err := dataSourceOpensearchSaRuleReferencesRead(d, conf); err != nil {
+		t.Fatal(err)
+	}
+	if ids := d.Get("detector_ids").([]interface{}); len(ids) != 0 {
+		t.Errorf("expected no detectors before any is created, got %v", ids)
+	}
+}
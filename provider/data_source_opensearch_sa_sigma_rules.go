@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v2"
+)
+
+func dataSourceOpensearchSaSigmaRules() *schema.Resource {
+	return &schema.Resource{
+		Description: "`opensearch_sa_sigma_rules` reads the Sigma rules of a local directory tree, such as a checkout of the upstream Sigma repository, and infers the category of each rule from the directory it is stored in. The result is meant to be fed into a `for_each` of `opensearch_sa_custom_rule`.",
+		Read:        dataSourceOpensearchSaSigmaRulesRead,
+
+		Schema: map[string]*schema.Schema{
+			"root_path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The directory the rules are read from.",
+			},
+			"categories": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The category of the rules below each directory, keyed by the directory path relative to `root_path`, for example `cloud/aws/cloudtrail = \"cloudtrail\"`. The longest matching directory wins. Rules outside of every listed directory are skipped.",
+			},
+			"rules": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The rules found, ordered by path.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The path of the rule file relative to `root_path`.",
+						},
+						"category": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The category inferred from the directory of the rule.",
+						},
+						"sigma_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The `id` declared by the rule.",
+						},
+						"title": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The `title` declared by the rule.",
+						},
+						"body": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The content of the rule file.",
+						},
+					},
+				},
+			},
+			"errors": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The rule files that could not be parsed. They are left out of `rules`.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The path of the rule file relative to `root_path`.",
+						},
+						"error": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The parse error.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceOpensearchSaSigmaRulesRead(d *schema.ResourceData, m interface{}) error {
+	root := d.Get("root_path").(string)
+
+	categories := make(map[string]string)
+	for dir, category := range d.Get("categories").(map[string]interface{}) {
+		categories[filepath.Clean(filepath.FromSlash(dir))] = category.(string)
+	}
+
+	rules := make([]map[string]interface{}, 0)
+	parseErrors := make([]map[string]interface{}, 0)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isSigmaRuleFile(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		category, ok := sigmaRuleCategory(rel, categories)
+		if !ok {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %+v", path, err)
+		}
+
+		var header sigmaRuleHeader
+		if err := yaml.Unmarshal(body, &header); err != nil {
+			parseErrors = append(parseErrors, map[string]interface{}{
+				"path":  filepath.ToSlash(rel),
+				"error": err.Error(),
+			})
+			return nil
+		}
+
+		rules = append(rules, map[string]interface{}{
+			"path":     filepath.ToSlash(rel),
+			"category": category,
+			"sigma_id": header.ID,
+			"title":    header.Title,
+			"body":     string(body),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading Sigma rules from %s: %+v", root, err)
+	}
+
+	d.SetId(root)
+	ds := &resourceDataSetter{d: d}
+	ds.set("rules", rules)
+	ds.set("errors", parseErrors)
+	return ds.err
+}
+
+func isSigmaRuleFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// sigmaRuleCategory returns the category of the closest directory of rel
+// listed in categories.
+func sigmaRuleCategory(rel string, categories map[string]string) (string, bool) {
+	for dir := filepath.Dir(rel); ; dir = filepath.Dir(dir) {
+		if category, ok := categories[dir]; ok {
+			return category, true
+		}
+		if dir == "." || dir == string(filepath.Separator) {
+			return "", false
+		}
+	}
+}
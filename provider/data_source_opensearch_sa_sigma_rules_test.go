@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDataSourceOpensearchSaSigmaRulesRead(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"cloud/aws/cloudtrail/aws_iam_denied.yml": "title: IAM Access Denied\nid: cb411bfe-e9f9-4eda-8276-414fe842261d\n",
+		"cloud/aws/cloudtrail/README.md":          "not a rule",
+		"cloud/aws/cloudtrail/broken.yaml":        "title: [unterminated\n",
+		"windows/process_creation/proc.yml":       "title: Process Creation\nid: 1a2b3c4d-0000-0000-0000-000000000000\n",
+		"linux/auditd.yml":                        "title: Skipped\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceOpensearchSaSigmaRules().Schema, map[string]interface{}{
+		"root_path": root,
+		"categories": map[string]interface{}{
+			"cloud/aws/cloudtrail": "cloudtrail",
+			"windows":              "windows",
+		},
+	})
+	if err := dataSourceOpensearchSaSigmaRulesRead(d, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := d.Get("rules").([]interface{})
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %v", rules)
+	}
+	first := rules[0].(map[string]interface{})
+	if first["path"] != "cloud/aws/cloudtrail/aws_iam_denied.yml" || first["category"] != "cloudtrail" || first["sigma_id"] != "cb411bfe-e9f9-4eda-8276-414fe842261d" || first["title"] != "IAM Access Denied" {
+		t.Errorf("unexpected rule %v", first)
+	}
+	if second := rules[1].(map[string]interface{}); second["category"] != "windows" {
+		t.Errorf("expected the nested windows rule to be categorized, got %v", second)
+	}
+
+	errs := d.Get("errors").([]interface{})
+	if len(errs) != 1 || errs[0].(map[string]interface{})["path"] != "cloud/aws/cloudtrail/broken.yaml" {
+		t.Errorf("expected a parse error for broken.yaml, got %v", errs)
+	}
+}
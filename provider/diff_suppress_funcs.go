@@ -164,21 +164,5 @@ func diffSuppressAnomalyDetection(k, old, new string, d *schema.ResourceData) bo
 }
 
 func diffSuppressSaDetector(k, old, new string, d *schema.ResourceData) bool {
-	var oo, no interface{}
-	if err := json.Unmarshal([]byte(old), &oo); err != nil {
-		return false
-	}
-	if err := json.Unmarshal([]byte(new), &no); err != nil {
-		return false
-	}
-
-	if om, ok := oo.(map[string]interface{}); ok {
-		normalizeSaDetector(om)
-	}
-
-	if nm, ok := no.(map[string]interface{}); ok {
-		normalizeSaDetector(nm)
-	}
-
-	return reflect.DeepEqual(oo, no)
+	return saDetectorNormalizer.equalJSON(old, new)
 }
@@ -12,6 +12,8 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,8 +26,10 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	elastic7 "github.com/olivere/elastic/v7"
+	"golang.org/x/net/http/httpproxy"
 )
 
 type ServerFlavor int64
@@ -42,10 +46,14 @@ var awsOpensearchServerlessUrlRegexp = regexp.MustCompile(`([a-z0-9-]+).aoss.ama
 var minimalOpensearchServerlessVersion = "2.0.0"
 
 type ProviderConf struct {
-	rawUrl                  string
-	insecure                bool
-	sniffing                bool
-	healthchecking          bool
+	rawUrl         string
+	insecure       bool
+	sniffing       bool
+	healthchecking bool
+	gzipRequests   bool
+	// set once the cluster rejected a compressed request body, after which
+	// bodies are sent uncompressed
+	gzipRejected            atomic.Bool
 	cacertFile              string
 	username                string
 	password                string
@@ -67,6 +75,33 @@ type ProviderConf struct {
 	keyPemPath              string
 	hostOverride            string
 	proxy                   string
+	proxyUsername           string
+	proxyPassword           string
+	// limits the number of in-flight security analytics writes
+	saWriteSemaphore chan struct{}
+	// index or alias searched for security analytics objects instead of the
+	// plugin search endpoints, when set
+	saDetectorsIndex   string
+	saCustomRulesIndex string
+	// routing value security analytics objects are looked up by ID with
+	saRouting string
+	// batches the detector reads running at the same time, when set
+	saDetectorReads *saDetectorBatcher
+	// logs security analytics request timings as structured fields, when set
+	saRequestLogger hclog.Logger
+	// timeout and retries of security analytics requests, per operation
+	saRequestSettings map[saOperation]saRequestSettings
+	// the server-managed detector fields recorded in server_fields
+	keepServerFields []string
+	// the category of custom rules that do not set one
+	defaultRuleCategory string
+	// the Sigma tag added to the custom rules the provider writes
+	managedByTag string
+	// the pre-packaged rules found by Sigma id, as []*SaDetectorRuleResponse.
+	// Pre-packaged rules only change with the plugin, so lookups are cached
+	// for the lifetime of the provider.
+	saPrepackagedRules sync.Map
+
 	// determined after connecting to the server
 	flavor ServerFlavor
 }
@@ -92,6 +127,12 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("OPENSEARCH_HEALTH", true),
 				Description: "Set the client healthcheck option for the OpenSearch client. Healthchecking is designed for direct access to the cluster.",
 			},
+			"gzip_requests": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Compress the bodies of the requests sent to the cluster with gzip, setting `Content-Encoding: gzip`, to save bandwidth on large requests such as batches of rules. OpenSearch decompresses request bodies out of the box. If a security analytics request is rejected because of its compressed body, for example by a proxy answering `415 Unsupported Media Type`, it is sent again uncompressed and the provider stops compressing bodies.",
+			},
 			"username": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -218,40 +259,177 @@ func Provider() *schema.Provider {
 				Description: "If provided, sets the 'Host' header of requests and the 'ServerName' for certificate validation to this value. See the documentation on connecting to OpenSearch via an SSH tunnel.",
 			},
 			"proxy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsURLWithScheme([]string{"http", "https", "socks5", "socks5h"}),
+				Description:  "Proxy URL to use for requests to OpenSearch, such as `http://proxy.example.com:3128`. Credentials of the proxy may be part of the URL or set with `proxy_username` and `proxy_password`. When unset, the proxy of the `HTTPS_PROXY` and `HTTP_PROXY` environment variables is used, except for the hosts of `NO_PROXY`.",
+			},
+			"proxy_username": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"proxy"},
+				Description:  "Username to authenticate to the proxy of `proxy` with, taking precedence over the credentials of its URL.",
+			},
+			"proxy_password": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"proxy_username"},
+				Description:  "Password to authenticate to the proxy of `proxy` with.",
+			},
+			"sa_max_concurrent_writes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      10,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The maximum number of security analytics detector and rule creates, updates and deletes sent to the cluster at the same time.",
+			},
+			"sa_detectors_index": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "The index or alias holding security analytics detectors. If provided, detectors are read back by searching it directly instead of through the security analytics detector search endpoint.",
+			},
+			"sa_custom_rules_index": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "The index or alias holding security analytics custom rules. If provided, custom rules are read back by searching it directly instead of through the security analytics rule search endpoint.",
+			},
+			"sa_routing": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Description: "Proxy URL to use for requests to OpenSearch.",
+				Default:     "",
+				Description: "The routing value of the documents of security analytics indices with custom routing. Detectors and custom rules are read back by ID through searches, which then only match documents indexed with this routing value, unless the `search_routing` of a detector sets another one. The security analytics API indexes its documents itself and takes no routing parameter on its create, update and delete requests, so the routing only applies to reads.",
+			},
+			"sa_detector_read_batch_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum number of detectors read with a single search. Terraform reads resources concurrently, up to its `-parallelism`, during a refresh: when set, the detector reads running at the same time are batched into searches for several detector IDs, instead of one search each. Detectors setting `search_index` or `search_routing` are read on their own, and so is every detector of a batch failing as a whole. 0 disables batching.",
+			},
+			"keep_server_fields": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice(saDetectorServerFields(), false),
+				},
+				Description: "The server-managed fields of security analytics detectors, such as `last_update_time`, recorded in the `server_fields` attribute of `opensearch_sa_detector` for auditing. They are stripped from the detector body either way, so that they do not cause diffs.",
+			},
+			"managed_by_tag": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "",
+				ValidateFunc: validation.StringMatch(sigmaTagRegexp, "must be a Sigma tag such as `managed_by.terraform`, a lowercase namespace and name separated by a dot"),
+				Description:  "A Sigma tag, such as `managed_by.terraform`, added to the `tags` of every `opensearch_sa_custom_rule` on create and update, to tell the rules managed by Terraform apart from the others, for example when searching rules in Dashboards. The tag is left out of the rule read back, so it does not show up in `body` or `sigma`, and removing it outside of Terraform is not reported as a change. Detectors have no field the cluster would keep a marker in, they are not tagged.",
+			},
+			"default_rule_category": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "",
+				ValidateFunc: validation.Any(validation.StringIsEmpty, saDetectorRuleSchema["category"].ValidateFunc),
+				Description:  "The category of the `opensearch_sa_custom_rule` resources that do not set one.",
+			},
+			"sa_request_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The time in seconds a security analytics request may take, retries included. 0 leaves requests bounded by the HTTP client only.",
+			},
+			"sa_request_max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      saDefaultMaxRetries,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The number of times a security analytics request failing with a transient error is retried. Requests creating an object are only retried when they were throttled or could not connect, since a gateway error does not tell whether the object was created.",
+			},
+			"sa_request_overrides": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Overrides of `sa_request_timeout` and `sa_request_max_retries` for the requests of one kind of operation, for example to give detector creates more time than reads.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"operation": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice([]string{"create", "read", "update", "delete"}, false),
+							Description:  "The operation the settings apply to, one of `create`, `read`, `update` or `delete`. Reads include the lookups done while creating, updating or deleting an object.",
+						},
+						"timeout": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      -1,
+							ValidateFunc: validation.IntAtLeast(-1),
+							Description:  "The timeout in seconds of the requests of the operation. -1 keeps `sa_request_timeout`.",
+						},
+						"max_retries": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      -1,
+							ValidateFunc: validation.IntAtLeast(-1),
+							Description:  "The number of retries of the requests of the operation. -1 keeps `sa_request_max_retries`.",
+						},
+					},
+				},
+			},
+			"sa_structured_request_logs": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Log the method, path, status code and duration of every security analytics request as JSON fields instead of a plain DEBUG message.",
 			},
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"opensearch_cluster_settings":          resourceOpensearchClusterSettings(),
-			"opensearch_component_template":        resourceOpensearchComponentTemplate(),
-			"opensearch_composable_index_template": resourceOpensearchComposableIndexTemplate(),
-			"opensearch_data_stream":               resourceOpensearchDataStream(),
-			"opensearch_index_template":            resourceOpensearchIndexTemplate(),
-			"opensearch_index":                     resourceOpensearchIndex(),
-			"opensearch_ingest_pipeline":           resourceOpensearchIngestPipeline(),
-			"opensearch_dashboard_object":          resourceOpensearchDashboardObject(),
-			"opensearch_audit_config":              resourceOpenSearchAuditConfig(),
-			"opensearch_ism_policy_mapping":        resourceOpenSearchISMPolicyMapping(),
-			"opensearch_ism_policy":                resourceOpenSearchISMPolicy(),
-			"opensearch_dashboard_tenant":          resourceOpenSearchDashboardTenant(),
-			"opensearch_monitor":                   resourceOpenSearchMonitor(),
-			"opensearch_role":                      resourceOpenSearchRole(),
-			"opensearch_roles_mapping":             resourceOpenSearchRolesMapping(),
-			"opensearch_user":                      resourceOpenSearchUser(),
-			"opensearch_script":                    resourceOpensearchScript(),
-			"opensearch_snapshot_repository":       resourceOpensearchSnapshotRepository(),
-			"opensearch_channel_configuration":     resourceOpenSearchChannelConfiguration(),
-			"opensearch_anomaly_detection":         resourceOpenSearchAnomalyDetection(),
-			"opensearch_sm_policy":                 resourceOpenSearchSMPolicy(),
-			"opensearch_sa_detector":               resourceOpenSearchSaDetector(),
-			"opensearch_sa_custom_rule":            resourceOpenSearchSaDetectorRule(),
+			"opensearch_cluster_settings":            resourceOpensearchClusterSettings(),
+			"opensearch_component_template":          resourceOpensearchComponentTemplate(),
+			"opensearch_composable_index_template":   resourceOpensearchComposableIndexTemplate(),
+			"opensearch_data_stream":                 resourceOpensearchDataStream(),
+			"opensearch_index_template":              resourceOpensearchIndexTemplate(),
+			"opensearch_index":                       resourceOpensearchIndex(),
+			"opensearch_ingest_pipeline":             resourceOpensearchIngestPipeline(),
+			"opensearch_dashboard_object":            resourceOpensearchDashboardObject(),
+			"opensearch_audit_config":                resourceOpenSearchAuditConfig(),
+			"opensearch_ism_policy_mapping":          resourceOpenSearchISMPolicyMapping(),
+			"opensearch_ism_policy":                  resourceOpenSearchISMPolicy(),
+			"opensearch_dashboard_tenant":            resourceOpenSearchDashboardTenant(),
+			"opensearch_monitor":                     resourceOpenSearchMonitor(),
+			"opensearch_role":                        resourceOpenSearchRole(),
+			"opensearch_roles_mapping":               resourceOpenSearchRolesMapping(),
+			"opensearch_user":                        resourceOpenSearchUser(),
+			"opensearch_script":                      resourceOpensearchScript(),
+			"opensearch_snapshot_repository":         resourceOpensearchSnapshotRepository(),
+			"opensearch_channel_configuration":       resourceOpenSearchChannelConfiguration(),
+			"opensearch_anomaly_detection":           resourceOpenSearchAnomalyDetection(),
+			"opensearch_sm_policy":                   resourceOpenSearchSMPolicy(),
+			"opensearch_sa_alerts_acknowledgement":   resourceOpenSearchSaAlertsAcknowledgement(),
+			"opensearch_sa_detector":                 resourceOpenSearchSaDetector(),
+			"opensearch_sa_detector_acknowledge_all": resourceOpenSearchSaDetectorAcknowledgeAll(),
+			"opensearch_sa_correlation_rule":         resourceOpenSearchSaCorrelationRule(),
+			"opensearch_sa_custom_rule":              resourceOpenSearchSaDetectorRule(),
+			"opensearch_sa_detectors_pause":          resourceOpenSearchSaDetectorsPause(),
+			"opensearch_sa_findings_index_template":  resourceOpenSearchSaFindingsIndexTemplate(),
+			"opensearch_sa_findings_retention":       resourceOpenSearchSaFindingsRetention(),
+			"opensearch_sa_settings":                 resourceOpenSearchSaSettings(),
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"opensearch_host": dataSourceOpensearchHost(),
+			"opensearch_host":                            dataSourceOpensearchHost(),
+			"opensearch_resolved_indices":                dataSourceOpensearchResolvedIndices(),
+			"opensearch_sa_capabilities":                 dataSourceOpensearchSaCapabilities(),
+			"opensearch_sa_custom_rule_validation":       dataSourceOpensearchSaCustomRuleValidation(),
+			"opensearch_sa_detector":                     dataSourceOpensearchSaDetector(),
+			"opensearch_sa_detector_rule_map":            dataSourceOpensearchSaDetectorRuleMap(),
+			"opensearch_sa_detector_rule_refs":           dataSourceOpensearchSaDetectorRuleRefs(),
+			"opensearch_sa_detector_types":               dataSourceOpensearchSaDetectorTypes(),
+			"opensearch_sa_detectors":                    dataSourceOpensearchSaDetectors(),
+			"opensearch_sa_findings_export":              dataSourceOpensearchSaFindingsExport(),
+			"opensearch_sa_findings_retention":           dataSourceOpensearchSaFindingsRetention(),
+			"opensearch_sa_prepackaged_rule_by_sigma_id": dataSourceOpensearchSaPrepackagedRuleBySigmaID(),
+			"opensearch_sa_rule_references":              dataSourceOpensearchSaRuleReferences(),
+			"opensearch_sa_sigma_rules":                  dataSourceOpensearchSaSigmaRules(),
 		},
 
 		ConfigureContextFunc: providerConfigure,
@@ -265,11 +443,22 @@ func providerConfigure(c context.Context, d *schema.ResourceData) (interface{},
 		return nil, diag.FromErr(err)
 	}
 
+	var saRequestLogger hclog.Logger
+	if d.Get("sa_structured_request_logs").(bool) {
+		saRequestLogger = hclog.New(&hclog.LoggerOptions{
+			Name:       "security-analytics",
+			Level:      hclog.Debug,
+			Output:     os.Stderr,
+			JSONFormat: true,
+		})
+	}
+
 	return &ProviderConf{
 		rawUrl:             rawUrl,
 		insecure:           d.Get("insecure").(bool),
 		sniffing:           d.Get("sniff").(bool),
 		healthchecking:     d.Get("healthcheck").(bool),
+		gzipRequests:       d.Get("gzip_requests").(bool),
 		cacertFile:         d.Get("cacert_file").(string),
 		username:           d.Get("username").(string),
 		password:           d.Get("password").(string),
@@ -292,9 +481,72 @@ func providerConfigure(c context.Context, d *schema.ResourceData) (interface{},
 		keyPemPath:              d.Get("client_key_path").(string),
 		hostOverride:            d.Get("host_override").(string),
 		proxy:                   d.Get("proxy").(string),
+		proxyUsername:           d.Get("proxy_username").(string),
+		proxyPassword:           d.Get("proxy_password").(string),
+		saWriteSemaphore:        make(chan struct{}, d.Get("sa_max_concurrent_writes").(int)),
+		saDetectorsIndex:        d.Get("sa_detectors_index").(string),
+		saCustomRulesIndex:      d.Get("sa_custom_rules_index").(string),
+		saRouting:               d.Get("sa_routing").(string),
+		saDetectorReads:         saDetectorReadsFromConfig(d),
+		saRequestLogger:         saRequestLogger,
+		saRequestSettings:       saRequestSettingsFromConfig(d),
+		keepServerFields:        expandStringList(d.Get("keep_server_fields").([]interface{})),
+		defaultRuleCategory:     d.Get("default_rule_category").(string),
+		managedByTag:            d.Get("managed_by_tag").(string),
 	}, nil
 }
 
+// saDetectorReadsFromConfig returns the batcher of detector reads, nil when
+// sa_detector_read_batch_size disables batching.
+func saDetectorReadsFromConfig(d *schema.ResourceData) *saDetectorBatcher {
+	size := d.Get("sa_detector_read_batch_size").(int)
+	if size == 0 {
+		return nil
+	}
+	return newSaDetectorBatcher(size)
+}
+
+// saRequestSettingsFromConfig applies the sa_request_overrides on top of the
+// global sa_request_timeout and sa_request_max_retries.
+func saRequestSettingsFromConfig(d *schema.ResourceData) map[saOperation]saRequestSettings {
+	defaults := saRequestSettings{
+		timeout:    time.Duration(d.Get("sa_request_timeout").(int)) * time.Second,
+		maxRetries: d.Get("sa_request_max_retries").(int),
+	}
+
+	settings := make(map[saOperation]saRequestSettings, len(saOperations))
+	for _, op := range saOperations {
+		settings[op] = defaults
+	}
+	for _, raw := range d.Get("sa_request_overrides").([]interface{}) {
+		override := raw.(map[string]interface{})
+		op := saOperation(override["operation"].(string))
+		opSettings := settings[op]
+		if timeout := override["timeout"].(int); timeout >= 0 {
+			opSettings.timeout = time.Duration(timeout) * time.Second
+		}
+		if maxRetries := override["max_retries"].(int); maxRetries >= 0 {
+			opSettings.maxRetries = maxRetries
+		}
+		settings[op] = opSettings
+	}
+	return settings
+}
+
+// acquireSaWrite blocks until a security analytics write slot is available
+// and returns the function releasing it. Without a configured limit it
+// returns immediately.
+func (conf *ProviderConf) acquireSaWrite() func() {
+	if conf.saWriteSemaphore == nil {
+		return func() {}
+	}
+
+	conf.saWriteSemaphore <- struct{}{}
+	return func() {
+		<-conf.saWriteSemaphore
+	}
+}
+
 func getClient(conf *ProviderConf) (*elastic7.Client, error) {
 	opts := []elastic7.ClientOptionFunc{
 		elastic7.SetURL(conf.rawUrl),
@@ -302,6 +554,9 @@ func getClient(conf *ProviderConf) (*elastic7.Client, error) {
 		elastic7.SetSniff(conf.sniffing),
 		elastic7.SetHealthcheck(conf.healthchecking),
 	}
+	if conf.gzipRequests && !conf.gzipRejected.Load() {
+		opts = append(opts, elastic7.SetGzip(true))
+	}
 
 	if conf.parsedUrl.User.Username() != "" {
 		p, _ := conf.parsedUrl.User.Password()
@@ -513,15 +768,12 @@ func awsHttpClient(region string, conf *ProviderConf, headers map[string]string)
 		return nil, err
 	}
 
-	// Set the proxy URL after configuring AWS credentials since the proxy
+	// Set the proxy after configuring AWS credentials since the proxy
 	// should be not used for credential sources that call a URL like ECS Task
 	// Roles or EC2 Instance Roles.
-	if conf.proxy != "" {
-		proxyURL, _ := url.Parse(conf.proxy)
-		transport, _ := session.Config.HTTPClient.Transport.(*http.Transport)
-		transport.Proxy = http.ProxyURL(proxyURL)
-		session.Config.HTTPClient.Transport = transport
-	}
+	transport, _ := session.Config.HTTPClient.Transport.(*http.Transport)
+	transport.Proxy = proxyFunc(conf)
+	session.Config.HTTPClient.Transport = transport
 
 	signer := awssigv4.NewSigner(session.Config.Credentials)
 	client, err := aws_signing_client.New(signer, session.Config.HTTPClient, conf.awsSig4Service, region)
@@ -550,11 +802,7 @@ func tokenHttpClient(conf *ProviderConf, headers map[string]string) *http.Client
 
 	// Wrapper to inject headers as needed
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	// Configure a proxy URL if one is provided.
-	if conf.proxy != "" {
-		proxyURL, _ := url.Parse(conf.proxy)
-		transport.Proxy = http.ProxyURL(proxyURL)
-	}
+	transport.Proxy = proxyFunc(conf)
 
 	rt := WithHeader(transport)
 	rt.hostOverride = conf.hostOverride
@@ -604,11 +852,7 @@ func tlsHttpClient(conf *ProviderConf, headers map[string]string) *http.Client {
 	}
 
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	// Configure a proxy URL if one is provided.
-	if conf.proxy != "" {
-		proxyURL, _ := url.Parse(conf.proxy)
-		transport.Proxy = http.ProxyURL(proxyURL)
-	}
+	transport.Proxy = proxyFunc(conf)
 
 	rt := WithHeader(transport)
 	rt.hostOverride = conf.hostOverride
@@ -621,6 +865,31 @@ func tlsHttpClient(conf *ProviderConf, headers map[string]string) *http.Client {
 	return client
 }
 
+// proxyFunc returns the proxy of the transports of the provider: the proxy
+// URL of the provider with the proxy credentials when set, or else the proxy
+// of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. Unlike
+// http.ProxyFromEnvironment, the environment is read for each client rather
+// than once per process.
+func proxyFunc(conf *ProviderConf) func(*http.Request) (*url.URL, error) {
+	if conf.proxy == "" {
+		proxy := httpproxy.FromEnvironment().ProxyFunc()
+		return func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+
+	proxyURL, err := url.Parse(conf.proxy)
+	if err != nil {
+		return func(*http.Request) (*url.URL, error) {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+	}
+	if conf.proxyUsername != "" {
+		proxyURL.User = url.UserPassword(conf.proxyUsername, conf.proxyPassword)
+	}
+	return http.ProxyURL(proxyURL)
+}
+
 func defaultHttpClient(conf *ProviderConf, headers map[string]string) *http.Client {
 	// Setup TLS options
 	tlsConfig := &tls.Config{}
@@ -631,11 +900,7 @@ func defaultHttpClient(conf *ProviderConf, headers map[string]string) *http.Clie
 	}
 
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	// Configure a proxy URL if one is provided.
-	if conf.proxy != "" {
-		proxyURL, _ := url.Parse(conf.proxy)
-		transport.Proxy = http.ProxyURL(proxyURL)
-	}
+	transport.Proxy = proxyFunc(conf)
 
 	// Wrapper to inject headers as needed
 	rt := WithHeader(transport)
@@ -2,16 +2,20 @@ package provider
 
 import (
 	"context"
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	elastic7 "github.com/olivere/elastic/v7"
 )
 
 var testAccProviders map[string]*schema.Provider
@@ -64,9 +68,21 @@ func TestProvider_impl(t *testing.T) {
 	var _ = Provider()
 }
 
+// testAccStartCluster starts a local cluster for acceptance tests run without
+// OPENSEARCH_URL. It is only set when building with the testcluster tag.
+var testAccStartCluster func() (string, error)
+
 func testAccPreCheck(t *testing.T) {
 	if v := os.Getenv("OPENSEARCH_URL"); v == "" {
-		t.Fatal("OPENSEARCH_URL must be set for acceptance tests")
+		if testAccStartCluster == nil {
+			t.Fatal("OPENSEARCH_URL must be set for acceptance tests, or build them with -tags testcluster")
+		}
+
+		url, err := testAccStartCluster()
+		if err != nil {
+			t.Fatalf("error starting the test cluster: %+v", err)
+		}
+		os.Setenv("OPENSEARCH_URL", url)
 	}
 }
 
@@ -357,6 +373,126 @@ func TestAWSSocksProxy(t *testing.T) {
 	}
 }
 
+// Given:
+// 1. A cluster only reachable through an authenticated proxy
+// 2. The proxy set either with the provider options or the environment
+//
+// This tests that: the requests of the client go through the proxy, with its
+// credentials, and that NO_PROXY bypasses the proxy of the environment.
+func TestProxy(t *testing.T) {
+	type received struct {
+		host string
+		auth string
+	}
+	var requests []received
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, received{host: r.URL.Host, auth: r.Header.Get("Proxy-Authorization")})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(proxy.Close)
+
+	// the host is never resolved, only the proxy knows it
+	rawUrl := "http://opensearch.test:9200"
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+
+	for _, tc := range []struct {
+		name  string
+		proxy string
+		user  string
+		token string
+		env   string
+		auth  string
+	}{
+		{
+			name:  "provider options",
+			proxy: proxy.URL,
+			user:  "user",
+			auth:  credentials,
+		},
+		{
+			name:  "credentials in the proxy URL",
+			proxy: strings.Replace(proxy.URL, "http://", "http://user:secret@", 1),
+			token: "t",
+			auth:  credentials,
+		},
+		{
+			name: "environment",
+			env:  proxy.URL,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("HTTP_PROXY", tc.env)
+			t.Setenv("NO_PROXY", "")
+			requests = nil
+			conf := &ProviderConf{
+				rawUrl:        rawUrl,
+				parsedUrl:     parsedUrl,
+				osVersion:     "2.11.0",
+				proxy:         tc.proxy,
+				proxyUsername: tc.user,
+				proxyPassword: "secret",
+				token:         tc.token,
+				tokenName:     "Bearer",
+			}
+
+			client, err := getClient(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.PerformRequest(context.Background(), elastic7.PerformRequestOptions{Method: "GET", Path: "/_plugins/_security_analytics/detectors/_search"}); err != nil {
+				t.Fatal(err)
+			}
+			expected := []received{{host: parsedUrl.Host, auth: tc.auth}}
+			if !reflect.DeepEqual(requests, expected) {
+				t.Errorf("expected the proxy to receive %+v, got %+v", expected, requests)
+			}
+		})
+	}
+
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", parsedUrl.Hostname())
+	proxyURL, err := proxyFunc(&ProviderConf{})(&http.Request{URL: parsedUrl})
+	if err != nil || proxyURL != nil {
+		t.Errorf("expected NO_PROXY to bypass the proxy, got %v, %v", proxyURL, err)
+	}
+}
+
+// Given:
+// 1. A limit of one concurrent security analytics write
+//
+// This tests that: a second write waits until the first one releases its slot.
+func TestAcquireSaWrite(t *testing.T) {
+	conf := &ProviderConf{saWriteSemaphore: make(chan struct{}, 1)}
+
+	release := conf.acquireSaWrite()
+	acquired := make(chan struct{})
+	go func() {
+		defer conf.acquireSaWrite()()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second write acquired a slot while the limit was reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second write did not acquire a slot after release")
+	}
+
+	// without a configured limit acquiring never blocks
+	(&ProviderConf{}).acquireSaWrite()()
+}
+
 type mockServer struct {
 	ResponseFixturePath string
 	ExpectedAccessKeyId string
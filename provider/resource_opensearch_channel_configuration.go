@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
@@ -150,6 +152,51 @@ func resourceOpensearchOpenDistroGetChannelConfiguration(channelConfigurationID
 	return response, err
 }
 
+// resourceOpensearchChannelConfigurationIDByName returns the ID of the
+// channel configuration with the given name. The name filter of the
+// notifications API also matches similar names, so the results are compared
+// with the name exactly.
+func resourceOpensearchChannelConfigurationIDByName(name string, m interface{}) (string, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return "", err
+	}
+	res, err := osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/_plugins/_notifications/configs",
+		Params: url.Values{"name": []string{name}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Configs []struct {
+			ID     string `json:"config_id"`
+			Config struct {
+				Name string `json:"name"`
+			} `json:"config"`
+		} `json:"config_list"`
+	}
+	if err := json.Unmarshal(res.Body, &response); err != nil {
+		return "", fmt.Errorf("error unmarshalling channel configurations: %+v: %+v", err, res.Body)
+	}
+
+	var ids []string
+	for _, config := range response.Configs {
+		if config.Config.Name == name {
+			ids = append(ids, config.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no notification channel named %q found", name)
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("several notification channels are named %q: %s", name, strings.Join(ids, ", "))
+	}
+	return ids[0], nil
+}
+
 func resourceOpensearchOpenDistroPostChannelConfiguration(d *schema.ResourceData, m interface{}) (*channelConfigurationCreationResponse, error) {
 	channelConfigurationJSON := d.Get("body").(string)
 
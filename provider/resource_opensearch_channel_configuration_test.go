@@ -2,6 +2,9 @@ package provider
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -347,3 +350,34 @@ resource "opensearch_channel_configuration" "email_channel_configuration" {
 EOF
 }
 `
+
+func TestChannelConfigurationIDByName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "" {
+			t.Errorf("expected a name filter, got %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"config_list": [
+  {"config_id": "a", "config": {"name": "ops slack"}},
+  {"config_id": "b", "config": {"name": "ops"}}
+]}`))
+	}))
+	defer server.Close()
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	id, err := resourceOpensearchChannelConfigurationIDByName("ops", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "b" {
+		t.Errorf("expected the channel named exactly ops, got %s", id)
+	}
+	if _, err := resourceOpensearchChannelConfigurationIDByName("dev", conf); err == nil {
+		t.Error("expected an error for a channel that does not exist")
+	}
+}
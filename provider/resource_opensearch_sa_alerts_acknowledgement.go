@@ -0,0 +1,365 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/olivere/elastic/uritemplates"
+)
+
+// the number of alerts requested per page when searching alerts, and
+// acknowledged per request. Acknowledge requests are further bounded by
+// sa_max_concurrent_writes.
+const (
+	saAlertsPageSize               = 100
+	saAlertsAcknowledgeBatchSize   = 100
+	saAlertsAcknowledgeConcurrency = 4
+)
+
+func resourceOpenSearchSaAlertsAcknowledgement() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Acknowledges security analytics alerts of a detector, either listed in `alert_ids` or all active alerts matching `filter`. The alerts are acknowledged once, when the resource is created: later applies neither search nor acknowledge alerts again, and destroying the resource only removes it from the state since alerts cannot be unacknowledged. Changing any argument acknowledges alerts again.",
+		CreateContext: resourceOpensearchSaAlertsAcknowledgementCreate,
+		ReadContext:   resourceOpensearchSaAlertsAcknowledgementRead,
+		DeleteContext: resourceOpensearchSaAlertsAcknowledgementDelete,
+		Schema: map[string]*schema.Schema{
+			"detector_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The ID of the detector the alerts belong to.",
+			},
+			"alert_ids": {
+				Type:         schema.TypeSet,
+				Optional:     true,
+				ForceNew:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ExactlyOneOf: []string{"alert_ids", "filter"},
+				Description:  "The IDs of the alerts to acknowledge.",
+			},
+			"filter": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				MaxItems:    1,
+				Description: "Acknowledges every active alert of the detector matching the filter. The alerts are searched page by page, then acknowledged by ID.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"severity": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							ValidateFunc: validation.StringInSlice([]string{"1", "2", "3", "4", "5"}, false),
+							Description:  "Only acknowledge alerts of this severity, from `1` (highest) to `5`.",
+						},
+						"start_time": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							ValidateFunc: validation.IsRFC3339Time,
+							Description:  "Only acknowledge alerts started at or after this time (RFC 3339).",
+						},
+						"end_time": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ForceNew:     true,
+							ValidateFunc: validation.IsRFC3339Time,
+							Description:  "Only acknowledge alerts started at or before this time (RFC 3339). It is required so that the alerts matched do not depend on when the resource is applied.",
+						},
+					},
+				},
+			},
+			"acknowledged_alert_ids": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the alerts acknowledged.",
+			},
+			"acknowledged_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of alerts acknowledged.",
+			},
+			"failed_alert_ids": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the alerts that could not be acknowledged, because the cluster failed to or could not find them.",
+			},
+			"matched_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of alerts listed in `alert_ids` or matching `filter`.",
+			},
+		},
+	}
+}
+
+// saAlertsFilter selects the active alerts of a detector to acknowledge. The
+// zero value matches every active alert.
+type saAlertsFilter struct {
+	Severity string
+	// zero when unbounded
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// matches reports whether an alert started at startTime falls within the
+// time range of the filter.
+func (f saAlertsFilter) matches(startTime time.Time) bool {
+	if !f.StartTime.IsZero() && startTime.Before(f.StartTime) {
+		return false
+	}
+	return f.EndTime.IsZero() || !startTime.After(f.EndTime)
+}
+
+func expandSaAlertsFilter(raw map[string]interface{}) (saAlertsFilter, error) {
+	filter := saAlertsFilter{Severity: raw["severity"].(string)}
+	var err error
+	if v := raw["start_time"].(string); v != "" {
+		if filter.StartTime, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("error parsing start_time: %+v", err)
+		}
+	}
+	if filter.EndTime, err = time.Parse(time.RFC3339, raw["end_time"].(string)); err != nil {
+		return filter, fmt.Errorf("error parsing end_time: %+v", err)
+	}
+	return filter, nil
+}
+
+func resourceOpensearchSaAlertsAcknowledgementCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	detectorID := d.Get("detector_id").(string)
+
+	var ids []string
+	if filters := d.Get("filter").([]interface{}); len(filters) > 0 && filters[0] != nil {
+		filter, err := expandSaAlertsFilter(filters[0].(map[string]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if ids, err = resourceOpensearchSaAlertsSearch(detectorID, filter, m); err != nil {
+			return diag.Errorf("error searching alerts of detector %s: %+v", detectorID, err)
+		}
+	} else {
+		ids = expandStringList(d.Get("alert_ids").(*schema.Set).List())
+	}
+	sort.Strings(ids)
+
+	acknowledged, failed, err := resourceOpensearchSaAlertsAcknowledgeBatches(detectorID, ids, m)
+	log.Printf("[INFO] Acknowledged %d of the %d alerts matched for detector %s", len(acknowledged), len(ids), detectorID)
+
+	// even on failure, the alerts acknowledged are recorded
+	d.SetId(resourceOpensearchSaAlertsAcknowledgementID(d))
+	ds := &resourceDataSetter{d: d}
+	ds.set("acknowledged_alert_ids", acknowledged)
+	ds.set("acknowledged_count", len(acknowledged))
+	ds.set("failed_alert_ids", failed)
+	ds.set("matched_count", len(ids))
+	if ds.err != nil {
+		return diag.FromErr(ds.err)
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return saAlertsNotAcknowledgedDiagnostics(detectorID, failed)
+}
+
+// saAlertsNotAcknowledgedDiagnostics warns about the alerts the cluster did
+// not acknowledge.
+func saAlertsNotAcknowledgedDiagnostics(detectorID string, failed []string) diag.Diagnostics {
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Some alerts were not acknowledged",
+		Detail:   fmt.Sprintf("The cluster did not acknowledge alerts %s of detector %s, either because it failed to or because they no longer exist.", strings.Join(failed, ", "), detectorID),
+	}}
+}
+
+// resourceOpensearchSaAlertsAcknowledgementID derives the ID from the
+// arguments, so that the same alerts map to the same ID.
+func resourceOpensearchSaAlertsAcknowledgementID(d *schema.ResourceData) string {
+	parts := []string{d.Get("detector_id").(string)}
+	if filters := d.Get("filter").([]interface{}); len(filters) > 0 && filters[0] != nil {
+		filter := filters[0].(map[string]interface{})
+		parts = append(parts, filter["severity"].(string), filter["start_time"].(string), filter["end_time"].(string))
+	} else {
+		ids := expandStringList(d.Get("alert_ids").(*schema.Set).List())
+		sort.Strings(ids)
+		parts = append(parts, ids...)
+	}
+	return hashSum(strings.Join(parts, ","))
+}
+
+func resourceOpensearchSaAlertsAcknowledgementRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceOpensearchSaAlertsAcknowledgementDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
+
+// resourceOpensearchSaAlertsSearch collects the IDs of the active alerts of a
+// detector matching filter. All pages are read before any alert is
+// acknowledged, as acknowledging alerts removes them from the active ones and
+// would shift the pages. The alerts endpoint filters by severity only, the
+// time range is applied to the start time of each alert.
+func resourceOpensearchSaAlertsSearch(detectorID string, filter saAlertsFilter, m interface{}) ([]string, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("detector_id", detectorID)
+	params.Set("alertState", "ACTIVE")
+	params.Set("severityLevel", "ALL")
+	if filter.Severity != "" {
+		params.Set("severityLevel", filter.Severity)
+	}
+	params.Set("sortString", "start_time")
+	params.Set("sortOrder", "asc")
+	params.Set("size", strconv.Itoa(saAlertsPageSize))
+
+	ids := make([]string, 0)
+	for read := 0; ; {
+		params.Set("startIndex", strconv.Itoa(read))
+		res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", joinURLPath(saAPIPath, "alerts"), params, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		var page SaAlertsResponse
+		if err := json.Unmarshal(res.Body, &page); err != nil {
+			return nil, fmt.Errorf("error unmarshalling alerts: %+v", err)
+		}
+
+		for _, alert := range page.Alerts {
+			if filter.StartTime.IsZero() && filter.EndTime.IsZero() {
+				ids = append(ids, alert.ID)
+				continue
+			}
+			startTime, err := alert.startTime()
+			if err != nil {
+				return nil, fmt.Errorf("error reading start time of alert %s: %+v", alert.ID, err)
+			}
+			if filter.matches(startTime) {
+				ids = append(ids, alert.ID)
+			}
+		}
+		read += len(page.Alerts)
+		if len(page.Alerts) == 0 || read >= page.TotalAlerts {
+			return ids, nil
+		}
+	}
+}
+
+// resourceOpensearchSaAlertsAcknowledgeBatches acknowledges alerts of a
+// detector saAlertsAcknowledgeBatchSize at a time, sending up to
+// saAlertsAcknowledgeConcurrency requests at once. It returns the IDs of the
+// alerts acknowledged and of those the cluster failed to acknowledge or could
+// not find, including when an error interrupted the acknowledgement.
+func resourceOpensearchSaAlertsAcknowledgeBatches(detectorID string, ids []string, m interface{}) ([]string, []string, error) {
+	batches := (len(ids) + saAlertsAcknowledgeBatchSize - 1) / saAlertsAcknowledgeBatchSize
+
+	var mu sync.Mutex
+	acknowledged := make([]string, 0, len(ids))
+	failed := make([]string, 0)
+	err := forEachConcurrently(batches, saAlertsAcknowledgeConcurrency, func(i int) error {
+		start := i * saAlertsAcknowledgeBatchSize
+		end := start + saAlertsAcknowledgeBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		res, err := resourceOpensearchSaAlertsAcknowledge(detectorID, ids[start:end], m)
+		if err != nil {
+			return fmt.Errorf("error acknowledging alerts of detector %s: %+v", detectorID, err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, alert := range res.Acknowledged {
+			acknowledged = append(acknowledged, alert.ID)
+		}
+		for _, alert := range res.Failed {
+			failed = append(failed, alert.ID)
+		}
+		failed = append(failed, res.Missing...)
+		return nil
+	})
+	sort.Strings(acknowledged)
+	return acknowledged, failed, err
+}
+
+// resourceOpensearchSaAlertsAcknowledge acknowledges alerts of a detector.
+func resourceOpensearchSaAlertsAcknowledge(detectorID string, ids []string, m interface{}) (*SaAlertsAcknowledgeResponse, error) {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	body, err := json.Marshal(map[string]interface{}{"alerts": ids})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling alert IDs: %+v", err)
+	}
+
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}/_acknowledge/alerts"), map[string]string{
+		"id": detectorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error building URL path for alerts: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationUpdate, saRequestOptions("POST", path, nil, string(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(SaAlertsAcknowledgeResponse)
+	if err := json.Unmarshal(res.Body, response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling acknowledge response: %+v", err)
+	}
+	return response, nil
+}
+
+type SaAlert struct {
+	ID string `json:"id"`
+	// epoch milliseconds or an ISO 8601 date depending on the version
+	StartTime interface{} `json:"start_time"`
+}
+
+func (a SaAlert) startTime() (time.Time, error) {
+	switch v := a.StartTime.(type) {
+	case float64:
+		return time.UnixMilli(int64(v)), nil
+	case string:
+		return time.Parse(time.RFC3339, v)
+	}
+	return time.Time{}, fmt.Errorf("unexpected start time %v", a.StartTime)
+}
+
+type SaAlertsResponse struct {
+	TotalAlerts int       `json:"total_alerts"`
+	Alerts      []SaAlert `json:"alerts"`
+}
+
+type SaAlertsAcknowledgeResponse struct {
+	Acknowledged []SaAlert `json:"acknowledged"`
+	Failed       []SaAlert `json:"failed"`
+	Missing      []string  `json:"missing"`
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestSaAlertsAcknowledgementFilter(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		// the first page, with an alert started before start_time
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_alerts":3,"alerts":[{"id":"a0","start_time":1717199999000},{"id":"a1","start_time":1717200000000}]}`},
+		// the second page, with an alert started after end_time
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_alerts":3,"alerts":[{"id":"a2","start_time":"2024-06-30T00:00:00Z"}]}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"acknowledged":[{"id":"a1"}],"failed":[],"missing":[]}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaAlertsAcknowledgement().Schema, map[string]interface{}{
+		"detector_id": "d1",
+		"filter": []interface{}{map[string]interface{}{
+			"severity":   "1",
+			"start_time": "2024-06-01T00:00:00Z",
+			"end_time":   "2024-06-02T00:00:00Z",
+		}},
+	})
+	if diags := resourceOpensearchSaAlertsAcknowledgementCreate(context.Background(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+
+	if len(cluster.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(cluster.requests))
+	}
+	for i, startIndex := range []string{"0", "2"} {
+		query := cluster.requests[i].Query
+		if query.Get("startIndex") != startIndex || query.Get("severityLevel") != "1" || query.Get("alertState") != "ACTIVE" || query.Get("detector_id") != "d1" {
+			t.Errorf("unexpected query of page %d: %v", i, query)
+		}
+	}
+	ack := cluster.lastRequest(t)
+	if ack.Method != "POST" || ack.Path != "/_plugins/_security_analytics/detectors/d1/_acknowledge/alerts" || ack.Body != `{"alerts":["a1"]}` {
+		t.Errorf("unexpected acknowledge request: %+v", ack)
+	}
+
+	if got := expandStringList(d.Get("acknowledged_alert_ids").(*schema.Set).List()); !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("expected acknowledged_alert_ids [a1], got %v", got)
+	}
+	if d.Get("matched_count").(int) != 1 || d.Get("acknowledged_count").(int) != 1 {
+		t.Errorf("unexpected counts: matched %v, acknowledged %v", d.Get("matched_count"), d.Get("acknowledged_count"))
+	}
+}
+
+func TestSaAlertsAcknowledgementIDs(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"acknowledged":[{"id":"a1"}],"failed":[{"id":"a2"}],"missing":["a3"]}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaAlertsAcknowledgement().Schema, map[string]interface{}{
+		"detector_id": "d1",
+		"alert_ids":   []interface{}{"a3", "a1", "a2"},
+	})
+	diags := resourceOpensearchSaAlertsAcknowledgementCreate(context.Background(), d, conf)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+	if len(diags) != 1 {
+		t.Errorf("expected a warning for the alerts not acknowledged, got %+v", diags)
+	}
+
+	if len(cluster.requests) != 1 || cluster.lastRequest(t).Body != `{"alerts":["a1","a2","a3"]}` {
+		t.Errorf("unexpected requests: %+v", cluster.requests)
+	}
+	if got := d.Get("failed_alert_ids").(*schema.Set).Len(); got != 2 {
+		t.Errorf("expected 2 failed alerts, got %d", got)
+	}
+	if d.Get("matched_count").(int) != 3 || d.Get("acknowledged_count").(int) != 1 {
+		t.Errorf("unexpected counts: matched %v, acknowledged %v", d.Get("matched_count"), d.Get("acknowledged_count"))
+	}
+}
@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/olivere/elastic/uritemplates"
+)
+
+// the time window the security analytics plugin applies to correlation rules
+// created without one, in milliseconds
+const saCorrelationDefaultTimeWindow = 300000
+
+func resourceOpenSearchSaCorrelationRule() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Provides an OpenSearch security analytics correlation rule, which links the findings of detectors of different log types raised within a time window. Before each create or update, the provider checks that the cluster has a detector of every `category` the queries reference, since the rule correlates nothing otherwise.",
+		CreateContext: resourceOpensearchSaCorrelationRuleCreate,
+		ReadContext:   resourceOpensearchSaCorrelationRuleRead,
+		UpdateContext: resourceOpensearchSaCorrelationRuleUpdate,
+		DeleteContext: resourceOpensearchSaCorrelationRuleDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the correlation rule.",
+			},
+			"time_window": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      saCorrelationDefaultTimeWindow,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The time window, in milliseconds, within which the findings matched by the queries are correlated.",
+			},
+			"query": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    2,
+				Description: "The queries correlated by the rule, one per log type. They are read back in the configured order.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"index": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The index or index pattern the documents of the query are searched in.",
+						},
+						"category": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The log type (`detector_type`) of the findings matched by the query.",
+						},
+						"query": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "A query string the documents must match, such as `EventID:4625`.",
+						},
+						"field": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringIsNotWhiteSpace,
+							Description:  "The field of the documents of the query holding the value correlated with the other queries, such as `source.ip`, mapped to the corresponding field of their log types.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceOpensearchSaCorrelationRuleCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := resourceOpensearchSaCorrelationRuleCheckCategories(d, m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	res, err := resourceOpensearchSaCorrelationRuleWrite(d, "POST", joinURLPath(saAPIPath, "correlation/rules"), saOperationCreate, m)
+	if err != nil {
+		return diag.Errorf("error creating correlation rule: %+v", err)
+	}
+
+	d.SetId(res.ID)
+	log.Printf("[INFO] Object ID: %s", d.Id())
+
+	// the rule returned by the create request is stored, the search only finds
+	// it once the rules index has been refreshed
+	return diag.FromErr(resourceOpensearchSaCorrelationRuleSetState(d, res.Rule))
+}
+
+func resourceOpensearchSaCorrelationRuleRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	res, err := resourceOpensearchSaCorrelationRuleGet(d.Id(), m)
+	if err != nil {
+		if IsSearchNotFound(err) {
+			log.Printf("[WARN] Security Analytics Correlation Rule (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+
+	return diag.FromErr(resourceOpensearchSaCorrelationRuleSetState(d, res.Rule))
+}
+
+func resourceOpensearchSaCorrelationRuleUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.HasChange("query") {
+		if err := resourceOpensearchSaCorrelationRuleCheckCategories(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "correlation/rules/{id}"), map[string]string{
+		"id": d.Id(),
+	})
+	if err != nil {
+		return diag.Errorf("error building URL path for correlation rule: %+v", err)
+	}
+
+	res, err := resourceOpensearchSaCorrelationRuleWrite(d, "PUT", path, saOperationUpdate, m)
+	if err != nil {
+		return diag.Errorf("error updating correlation rule %s: %+v", d.Id(), err)
+	}
+	return diag.FromErr(resourceOpensearchSaCorrelationRuleSetState(d, res.Rule))
+}
+
+func resourceOpensearchSaCorrelationRuleDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "correlation/rules/{id}"), map[string]string{
+		"id": d.Id(),
+	})
+	if err != nil {
+		return diag.Errorf("error building URL path for correlation rule: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	_, err = performSaRequest(ctx, m.(*ProviderConf), osClient, saOperationDelete, saRequestOptions("DELETE", path, nil, ""))
+	if IsSearchNotFound(err) {
+		log.Printf("[WARN] Security Analytics Correlation Rule (%s) already deleted", d.Id())
+		return nil
+	}
+	return diag.FromErr(err)
+}
+
+// resourceOpensearchSaCorrelationRuleWrite sends the configured rule with the
+// given method and decodes the rule the cluster returns.
+func resourceOpensearchSaCorrelationRuleWrite(d *schema.ResourceData, method, path string, op saOperation, m interface{}) (*SaCorrelationRuleResponse, error) {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	body, err := json.Marshal(expandSaCorrelationRule(d))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling correlation rule: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, op, saRequestOptions(method, path, nil, string(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(SaCorrelationRuleResponse)
+	if err := json.Unmarshal(res.Body, response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling correlation rule: %+v", err)
+	}
+	return response, nil
+}
+
+func resourceOpensearchSaCorrelationRuleGet(id string, m interface{}) (*SaCorrelationRuleResponse, error) {
+	query := map[string]interface{}{
+		"size": 1,
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{
+				"values": []string{id},
+			},
+		},
+	}
+
+	hit, err := saSearchOne(joinURLPath(saAPIPath, "correlation/rules/_search"), nil, query, "correlation_rule", id, m)
+	if err != nil {
+		return nil, err
+	}
+	return &SaCorrelationRuleResponse{
+		ID:      hit.ID,
+		Version: hit.Version,
+		Rule:    hit.Source,
+	}, nil
+}
+
+// resourceOpensearchSaCorrelationRuleCheckCategories fails when no detector
+// of the cluster has the category of one of the queries.
+func resourceOpensearchSaCorrelationRuleCheckCategories(d *schema.ResourceData, m interface{}) error {
+	types, err := resourceOpensearchSaDetectorTypes(m)
+	if err != nil {
+		return fmt.Errorf("error listing detector types: %+v", err)
+	}
+
+	existing := make([]string, 0, len(types))
+	for _, t := range types {
+		existing = append(existing, strings.ToLower(t.(map[string]interface{})["name"].(string)))
+	}
+
+	var missing []string
+	for _, q := range d.Get("query").([]interface{}) {
+		category := q.(map[string]interface{})["category"].(string)
+		if !containsString(existing, strings.ToLower(category)) && !containsString(missing, category) {
+			missing = append(missing, category)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no detector has the categories %s referenced by the correlation rule", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandSaCorrelationRule builds the correlation rule document of the
+// configuration. Empty query strings and fields are left out.
+func expandSaCorrelationRule(d resourceGetter) map[string]interface{} {
+	queries := d.Get("query").([]interface{})
+	correlate := make([]interface{}, 0, len(queries))
+	for _, raw := range queries {
+		q := raw.(map[string]interface{})
+		query := map[string]interface{}{
+			"index":    q["index"],
+			"category": q["category"],
+		}
+		for _, key := range []string{"query", "field"} {
+			if v, _ := q[key].(string); v != "" {
+				query[key] = v
+			}
+		}
+		correlate = append(correlate, query)
+	}
+
+	return map[string]interface{}{
+		"name":        d.Get("name"),
+		"time_window": d.Get("time_window"),
+		"correlate":   correlate,
+	}
+}
+
+func resourceOpensearchSaCorrelationRuleSetState(d *schema.ResourceData, rule map[string]interface{}) error {
+	ds := &resourceDataSetter{d: d}
+	ds.set("name", rule["name"])
+	// rules created by versions without time windows are read without one
+	timeWindow := saCorrelationDefaultTimeWindow
+	if v, ok := rule["time_window"].(float64); ok {
+		timeWindow = int(v)
+	}
+	ds.set("time_window", timeWindow)
+	ds.set("query", orderSaCorrelationQueries(d.Get("query").([]interface{}), flattenSaCorrelationQueries(rule)))
+	return ds.err
+}
+
+// flattenSaCorrelationQueries reads the queries of a correlation rule
+// document. Missing fields are left empty.
+func flattenSaCorrelationQueries(rule map[string]interface{}) []interface{} {
+	correlate, _ := rule["correlate"].([]interface{})
+	queries := make([]interface{}, 0, len(correlate))
+	for _, raw := range correlate {
+		q, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		query := make(map[string]interface{}, 4)
+		for _, key := range []string{"index", "category", "query", "field"} {
+			value, _ := q[key].(string)
+			query[key] = value
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// orderSaCorrelationQueries returns the queries read from the cluster in the
+// order of the configured ones, when both hold the same queries, so that a
+// reordering by the cluster does not show up as a change. Otherwise the
+// queries are returned as read.
+func orderSaCorrelationQueries(configured, remote []interface{}) []interface{} {
+	if len(configured) != len(remote) {
+		return remote
+	}
+
+	key := func(q interface{}) string {
+		m := q.(map[string]interface{})
+		parts := make([]string, 0, 4)
+		for _, k := range []string{"index", "category", "query", "field"} {
+			v, _ := m[k].(string)
+			parts = append(parts, v)
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	used := make([]bool, len(remote))
+	ordered := make([]interface{}, 0, len(remote))
+	for _, c := range configured {
+		found := false
+		for i, r := range remote {
+			if !used[i] && key(c) == key(r) {
+				used[i] = true
+				ordered = append(ordered, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return remote
+		}
+	}
+	return ordered
+}
+
+type SaCorrelationRuleResponse struct {
+	ID      string                 `json:"_id"`
+	Version int                    `json:"_version"`
+	Rule    map[string]interface{} `json:"rule"`
+}
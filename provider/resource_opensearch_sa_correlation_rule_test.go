@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOpensearchSaCorrelationRule(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaCorrelationRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaCorrelationRule(300000),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCorrelationRuleExists("opensearch_sa_correlation_rule.test"),
+					resource.TestCheckResourceAttr("opensearch_sa_correlation_rule.test", "time_window", "300000"),
+					resource.TestCheckResourceAttr("opensearch_sa_correlation_rule.test", "query.#", "2"),
+					resource.TestCheckResourceAttr("opensearch_sa_correlation_rule.test", "query.0.category", "windows"),
+					resource.TestCheckResourceAttr("opensearch_sa_correlation_rule.test", "query.1.field", "sourceIPAddress"),
+				),
+			},
+			{
+				Config: testAccOpensearchSaCorrelationRule(600000),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCorrelationRuleExists("opensearch_sa_correlation_rule.test"),
+					resource.TestCheckResourceAttr("opensearch_sa_correlation_rule.test", "time_window", "600000"),
+				),
+			},
+			{
+				ResourceName:      "opensearch_sa_correlation_rule.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testCheckOpensearchSaCorrelationRuleExists(name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("Not found: %s", name)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No correlation rule ID is set")
+		}
+
+		_, err := resourceOpensearchSaCorrelationRuleGet(rs.Primary.ID, testAccOpendistroProvider.Meta())
+		return err
+	}
+}
+
+func testCheckOpensearchSaCorrelationRuleDestroy(s *terraform.State) error {
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "opensearch_sa_correlation_rule" {
+			continue
+		}
+
+		_, err := resourceOpensearchSaCorrelationRuleGet(rs.Primary.ID, testAccOpendistroProvider.Meta())
+		if err != nil {
+			if IsSearchNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		return fmt.Errorf("Correlation rule %q still exists", rs.Primary.ID)
+	}
+
+	return nil
+}
+
+func TestSaCorrelationRule(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[]},"aggregations":{"detector_types":{"buckets":[{"key":"cloudtrail","doc_count":1},{"key":"windows","doc_count":2}]}}}`},
+		// the cluster returns the queries in another order
+		saFakeResponse{Status: http.StatusOK, Body: `{"_id":"c1","_version":1,"rule":{"name":"test","time_window":600000,"correlate":[
+  {"index":"cloudtrail","category":"cloudtrail","field":"sourceIPAddress"},
+  {"index":"windows","category":"windows","query":"EventID:4625"}
+]}}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaCorrelationRule().Schema, map[string]interface{}{
+		"name":        "test",
+		"time_window": 600000,
+		"query": []interface{}{
+			map[string]interface{}{"index": "windows", "category": "windows", "query": "EventID:4625"},
+			map[string]interface{}{"index": "cloudtrail", "category": "cloudtrail", "field": "sourceIPAddress"},
+		},
+	})
+	if diags := resourceOpensearchSaCorrelationRuleCreate(context.Background(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+
+	request := cluster.lastRequest(t)
+	expected := `{"correlate":[{"category":"windows","index":"windows","query":"EventID:4625"},{"category":"cloudtrail","field":"sourceIPAddress","index":"cloudtrail"}],"name":"test","time_window":600000}`
+	if request.Method != "POST" || request.Path != "/_plugins/_security_analytics/correlation/rules" || request.Body != expected {
+		t.Errorf("unexpected request %+v", request)
+	}
+	if d.Id() != "c1" {
+		t.Errorf("expected ID c1, got %s", d.Id())
+	}
+	if category := d.Get("query.0.category").(string); category != "windows" {
+		t.Errorf("expected the configured order of queries to be kept, got %s first", category)
+	}
+}
+
+func TestSaCorrelationRuleMissingCategory(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[]},"aggregations":{"detector_types":{"buckets":[{"key":"windows","doc_count":1}]}}}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaCorrelationRule().Schema, map[string]interface{}{
+		"name": "test",
+		"query": []interface{}{
+			map[string]interface{}{"index": "windows", "category": "windows"},
+			map[string]interface{}{"index": "dns", "category": "dns"},
+		},
+	})
+	diags := resourceOpensearchSaCorrelationRuleCreate(context.Background(), d, conf)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "dns") {
+		t.Errorf("expected an error about the dns category, got %+v", diags)
+	}
+	if len(cluster.requests) != 1 {
+		t.Errorf("expected the rule not to be created, got %d requests", len(cluster.requests))
+	}
+}
+
+func TestOrderSaCorrelationQueries(t *testing.T) {
+	a := map[string]interface{}{"index": "a", "category": "windows", "query": "", "field": ""}
+	b := map[string]interface{}{"index": "b", "category": "cloudtrail", "query": "", "field": ""}
+	c := map[string]interface{}{"index": "c", "category": "dns", "query": "", "field": ""}
+
+	for _, tc := range []struct {
+		name       string
+		configured []interface{}
+		remote     []interface{}
+		expected   []interface{}
+	}{
+		{name: "reordered", configured: []interface{}{a, b}, remote: []interface{}{b, a}, expected: []interface{}{a, b}},
+		{name: "changed", configured: []interface{}{a, b}, remote: []interface{}{c, a}, expected: []interface{}{c, a}},
+		{name: "import", configured: []interface{}{}, remote: []interface{}{b, a}, expected: []interface{}{b, a}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := orderSaCorrelationQueries(tc.configured, tc.remote); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func testAccOpensearchSaCorrelationRule(timeWindow int) string {
+	return fmt.Sprintf(`
+resource "opensearch_index" "windows" {
+  name               = "windows"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_index" "cloudtrail" {
+  name               = "cloudtrail"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "windows" {
+  body = <<EOF
+{
+  "name": "test-correlation-windows",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {"period": {"interval": 1, "unit": "MINUTES"}},
+  "inputs": [{"detector_input": {"description": "", "indices": ["windows"], "custom_rules": [], "pre_packaged_rules": []}}],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.windows]
+}
+
+resource "opensearch_sa_detector" "cloudtrail" {
+  body = <<EOF
+{
+  "name": "test-correlation-cloudtrail",
+  "detector_type": "cloudtrail",
+  "enabled": true,
+  "schedule": {"period": {"interval": 1, "unit": "MINUTES"}},
+  "inputs": [{"detector_input": {"description": "", "indices": ["cloudtrail"], "custom_rules": [], "pre_packaged_rules": []}}],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.cloudtrail]
+}
+
+resource "opensearch_sa_correlation_rule" "test" {
+  name        = "test-correlation"
+  time_window = %d
+
+  query {
+    index    = opensearch_index.windows.name
+    category = "windows"
+    query    = "EventID:4625"
+  }
+
+  query {
+    index    = opensearch_index.cloudtrail.name
+    category = "cloudtrail"
+    field    = "sourceIPAddress"
+  }
+
+  depends_on = [opensearch_sa_detector.windows, opensearch_sa_detector.cloudtrail]
+}
+`, timeWindow)
+}
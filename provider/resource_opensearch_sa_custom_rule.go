@@ -5,59 +5,316 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/olivere/elastic/uritemplates"
 	elastic7 "github.com/olivere/elastic/v7"
+	"gopkg.in/yaml.v2"
 )
 
 var saDetectorRuleSchema = map[string]*schema.Schema{
 	"body": {
-		Description: "The security analytics detector rule document containing a Sigma rule",
-		Type:        schema.TypeString,
-		Required:    true,
+		Description:      "The security analytics detector rule document containing a Sigma rule. Changes that leave the parsed YAML document unchanged, such as reformatting or comments, are ignored, so that they do not cause a forced update of the rule. Exactly one of `body` and `sigma` must be set.",
+		Type:             schema.TypeString,
+		Optional:         true,
+		ExactlyOneOf:     []string{"body", "sigma"},
+		DiffSuppressFunc: diffSuppressSaRuleBody,
+	},
+	"sigma": {
+		Description:  "The Sigma rule as attributes, serialized to YAML by the provider. The rule is read back into whichever of `body` and `sigma` is configured, and into `body` on import.",
+		Type:         schema.TypeList,
+		Optional:     true,
+		MaxItems:     1,
+		ExactlyOneOf: []string{"body", "sigma"},
+		Elem:         saSigmaRuleResource,
 	},
 	"category": {
-		Description: "A category of the detector rule",
+		Description: "A category of the detector rule, the log type it applies to: either a built-in log type such as `windows` or `cloudtrail`, or the name of a custom log type such as `my app/logs`. Defaults to the `default_rule_category` provider option, one of the two must be set. It is read back from the cluster, so that a rule moved to another category outside of Terraform shows up as drift.",
+		Type:        schema.TypeString,
+		Optional:    true,
+		Computed:    true,
+		DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+			return strings.EqualFold(old, new)
+		},
+		// custom log types may have any name, the cluster rejects unknown ones
+		ValidateFunc: validation.StringIsNotWhiteSpace,
+	},
+	"author": {
+		Description: "An `author` merged into the Sigma rule before it is submitted. The `author` declared by the rule is kept unless `force_metadata` is set.",
+		Type:        schema.TypeString,
+		Optional:    true,
+	},
+	"custom_metadata": {
+		Description:  "Top-level fields merged into the Sigma rule before it is submitted, such as `license`. The fields declared by the rule are kept unless `force_metadata` is set. The `title`, `id`, `logsource` and `detection` fields cannot be set, and `author` is set through the `author` argument.",
+		Type:         schema.TypeMap,
+		Optional:     true,
+		Elem:         &schema.Schema{Type: schema.TypeString},
+		ValidateFunc: validateSaRuleMetadataKeys,
+	},
+	"force_metadata": {
+		Description: "Replace the fields the rule declares with `author` and `custom_metadata`, instead of keeping them.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"force_delete": {
+		Description: "Delete the rule even while detectors reference it, which the cluster allows. By default, destroying a rule fails with the list of referencing detectors, which should be updated or destroyed first. Referencing the rule from the body of `opensearch_sa_detector`, for example with `${opensearch_sa_custom_rule.example.id}`, lets Terraform order this on its own.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"recreate_on_category_change": {
+		Description: "Move the rule to a new `category` by creating it in that category, pointing the detectors referencing the rule to the new rule and deleting the old one, instead of updating the rule in place, which leaves its detectors evaluating a rule of another log type. The ID of the rule changes, so detectors should reference the rule through `rule_id`, which is planned as unknown until the change is applied. The rule must declare a Sigma `id`, which lets an interrupted change be applied again without creating the rule twice.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"adopt_existing": {
+		Description: "On create, look for a custom rule declaring the same Sigma `id` as `body` and manage it instead of creating a second copy. The adopted rule is updated to match the configuration. Creation fails if several custom rules declare the id.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"status": {
+		Description: "The `status` declared by the Sigma rule. While it is `deprecated`, updates of the rule warn about the detectors still referencing it.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"body_json": {
+		Description: "The Sigma rule held by the cluster, parsed and encoded as JSON, so that its fields can be read with `jsondecode` instead of parsing YAML. Empty when the rule cannot be parsed.",
 		Type:        schema.TypeString,
-		Required:    true,
-		ValidateFunc: validation.StringInSlice([]string{
-			"cloudtrail",
-			"windows",
-		}, true),
+		Computed:    true,
+	},
+	"rule_id": {
+		Description: "The ID of the rule document, the same as `id`. It is what dependents such as the body of `opensearch_sa_detector` should reference: it is marked as changing in the plan whenever `recreate_on_category_change` gives the rule a new ID, so that the dependents are planned with the new ID.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"forced_update_detectors": {
+		Description: "The detectors referencing this rule that a pending update of `body`, `sigma` or `category` affects. Rule updates are forced, so these detectors pick up the new rule body immediately. The list is computed when the update is planned and cleared on each refresh, so the plan of every such update lists the detectors referencing the rule, even when they are the same as for the previous update.",
+		Type:        schema.TypeList,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+}
+
+var saSigmaRuleResource = &schema.Resource{
+	Schema: map[string]*schema.Schema{
+		"title": {
+			Description: "The title of the rule.",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"id": {
+			Description: "The Sigma `id` of the rule, a UUID.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"status": {
+			Description: "The maturity of the rule, such as `experimental`, `test`, `stable` or `deprecated`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"description": {
+			Description: "What the rule detects.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"author": {
+			Description: "The author of the rule.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"references": {
+			Description: "References to the source the rule was derived from.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"tags": {
+			Description: "The tags of the rule, for example `attack.t1078`.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"logsource": {
+			Description: "The log data the rule applies to.",
+			Type:        schema.TypeList,
+			Required:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"product": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"service": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"category": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"definition": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"detection": {
+			Description:      "The `detection` section of the rule as JSON, usually written with `jsonencode`, including its `condition`.",
+			Type:             schema.TypeString,
+			Required:         true,
+			DiffSuppressFunc: diffSuppressSigmaDetection,
+			ValidateFunc:     validateJSONObject,
+		},
+		"falsepositives": {
+			Description: "Known situations in which the rule matches legitimate activity.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"level": {
+			Description: "The severity of the rule, such as `low`, `medium`, `high` or `critical`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
 	},
 }
 
 func resourceOpenSearchSaDetectorRule() *schema.Resource {
 	return &schema.Resource{
-		Description: "Provides an OpenSearch security analytics detector rule. Please refer to the OpenSearch security analytics documentation for details.",
-		Create:      resourceOpensearchSaDetectorRuleCreate,
-		Read:        resourceOpensearchSaDetectorRuleRead,
-		Update:      resourceOpensearchSaDetectorRuleUpdate,
-		Delete:      resourceOpensearchSaDetectorRuleDelete,
-		Schema:      saDetectorRuleSchema,
+		Description:   "Provides an OpenSearch security analytics detector rule. Please refer to the OpenSearch security analytics documentation for details.",
+		CreateContext: resourceOpensearchSaDetectorRuleCreate,
+		ReadContext:   resourceOpensearchSaDetectorRuleRefresh,
+		UpdateContext: resourceOpensearchSaDetectorRuleUpdate,
+		DeleteContext: resourceOpensearchSaDetectorRuleDelete,
+		Schema:        saDetectorRuleSchema,
+		CustomizeDiff: resourceOpensearchSaDetectorRuleCustomizeDiff,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: resourceOpensearchSaDetectorRuleImport,
 		},
 	}
 }
 
-func resourceOpensearchSaDetectorRuleCreate(d *schema.ResourceData, m interface{}) error {
+// resourceOpensearchSaDetectorRuleImport sets the defaults of the
+// provider-side options, the rule itself is set by the read that follows.
+func resourceOpensearchSaDetectorRuleImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	for _, key := range []string{"adopt_existing", "force_metadata", "force_delete", "recreate_on_category_change"} {
+		if err := d.Set(key, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceOpensearchSaDetectorRuleCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.Get("adopt_existing").(bool) {
+		adopted, diags := resourceOpensearchSaDetectorRuleAdopt(ctx, d, m)
+		if adopted || diags.HasError() {
+			return diags
+		}
+	}
+
 	res, err := resourceOpensearchPostSaDetectorRule(d, m)
 
 	if err != nil {
 		log.Printf("[INFO] Failed to put security analytics detector rule: %+v", err)
-		return err
+		return diag.FromErr(err)
 	}
 
 	d.SetId(res.ID)
 	log.Printf("[INFO] Object ID: %s", d.Id())
 
-	return resourceOpensearchSaDetectorRuleRead(d, m)
+	return resourceOpensearchSaDetectorRuleRead(ctx, d, m)
+}
+
+// resourceOpensearchSaDetectorRuleDefaultCategory plans the
+// default_rule_category provider option as the category of rules that do not
+// configure one.
+func resourceOpensearchSaDetectorRuleDefaultCategory(d *schema.ResourceDiff, m interface{}) error {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() || !config.GetAttr("category").IsNull() {
+		return nil
+	}
+
+	category := m.(*ProviderConf).defaultRuleCategory
+	if category == "" {
+		return fmt.Errorf("category must be set, either on the rule or through the default_rule_category provider option")
+	}
+	if strings.EqualFold(d.Get("category").(string), category) {
+		return nil
+	}
+	return d.SetNew("category", category)
 }
 
-func resourceOpensearchSaDetectorRuleRead(d *schema.ResourceData, m interface{}) error {
+// resourceOpensearchSaDetectorRuleAdopt binds the resource to the custom rule
+// declaring the Sigma id of the configured body, if there is one, and
+// updates it when it differs from the configuration.
+func resourceOpensearchSaDetectorRuleAdopt(ctx context.Context, d *schema.ResourceData, m interface{}) (bool, diag.Diagnostics) {
+	configured, err := resourceOpensearchSaRuleBody(d, m)
+	if err != nil {
+		return false, diag.FromErr(err)
+	}
+
+	sigmaID := parseSigmaRuleHeader(configured).ID
+	if sigmaID == "" {
+		return false, diag.Errorf("adopt_existing requires the rule to declare a Sigma id")
+	}
+
+	rules, err := resourceOpensearchSaRulesBySigmaID(sigmaID, false, m)
+	if err != nil {
+		return false, diag.Errorf("error looking up custom rules with Sigma id %s: %+v", sigmaID, err)
+	}
+
+	if len(rules) == 0 {
+		return false, nil
+	}
+	if len(rules) > 1 {
+		ids := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			ids = append(ids, rule.ID)
+		}
+		return false, diag.Errorf("cannot adopt a custom rule with Sigma id %s, it is declared by several rules: %s", sigmaID, strings.Join(ids, ", "))
+	}
+
+	rule := rules[0]
+	d.SetId(rule.ID)
+	log.Printf("[INFO] Adopted security analytics detector rule %s with Sigma id %s", d.Id(), sigmaID)
+
+	body, _ := rule.Rule["rule"].(string)
+	category, _ := rule.Rule["category"].(string)
+	if diffSuppressSaRuleBody("body", body, configured, d) && strings.EqualFold(category, d.Get("category").(string)) {
+		return true, resourceOpensearchSaDetectorRuleRead(ctx, d, m)
+	}
+
+	return true, resourceOpensearchSaDetectorRuleUpdate(ctx, d, m)
+}
+
+// resourceOpensearchSaDetectorRuleRefresh reads the rule and clears
+// forced_update_detectors, which only describes the last update, so that the
+// next update planned shows the detectors it affects as a change.
+func resourceOpensearchSaDetectorRuleRefresh(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	diags := resourceOpensearchSaDetectorRuleRead(ctx, d, m)
+	if diags.HasError() || d.Id() == "" {
+		return diags
+	}
+	if err := d.Set("forced_update_detectors", []string{}); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	return diags
+}
+
+func resourceOpensearchSaDetectorRuleRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	res, err := resourceOpensearchSaDetectorRuleGet(d.Id(), m)
 
 	if err != nil {
@@ -67,95 +324,209 @@ func resourceOpensearchSaDetectorRuleRead(d *schema.ResourceData, m interface{})
 			return nil
 		}
 
-		return err
+		return diag.FromErr(err)
 	}
 
 	d.SetId(res.ID)
-	return d.Set("body", res.Rule["rule"])
+	ds := &resourceDataSetter{d: d}
+	ds.set("rule_id", res.ID)
+	remote, _ := res.Rule["rule"].(string)
+	// the configured rule is kept while the cluster holds the rule it is
+	// submitted as, so that the merged metadata does not show up as a change
+	configured, configErr := resourceOpensearchSaRuleBody(d, m)
+	if configErr == nil && configured != "" && saRuleNormalizer.equalYAML(remote, configured) {
+		log.Printf("[DEBUG] Security analytics detector rule %s matches the configured rule", d.Id())
+	} else if _, ok := d.GetOk("sigma"); ok {
+		sigma, err := flattenSaSigmaRule(removeSigmaRuleTag(remote, m.(*ProviderConf).managedByTag))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		ds.set("sigma", sigma)
+	} else {
+		// the managed_by_tag of the provider is not part of the configuration
+		ds.set("body", removeSigmaRuleTag(remote, m.(*ProviderConf).managedByTag))
+	}
+	if configErr == nil {
+		flattenSaRuleMetadata(ds, d, configured, remote)
+	}
+	ds.set("status", parseSigmaRuleHeader(res.Rule["rule"]).Status)
+	ds.set("body_json", sigmaRuleJSON(remote))
+	// the category may be changed outside of Terraform, the configured
+	// spelling is kept when it only differs in case
+	if category, ok := res.Rule["category"].(string); ok && category != "" && !strings.EqualFold(category, d.Get("category").(string)) {
+		ds.set("category", category)
+	}
+	return diag.FromErr(ds.err)
 }
 
-func resourceOpensearchSaDetectorRuleUpdate(d *schema.ResourceData, m interface{}) error {
-	_, err := resourceOpensearchPutSaDetectorRule(d, m)
+func resourceOpensearchSaDetectorRuleUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if d.HasChange("category") && d.Get("recreate_on_category_change").(bool) {
+		diags = resourceOpensearchSaDetectorRuleRecategorize(ctx, d, m)
+		if diags.HasError() {
+			return diags
+		}
+	} else if _, err := resourceOpensearchPutSaDetectorRule(d, m); err != nil {
+		return diag.FromErr(err)
+	}
 
-	if err != nil {
-		return err
+	diags = append(diags, resourceOpensearchSaDetectorRuleRead(ctx, d, m)...)
+	if d.Get("status").(string) == sigmaStatusDeprecated {
+		detectors, err := resourceOpensearchSaDetectorsReferencingRule(d.Id(), m)
+		if err != nil {
+			return append(diags, diag.Errorf("error looking up detectors referencing rule %s: %+v", d.Id(), err)...)
+		}
+		if len(detectors) > 0 {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Deprecated rule is still referenced by detectors",
+				Detail:   fmt.Sprintf("Rule %s is deprecated but the following detectors still use it: %s. Remove it from their inputs to stop evaluating it.", d.Id(), strings.Join(detectors, ", ")),
+			})
+		}
 	}
 
-	return resourceOpensearchSaDetectorRuleRead(d, m)
+	return diags
 }
 
-func resourceOpensearchSaDetectorRuleGet(SaDetectorRuleID string, m interface{}) (*SaDetectorRuleResponse, error) {
-	var err error
-	response := new(SaDetectorRuleResponse)
+// resourceOpensearchSaDetectorRuleRecategorize moves the rule to its new
+// category by creating it there, pointing the detectors referencing the old
+// rule to the new one, then deleting the old rule. A rule of the new category
+// declaring the same Sigma id, left by an interrupted recategorization, is
+// reused instead of being created again, so the operation can be retried.
+func resourceOpensearchSaDetectorRuleRecategorize(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	configured, err := resourceOpensearchSaRuleBody(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sigmaID := parseSigmaRuleHeader(configured).ID
+	if sigmaID == "" {
+		return diag.Errorf("recreate_on_category_change requires the rule to declare a Sigma id")
+	}
 
-	query := map[string]interface{}{
-		"size": 1,
-		"query": map[string]interface{}{
-			"ids": map[string]interface{}{
-				"values": []string{SaDetectorRuleID},
-			},
-		},
+	oldID := d.Id()
+	category := d.Get("category").(string)
+	rules, err := resourceOpensearchSaRulesBySigmaID(sigmaID, false, m)
+	if err != nil {
+		return diag.Errorf("error looking up custom rules with Sigma id %s: %+v", sigmaID, err)
+	}
+	newID := ""
+	for _, rule := range rules {
+		if existing, _ := rule.Rule["category"].(string); rule.ID != oldID && strings.EqualFold(existing, category) {
+			newID = rule.ID
+			log.Printf("[INFO] Reusing security analytics detector rule %s of category %s", newID, category)
+			break
+		}
+	}
+	if newID == "" {
+		res, err := resourceOpensearchPostSaDetectorRule(d, m)
+		if err != nil {
+			return diag.Errorf("error creating rule %s in category %s: %+v", oldID, category, err)
+		}
+		newID = res.ID
 	}
 
-	queryBody, err := json.Marshal(query)
+	detectors, err := resourceOpensearchSaDetectorsReplaceRule(oldID, newID, m)
 	if err != nil {
-		return response, fmt.Errorf("error marshalling query body: %+v", err)
+		return diag.Errorf("error pointing the detectors referencing rule %s to rule %s: %+v", oldID, newID, err)
 	}
+	log.Printf("[INFO] Replaced rule %s with rule %s in detectors: %s", oldID, newID, strings.Join(detectors, ", "))
 
-	log.Printf("[DEBUG] queryBody=%s", queryBody)
+	d.SetId(newID)
+	if err := resourceOpensearchDeleteSaDetectorRule(ctx, oldID, m); err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "The rule of the previous category could not be deleted",
+			Detail:   fmt.Sprintf("Rule %s was replaced with rule %s of category %s, but its deletion failed: %+v. No detector references it anymore, it can be deleted by hand.", oldID, newID, category, err),
+		}}
+	}
+	return nil
+}
 
-	osClient, err := getClient(m.(*ProviderConf))
-	if err != nil {
-		return nil, err
+// resourceOpensearchSaDetectorRuleCustomizeDiff surfaces the detectors that
+// reference a rule whenever an update, which is always sent with forced=true,
+// is planned for it.
+func resourceOpensearchSaDetectorRuleCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if err := resourceOpensearchSaDetectorRuleDefaultCategory(d, m); err != nil {
+		return err
 	}
-	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method:      "POST",
-		Path:        "/_plugins/_security_analytics/rules/_search?pre_packaged=false",
-		Body:        string(queryBody),
-		ContentType: "application/json",
-	})
-	if err != nil {
-		return response, err
+
+	if len(d.Get("sigma").([]interface{})) > 0 {
+		if !d.NewValueKnown("sigma.0.status") {
+			if err := d.SetNewComputed("status"); err != nil {
+				return err
+			}
+		} else if d.HasChange("sigma") {
+			if err := d.SetNew("status", d.Get("sigma.0.status")); err != nil {
+				return err
+			}
+		}
+	} else if !d.NewValueKnown("body") {
+		if err := d.SetNewComputed("status"); err != nil {
+			return err
+		}
+	} else if d.HasChange("body") {
+		if err := d.SetNew("status", parseSigmaRuleHeader(d.Get("body")).Status); err != nil {
+			return err
+		}
 	}
 
-	var searchResult querySearchResult
+	if d.Id() == "" || !d.HasChanges("body", "sigma", "category") {
+		return nil
+	}
 
-	if err := json.Unmarshal(res.Body, &searchResult); err != nil {
-		return response, fmt.Errorf("error unmarshalling search result: %+v", err)
+	// the rule is created again under a new ID
+	if d.HasChange("category") && d.Get("recreate_on_category_change").(bool) {
+		if err := d.SetNewComputed("rule_id"); err != nil {
+			return err
+		}
 	}
 
-	if searchResult.Hits.Total.Value == 0 {
-		return response, fmt.Errorf("no search results found for ID: %s", SaDetectorRuleID)
+	detectors, err := resourceOpensearchSaDetectorsReferencingRule(d.Id(), m)
+	if err != nil {
+		return fmt.Errorf("error looking up detectors referencing rule %s: %+v", d.Id(), err)
 	}
 
-	var rule map[string]interface{}
-	if err := json.Unmarshal(searchResult.Hits.Hits[0].Source, &rule); err != nil {
-		return response, fmt.Errorf("error unmarshalling rule source: %+v", err)
+	return d.SetNew("forced_update_detectors", detectors)
+}
+
+func resourceOpensearchSaDetectorRuleGet(SaDetectorRuleID string, m interface{}) (*SaDetectorRuleResponse, error) {
+	query := map[string]interface{}{
+		"size":  1,
+		"query": saIDsQuery([]string{SaDetectorRuleID}, "", m.(*ProviderConf).saRouting),
 	}
 
-	response.ID = searchResult.Hits.Hits[0].ID
-	response.Version = searchResult.Hits.Hits[0].Version
-	response.Rule = rule
+	path, params, err := saSearchPath(m.(*ProviderConf).saCustomRulesIndex, joinURLPath(saAPIPath, "rules/_search"), url.Values{
+		"pre_packaged": []string{"false"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	hit, err := saSearchOne(path, params, query, "rule", SaDetectorRuleID, m)
+	if err != nil {
+		return new(SaDetectorRuleResponse), err
+	}
 
+	response := &SaDetectorRuleResponse{
+		ID:      hit.ID,
+		Version: hit.Version,
+		Rule:    hit.Source,
+	}
 	log.Printf("[INFO] Response: %+v", response)
-	log.Printf("The version %v", response.Version)
-	return response, err
+	return response, nil
 }
 
 func resourceOpensearchPostSaDetectorRule(d *schema.ResourceData, m interface{}) (*SaDetectorRuleResponse, error) {
-	SaDetectorRuleBody := d.Get("body").(string)
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	SaDetectorRuleBody, err := resourceOpensearchSaRuleBody(d, m)
+	if err != nil {
+		return nil, err
+	}
 	Category := d.Get("category").(string)
 
-	var err error
 	response := new(SaDetectorRuleResponse)
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/rules?category={category}", map[string]string{
-		"category": Category,
-	})
-	if err != nil {
-		return response, fmt.Errorf("error building URL path for detector rule: %+v", err)
-	}
+	params := url.Values{"category": []string{Category}}
 
 	var body json.RawMessage
 	osClient, err := getClient(m.(*ProviderConf))
@@ -163,12 +534,7 @@ func resourceOpensearchPostSaDetectorRule(d *schema.ResourceData, m interface{})
 		return nil, err
 	}
 	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method:      "POST",
-		Path:        path,
-		Body:        SaDetectorRuleBody,
-		ContentType: "application/json",
-	})
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationCreate, saRequestOptions("POST", joinURLPath(saAPIPath, "rules"), params, SaDetectorRuleBody))
 	if err != nil {
 		return response, err
 	}
@@ -181,19 +547,26 @@ func resourceOpensearchPostSaDetectorRule(d *schema.ResourceData, m interface{})
 }
 
 func resourceOpensearchPutSaDetectorRule(d *schema.ResourceData, m interface{}) (*SaDetectorRuleResponse, error) {
-	SaDetectorRuleJSON := d.Get("body").(string)
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	SaDetectorRuleJSON, err := resourceOpensearchSaRuleBody(d, m)
+	if err != nil {
+		return nil, err
+	}
 	Category := d.Get("category").(string)
 
-	var err error
 	response := new(SaDetectorRuleResponse)
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/rules/{id}?category={category}&forced=true", map[string]string{
-		"id":       d.Id(),
-		"category": Category,
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "rules/{id}"), map[string]string{
+		"id": d.Id(),
 	})
 	if err != nil {
 		return response, fmt.Errorf("error building URL path for detector rule: %+v", err)
 	}
+	params := url.Values{
+		"category": []string{Category},
+		"forced":   []string{"true"},
+	}
 
 	var body json.RawMessage
 	osClient, err := getClient(m.(*ProviderConf))
@@ -201,12 +574,7 @@ func resourceOpensearchPutSaDetectorRule(d *schema.ResourceData, m interface{})
 		return nil, err
 	}
 	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method:      "PUT",
-		Path:        path,
-		Body:        SaDetectorRuleJSON,
-		ContentType: "application/json",
-	})
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationUpdate, saRequestOptions("PUT", path, params, SaDetectorRuleJSON))
 	if err != nil {
 		return response, err
 	}
@@ -219,11 +587,27 @@ func resourceOpensearchPutSaDetectorRule(d *schema.ResourceData, m interface{})
 	return response, nil
 }
 
-func resourceOpensearchSaDetectorRuleDelete(d *schema.ResourceData, m interface{}) error {
-	var err error
+func resourceOpensearchSaDetectorRuleDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("force_delete").(bool) {
+		detectors, err := resourceOpensearchSaDetectorsReferencingRule(d.Id(), m)
+		if err != nil {
+			return diag.Errorf("error looking up detectors referencing rule %s: %+v", d.Id(), err)
+		}
+		if len(detectors) > 0 {
+			return diag.Errorf("custom rule %s is still referenced by detectors %s. Update or destroy them first, for example by referencing the rule ID from their body so that Terraform destroys them before the rule, or set force_delete", d.Id(), strings.Join(detectors, ", "))
+		}
+	}
+
+	return diag.FromErr(resourceOpensearchDeleteSaDetectorRule(ctx, d.Id(), m))
+}
+
+// resourceOpensearchDeleteSaDetectorRule deletes a custom rule, succeeding
+// when it does not exist.
+func resourceOpensearchDeleteSaDetectorRule(ctx context.Context, id string, m interface{}) error {
+	defer m.(*ProviderConf).acquireSaWrite()()
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/rules/{id}?forced=true", map[string]string{
-		"id": d.Id(),
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "rules/{id}"), map[string]string{
+		"id": id,
 	})
 	if err != nil {
 		return fmt.Errorf("error building URL path for detector: %+v", err)
@@ -234,11 +618,11 @@ func resourceOpensearchSaDetectorRuleDelete(d *schema.ResourceData, m interface{
 		return err
 	}
 
-	_, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method: "DELETE",
-		Path:   path,
-	})
-
+	_, err = performSaRequest(ctx, m.(*ProviderConf), osClient, saOperationDelete, saRequestOptions("DELETE", path, url.Values{"forced": []string{"true"}}, ""))
+	if IsSearchNotFound(err) {
+		log.Printf("[WARN] Security Analytics Detector Rule (%s) already deleted", id)
+		return nil
+	}
 	return err
 }
 
@@ -251,3 +635,451 @@ type SaDetectorRuleResponse struct {
 type SaDetectorRuleObject struct {
 	Rule string `json:"rule"`
 }
+
+// diffSuppressSaRuleBody suppresses differences between two Sigma rule
+// documents that parse to the same YAML document. Rule updates are always
+// forced, so purely cosmetic edits are not worth sending.
+func diffSuppressSaRuleBody(k, old, new string, d *schema.ResourceData) bool {
+	return saRuleNormalizer.equalYAML(old, new)
+}
+
+// sigmaTagRegexp matches Sigma tags, a namespace and a name separated by a
+// dot, in lowercase.
+var sigmaTagRegexp = regexp.MustCompile(`^[a-z0-9_-]+\.[a-z0-9._-]+$`)
+
+// sigmaStatusDeprecated is the Sigma rule status marking rules that should no
+// longer be used.
+const sigmaStatusDeprecated = "deprecated"
+
+// sigmaRuleHeader holds the Sigma rule fields the provider relies on.
+type sigmaRuleHeader struct {
+	ID     string `yaml:"id"`
+	Title  string `yaml:"title"`
+	Status string `yaml:"status"`
+}
+
+// parseSigmaRuleHeader parses the header fields of a Sigma rule document.
+// Fields of a document that cannot be parsed are left empty.
+func parseSigmaRuleHeader(body interface{}) sigmaRuleHeader {
+	var header sigmaRuleHeader
+	if s, ok := body.(string); ok {
+		_ = yaml.Unmarshal([]byte(s), &header)
+	}
+	return header
+}
+
+// sigmaRuleJSON encodes a Sigma rule document as JSON, or returns an empty
+// string when it cannot be parsed.
+func sigmaRuleJSON(body string) string {
+	var rule map[string]interface{}
+	if err := yaml.Unmarshal([]byte(body), &rule); err != nil || rule == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(jsonCompatibleYAML(rule))
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// jsonCompatibleYAML converts the maps of a decoded YAML value, keyed by any
+// value, to maps keyed by string, which JSON can encode.
+func jsonCompatibleYAML(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			converted[fmt.Sprintf("%v", k)] = jsonCompatibleYAML(v)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			converted[k] = jsonCompatibleYAML(v)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, v := range value {
+			converted[i] = jsonCompatibleYAML(v)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// sigmaRuleFields returns the sorted names of the fields matched by the
+// detection of a Sigma rule document, without value modifiers such as
+// "|contains". Keyword selections, which match no field, are skipped.
+func sigmaRuleFields(body interface{}) []string {
+	s, ok := body.(string)
+	if !ok {
+		return nil
+	}
+
+	var rule struct {
+		Detection map[string]interface{} `yaml:"detection"`
+	}
+	if err := yaml.Unmarshal([]byte(s), &rule); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var collect func(selection interface{})
+	collect = func(selection interface{}) {
+		switch selection := selection.(type) {
+		case map[interface{}]interface{}:
+			for key := range selection {
+				if field, ok := key.(string); ok {
+					seen[strings.SplitN(field, "|", 2)[0]] = true
+				}
+			}
+		case []interface{}:
+			for _, item := range selection {
+				if _, ok := item.(map[interface{}]interface{}); ok {
+					collect(item)
+				}
+			}
+		}
+	}
+	for name, selection := range rule.Detection {
+		if name == "condition" || name == "timeframe" {
+			continue
+		}
+		collect(selection)
+	}
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// resourceOpensearchSaRuleBody returns the Sigma rule document to submit,
+// either the configured body or the YAML serialization of sigma, with author
+// and custom_metadata merged in and tagged with the managed_by_tag of the
+// provider.
+func resourceOpensearchSaRuleBody(d resourceGetter, m interface{}) (string, error) {
+	body := d.Get("body").(string)
+	if sigma, ok := d.Get("sigma").([]interface{}); ok && len(sigma) > 0 && sigma[0] != nil {
+		var err error
+		if body, err = expandSaSigmaRule(sigma[0].(map[string]interface{})); err != nil {
+			return "", err
+		}
+	}
+
+	if body == "" {
+		return body, nil
+	}
+	if metadata := saRuleMetadata(d); len(metadata) > 0 {
+		var err error
+		if body, err = mergeSigmaRuleMetadata(body, metadata, d.Get("force_metadata").(bool)); err != nil {
+			return "", err
+		}
+	}
+	if tag := m.(*ProviderConf).managedByTag; tag != "" {
+		return addSigmaRuleTag(body, tag)
+	}
+	return body, nil
+}
+
+// addSigmaRuleTag appends tag to the tags of a Sigma rule document, unless
+// the rule already has it.
+func addSigmaRuleTag(body, tag string) (string, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return "", fmt.Errorf("error unmarshalling sigma rule: %+v", err)
+	}
+
+	i := 0
+	for i < len(doc) && doc[i].Key != "tags" {
+		i++
+	}
+	if i == len(doc) {
+		doc = append(doc, yaml.MapItem{Key: "tags", Value: []interface{}{}})
+	}
+	tags, _ := doc[i].Value.([]interface{})
+	for _, t := range tags {
+		if t == tag {
+			return body, nil
+		}
+	}
+	doc[i].Value = append(tags, tag)
+
+	tagged, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling sigma rule: %+v", err)
+	}
+	return string(tagged), nil
+}
+
+// removeSigmaRuleTag removes tag from the tags of a Sigma rule document, and
+// the tags field when no other tag is left. Documents without the tag, or
+// that cannot be parsed, are returned as is.
+func removeSigmaRuleTag(body, tag string) string {
+	var doc yaml.MapSlice
+	if tag == "" || yaml.Unmarshal([]byte(body), &doc) != nil {
+		return body
+	}
+
+	for i, item := range doc {
+		tags, ok := item.Value.([]interface{})
+		if item.Key != "tags" || !ok {
+			continue
+		}
+		kept := make([]interface{}, 0, len(tags))
+		for _, t := range tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == len(tags) {
+			return body
+		}
+		if len(kept) == 0 {
+			doc = append(doc[:i], doc[i+1:]...)
+		} else {
+			doc[i].Value = kept
+		}
+		untagged, err := yaml.Marshal(doc)
+		if err != nil {
+			return body
+		}
+		return string(untagged)
+	}
+	return body
+}
+
+// saRuleMetadataReservedKeys are the Sigma rule fields custom_metadata
+// cannot set, as they define the rule rather than describe it.
+var saRuleMetadataReservedKeys = []string{"title", "id", "logsource", "detection", "author"}
+
+func validateSaRuleMetadataKeys(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(map[string]interface{})
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be a map", k))
+		return warnings, errors
+	}
+
+	for key := range v {
+		if containsString(saRuleMetadataReservedKeys, key) {
+			errors = append(errors, fmt.Errorf("%s cannot set the %q field of the rule", k, key))
+		}
+	}
+	return warnings, errors
+}
+
+// saRuleMetadata returns the fields merged into the Sigma rule, author and
+// custom_metadata.
+func saRuleMetadata(d resourceGetter) map[string]string {
+	metadata := make(map[string]string)
+	if custom, ok := d.Get("custom_metadata").(map[string]interface{}); ok {
+		for key, value := range custom {
+			metadata[key] = value.(string)
+		}
+	}
+	if author, _ := d.Get("author").(string); author != "" {
+		metadata["author"] = author
+	}
+	return metadata
+}
+
+// mergeSigmaRuleMetadata sets top-level fields of a Sigma rule document, after
+// the fields it declares. Fields the document already declares with a value
+// are only replaced when force is set.
+func mergeSigmaRuleMetadata(body string, metadata map[string]string, force bool) (string, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return "", fmt.Errorf("error unmarshalling sigma rule: %+v", err)
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		i := 0
+		for i < len(doc) && doc[i].Key != key {
+			i++
+		}
+		if i == len(doc) {
+			doc = append(doc, yaml.MapItem{Key: key, Value: metadata[key]})
+		} else if force || doc[i].Value == nil || doc[i].Value == "" {
+			doc[i].Value = metadata[key]
+		}
+	}
+
+	merged, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling sigma rule: %+v", err)
+	}
+	return string(merged), nil
+}
+
+// sigmaRuleTopLevelFields returns the top-level fields of a Sigma rule
+// document, with scalar values formatted as strings. Nothing is returned for
+// a document that cannot be parsed.
+func sigmaRuleTopLevelFields(body string) map[string]string {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]string, len(doc))
+	for key, value := range doc {
+		if value != nil {
+			fields[key] = fmt.Sprint(value)
+		}
+	}
+	return fields
+}
+
+// flattenSaRuleMetadata reads author and custom_metadata back from the rule
+// held by the cluster. A field is only read back when it differs from the
+// value the configured rule is submitted with, so that fields the rule
+// declares itself are not reported as changes of these arguments.
+func flattenSaRuleMetadata(ds *resourceDataSetter, d *schema.ResourceData, configured, remote string) {
+	expected := sigmaRuleTopLevelFields(configured)
+	actual := sigmaRuleTopLevelFields(remote)
+
+	if author := d.Get("author").(string); author != "" && actual["author"] != expected["author"] {
+		ds.set("author", actual["author"])
+	}
+
+	custom := d.Get("custom_metadata").(map[string]interface{})
+	changed := false
+	metadata := make(map[string]interface{}, len(custom))
+	for key, value := range custom {
+		metadata[key] = value
+		if actual[key] == expected[key] {
+			continue
+		}
+		changed = true
+		if v, ok := actual[key]; ok {
+			metadata[key] = v
+		} else {
+			delete(metadata, key)
+		}
+	}
+	if changed {
+		ds.set("custom_metadata", metadata)
+	}
+}
+
+// sigmaRuleDocument is the part of a Sigma rule modelled by the sigma block.
+type sigmaRuleDocument struct {
+	Title          string            `yaml:"title"`
+	ID             string            `yaml:"id"`
+	Status         string            `yaml:"status"`
+	Description    string            `yaml:"description"`
+	Author         string            `yaml:"author"`
+	References     []string          `yaml:"references"`
+	Tags           []string          `yaml:"tags"`
+	LogSource      map[string]string `yaml:"logsource"`
+	Detection      interface{}       `yaml:"detection"`
+	FalsePositives []string          `yaml:"falsepositives"`
+	Level          string            `yaml:"level"`
+}
+
+// expandSaSigmaRule serializes a sigma block into a Sigma rule document,
+// with its fields in the order of the Sigma specification.
+func expandSaSigmaRule(sigma map[string]interface{}) (string, error) {
+	detection, err := unmarshalCoercingNumbers(sigma["detection"].(string))
+	if err != nil {
+		return "", fmt.Errorf("error unmarshalling sigma detection: %+v", err)
+	}
+
+	var doc yaml.MapSlice
+	add := func(key string, value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return
+			}
+		case yaml.MapSlice:
+			if len(v) == 0 {
+				return
+			}
+		}
+		doc = append(doc, yaml.MapItem{Key: key, Value: value})
+	}
+
+	var logSource yaml.MapSlice
+	if raw, ok := sigma["logsource"].([]interface{}); ok && len(raw) > 0 && raw[0] != nil {
+		fields := raw[0].(map[string]interface{})
+		for _, key := range []string{"product", "service", "category", "definition"} {
+			if value, _ := fields[key].(string); value != "" {
+				logSource = append(logSource, yaml.MapItem{Key: key, Value: value})
+			}
+		}
+	}
+
+	add("title", sigma["title"])
+	add("id", sigma["id"])
+	add("status", sigma["status"])
+	add("description", sigma["description"])
+	add("author", sigma["author"])
+	add("references", sigma["references"])
+	add("tags", sigma["tags"])
+	add("logsource", logSource)
+	add("detection", detection)
+	add("falsepositives", sigma["falsepositives"])
+	add("level", sigma["level"])
+
+	body, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling sigma rule: %+v", err)
+	}
+	return string(body), nil
+}
+
+// flattenSaSigmaRule parses a Sigma rule document into a sigma block.
+func flattenSaSigmaRule(body string) ([]interface{}, error) {
+	var doc sigmaRuleDocument
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, fmt.Errorf("error unmarshalling sigma rule: %+v", err)
+	}
+
+	detection, err := json.Marshal(saStringKeys(doc.Detection))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling sigma detection: %+v", err)
+	}
+
+	logSource := make([]interface{}, 0, 1)
+	if len(doc.LogSource) > 0 {
+		logSource = append(logSource, map[string]interface{}{
+			"product":    doc.LogSource["product"],
+			"service":    doc.LogSource["service"],
+			"category":   doc.LogSource["category"],
+			"definition": doc.LogSource["definition"],
+		})
+	}
+
+	return []interface{}{map[string]interface{}{
+		"title":          doc.Title,
+		"id":             doc.ID,
+		"status":         doc.Status,
+		"description":    doc.Description,
+		"author":         doc.Author,
+		"references":     doc.References,
+		"tags":           doc.Tags,
+		"logsource":      logSource,
+		"detection":      string(detection),
+		"falsepositives": doc.FalsePositives,
+		"level":          doc.Level,
+	}}, nil
+}
+
+// diffSuppressSigmaDetection compares detection sections as JSON documents.
+func diffSuppressSigmaDetection(k, old, new string, d *schema.ResourceData) bool {
+	return saRuleNormalizer.equalJSON(old, new)
+}
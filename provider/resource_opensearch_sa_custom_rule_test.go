@@ -1,10 +1,17 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
@@ -20,12 +27,520 @@ func TestAccOpensearchSaCustomRule(t *testing.T) {
 				Config: testAccOpensearchSaCustomRule,
 				Check: resource.ComposeTestCheckFunc(
 					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.test_rule"),
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.test_rule", "status", "experimental"),
 				),
 			},
 			{
 				Config: testAccOpensearchSaCustomRuleUpdate,
 				Check: resource.ComposeTestCheckFunc(
 					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.test_rule"),
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.test_rule", "status", "experimental"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaCustomRule_categoryDrift(t *testing.T) {
+	var ruleID string
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaCustomRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaCustomRule,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.test_rule"),
+					resource.TestCheckResourceAttrWith("opensearch_sa_custom_rule.test_rule", "id", func(id string) error {
+						ruleID = id
+						return nil
+					}),
+				),
+			},
+			{
+				// move the rule to another category behind Terraform's back
+				PreConfig: func() {
+					d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorRule().Schema, map[string]interface{}{
+						"category": "windows",
+						"body":     testAccOpensearchSaCustomRuleBody(testAccOpensearchSaCustomRule),
+					})
+					d.SetId(ruleID)
+					if _, err := resourceOpensearchPutSaDetectorRule(d, testAccOpendistroProvider.Meta()); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:             testAccOpensearchSaCustomRule,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccOpensearchSaCustomRule,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.test_rule", "category", "cloudtrail"),
+					resource.TestCheckResourceAttrPtr("opensearch_sa_custom_rule.test_rule", "id", &ruleID),
+				),
+			},
+		},
+	})
+}
+
+// testAccOpensearchSaCustomRuleBody returns the rule body of a configuration.
+func testAccOpensearchSaCustomRuleBody(config string) string {
+	body := config[strings.Index(config, "<<EOF\n")+len("<<EOF\n"):]
+	return body[:strings.Index(body, "EOF\n")]
+}
+
+func TestSaCustomRuleReadCategoryDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":{"category":"windows","rule":"title: Test\nstatus: test\n"}}}]}}`))
+	}))
+	defer server.Close()
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	for configured, expected := range map[string]string{"cloudtrail": "windows", "Windows": "Windows", "": "windows"} {
+		d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorRule().Schema, map[string]interface{}{
+			"category": configured,
+			"body":     "title: Test\nstatus: test\n",
+		})
+		d.SetId("r1")
+		if diags := resourceOpensearchSaDetectorRuleRead(context.TODO(), d, conf); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if category := d.Get("category").(string); category != expected {
+			t.Errorf("expected category %q read over %q, got %q", expected, configured, category)
+		}
+	}
+}
+
+func TestSaCustomRuleGetDuplicates(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[
+  {"_index":".opensearch-sap-custom-rules-config","_id":"r1","_version":2,"_source":{"rule":{"category":"windows"}}},
+  {"_index":".opensearch-sap-custom-rules-config-old","_id":"r1","_version":1,"_source":{"rule":{"category":"windows"}}}
+]}}`})
+
+	_, err := resourceOpensearchSaDetectorRuleGet("r1", conf)
+	if err == nil {
+		t.Fatal("expected an error for a rule found twice")
+	}
+	if IsSearchNotFound(err) {
+		t.Fatalf("expected the duplicates not to be reported as missing, got %v", err)
+	}
+	expected := "matched 2 documents, in indices .opensearch-sap-custom-rules-config, .opensearch-sap-custom-rules-config-old"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected the error to contain %q, got %v", expected, err)
+	}
+}
+
+func TestMergeSigmaRuleMetadata(t *testing.T) {
+	metadata := map[string]string{"author": "Security Team", "license": "MIT"}
+	for _, tc := range []struct {
+		name     string
+		body     string
+		force    bool
+		expected string
+	}{
+		{name: "added", body: "title: Test\nlevel: high\n", expected: "title: Test\nlevel: high\nauthor: Security Team\nlicense: MIT\n"},
+		{name: "kept", body: "title: Test\nauthor: Jane\n", expected: "title: Test\nauthor: Jane\nlicense: MIT\n"},
+		{name: "empty replaced", body: "title: Test\nauthor: ''\n", expected: "title: Test\nauthor: Security Team\nlicense: MIT\n"},
+		{name: "forced", body: "title: Test\nauthor: Jane\n", force: true, expected: "title: Test\nauthor: Security Team\nlicense: MIT\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := mergeSigmaRuleMetadata(tc.body, metadata, tc.force)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, merged)
+			}
+		})
+	}
+}
+
+func TestSaCustomRuleMetadata(t *testing.T) {
+	remote := "title: Test\nauthor: Security Team\nlicense: MIT\n"
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"_id":"r1","_version":1,"rule":{"category":"windows","rule":"title: Test\n"}}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":1,"_source":{"rule":{"category":"windows","rule":` + fmt.Sprintf("%q", remote) + `}}}]}}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorRule().Schema, map[string]interface{}{
+		"category":        "windows",
+		"body":            "title: Test\n",
+		"author":          "Security Team",
+		"custom_metadata": map[string]interface{}{"license": "MIT"},
+	})
+	if diags := resourceOpensearchSaDetectorRuleCreate(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if body := cluster.requests[0].Body; body != remote {
+		t.Errorf("expected the metadata to be merged into the submitted rule, got %q", body)
+	}
+	// the rule read back is the one submitted, so the configuration is kept
+	if body := d.Get("body").(string); body != "title: Test\n" {
+		t.Errorf("expected the configured body to be kept, got %q", body)
+	}
+	if author := d.Get("author").(string); author != "Security Team" {
+		t.Errorf("expected the configured author to be kept, got %q", author)
+	}
+}
+
+func TestSaCustomRuleMetadataDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":{"category":"windows","rule":"title: Test\nauthor: Jane\n"}}}]}}`))
+	}))
+	defer server.Close()
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorRule().Schema, map[string]interface{}{
+		"category":        "windows",
+		"body":            "title: Test\n",
+		"author":          "Security Team",
+		"custom_metadata": map[string]interface{}{"license": "MIT"},
+	})
+	d.SetId("r1")
+	if diags := resourceOpensearchSaDetectorRuleRead(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if author := d.Get("author").(string); author != "Jane" {
+		t.Errorf("expected author Jane read back, got %q", author)
+	}
+	if metadata := d.Get("custom_metadata").(map[string]interface{}); len(metadata) != 0 {
+		t.Errorf("expected the missing license to be read back, got %v", metadata)
+	}
+}
+
+func TestSaCustomRuleDeleteReferenced(t *testing.T) {
+	detectors := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"a","inputs":[{"detector_input":{"custom_rules":[{"id":"r1"}]}}]}}},
+  {"_id":"d2","_source":{"detector":{"name":"b","inputs":[{"detector_input":{"custom_rules":[{"id":"r2"}]}}]}}}
+]}}`}
+
+	cluster, conf := newSaFakeCluster(t, detectors)
+	diags := resourceOpensearchSaDetectorRuleDelete(context.TODO(), saFakeRuleData(t), conf)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "referenced by detectors d1.") {
+		t.Errorf("expected an error listing detector d1, got %v", diags)
+	}
+	if request := cluster.lastRequest(t); request.Method == "DELETE" {
+		t.Error("expected the referenced rule not to be deleted")
+	}
+
+	cluster, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"_id":"r1","_version":2,"result":"deleted"}`})
+	d := saFakeRuleData(t)
+	if err := d.Set("force_delete", true); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceOpensearchSaDetectorRuleDelete(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(cluster.requests) != 1 || cluster.lastRequest(t).Method != "DELETE" {
+		t.Errorf("expected a forced delete without looking up detectors, got %+v", cluster.requests)
+	}
+}
+
+func TestSaCustomRuleForcedUpdateDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		response saFakeResponse
+		expected string
+	}{
+		{
+			name: "referencing detectors",
+			response: saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"a","inputs":[{"detector_input":{"custom_rules":[{"id":"r1"}]}}]}}},
+  {"_id":"d2","_source":{"detector":{"name":"b","inputs":[{"detector_input":{"custom_rules":[{"id":"r2"}]}}]}}}
+]}}`},
+			expected: "d1",
+		},
+		{
+			name:     "no detector index",
+			response: saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, conf := newSaFakeCluster(t, tc.response)
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"category": "windows",
+				"body":     "title: Updated\n",
+			})
+
+			diff, err := resourceOpenSearchSaDetectorRule().Diff(context.Background(), saFakeRuleData(t).State(), config, conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expected == "" {
+				// the empty list of the state is left unchanged
+				if count := diff.Attributes["forced_update_detectors.#"]; count != nil && count.New != "0" {
+					t.Errorf("expected no forced updates, got %+v", count)
+				}
+				return
+			}
+			if detector := diff.Attributes["forced_update_detectors.0"]; detector == nil || detector.New != tc.expected {
+				t.Errorf("expected forced_update_detectors to hold %q, got %+v", tc.expected, detector)
+			}
+		})
+	}
+}
+
+func TestSaCustomRuleRefreshClearsForcedUpdates(t *testing.T) {
+	detectors := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"a","inputs":[{"detector_input":{"custom_rules":[{"id":"r1"}]}}]}}}
+]}}`}
+	_, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":` + saFakeRule + `}}]}}`},
+		detectors,
+	)
+	d := saFakeRuleData(t)
+	if err := d.Set("forced_update_detectors", []string{"d1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := resourceOpensearchSaDetectorRuleRefresh(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+	if forced := d.Get("forced_update_detectors").([]interface{}); len(forced) != 0 {
+		t.Fatalf("expected the refresh to clear forced_update_detectors, got %v", forced)
+	}
+	if d.Get("rule_id").(string) != "r1" {
+		t.Errorf("expected rule_id r1, got %q", d.Get("rule_id"))
+	}
+
+	// the same detectors as in the last update show up in the next plan
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"category": "windows",
+		"body":     "title: Updated\n",
+	})
+	diff, err := resourceOpenSearchSaDetectorRule().Diff(context.Background(), d.State(), config, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detector := diff.Attributes["forced_update_detectors.0"]; detector == nil || detector.New != "d1" {
+		t.Errorf("expected the plan to list detector d1, got %+v", detector)
+	}
+}
+
+func TestSaCustomRuleUpdateDeprecatedNoDetectors(t *testing.T) {
+	rule := `{"category":"windows","rule":"title: Test\nstatus: deprecated\n"}`
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"_id":"r1","_version":2,"rule":` + rule + `}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":` + rule + `}}]}}`},
+		saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`},
+	)
+	d := schema.TestResourceDataRaw(t, saDetectorRuleSchema, map[string]interface{}{
+		"category": "windows",
+		"body":     "title: Test\nstatus: deprecated\n",
+	})
+	d.SetId("r1")
+
+	if diags := resourceOpensearchSaDetectorRuleUpdate(context.TODO(), d, conf); len(diags) > 0 {
+		t.Errorf("expected the update of a deprecated rule without detectors to succeed without warnings, got %+v", diags)
+	}
+	if d.Get("status").(string) != sigmaStatusDeprecated {
+		t.Errorf("expected a deprecated rule, got status %q", d.Get("status"))
+	}
+	if request := cluster.lastRequest(t); request.Path != "/_plugins/_security_analytics/detectors/_search" {
+		t.Errorf("expected the detectors referencing the rule to be looked up, got %+v", cluster.requests)
+	}
+}
+
+func TestSaCustomRuleRecategorize(t *testing.T) {
+	body := "title: Test\nid: 5f92fff9-82e2-48eb-8fc1-8b133556a551\n"
+	rules := func(hits string) saFakeResponse {
+		return saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[` + hits + `]}}`}
+	}
+	oldRule := `{"_id":"r1","_version":1,"_source":{"rule":{"category":"windows","rule":"title: Test\nid: 5f92fff9-82e2-48eb-8fc1-8b133556a551\n"}}}`
+	newRule := `{"_id":"r2","_version":1,"_source":{"rule":{"category":"cloudtrail","rule":"title: Test\nid: 5f92fff9-82e2-48eb-8fc1-8b133556a551\n"}}}`
+	detectors := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"a","inputs":[{"detector_input":{"custom_rules":[{"id":"r1"},{"id":"r3"}]}}]}}}
+]}}`}
+	ok := saFakeResponse{Status: http.StatusOK, Body: `{"_id":"d1","_version":2}`}
+
+	for _, tc := range []struct {
+		name      string
+		responses []saFakeResponse
+		methods   []string
+	}{
+		{
+			name:      "first attempt",
+			responses: []saFakeResponse{rules(oldRule), {Status: http.StatusCreated, Body: `{"_id":"r2","_version":1}`}, detectors, ok, ok},
+			methods:   []string{"POST", "POST", "POST", "PUT", "DELETE"},
+		},
+		{
+			name:      "retried attempt",
+			responses: []saFakeResponse{rules(oldRule + "," + newRule), detectors, ok, ok},
+			methods:   []string{"POST", "POST", "PUT", "DELETE"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, tc.responses...)
+			d := schema.TestResourceDataRaw(t, saDetectorRuleSchema, map[string]interface{}{
+				"category":                    "cloudtrail",
+				"body":                        body,
+				"recreate_on_category_change": true,
+			})
+			d.SetId("r1")
+
+			if diags := resourceOpensearchSaDetectorRuleRecategorize(context.TODO(), d, conf); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if d.Id() != "r2" {
+				t.Errorf("expected the rule to be replaced with r2, got %s", d.Id())
+			}
+
+			methods := make([]string, 0, len(cluster.requests))
+			for _, request := range cluster.requests {
+				methods = append(methods, request.Method)
+			}
+			if strings.Join(methods, ",") != strings.Join(tc.methods, ",") {
+				t.Fatalf("expected requests %v, got %v", tc.methods, methods)
+			}
+			put := cluster.requests[len(cluster.requests)-2]
+			if put.Path != "/_plugins/_security_analytics/detectors/d1" || !strings.Contains(put.Body, `"custom_rules":[{"id":"r2"},{"id":"r3"}]`) {
+				t.Errorf("expected detector d1 to reference r2, got %s %s", put.Path, put.Body)
+			}
+			if request := cluster.lastRequest(t); request.Path != "/_plugins/_security_analytics/rules/r1" {
+				t.Errorf("expected the old rule to be deleted, got %s", request.Path)
+			}
+		})
+	}
+}
+
+func TestSaCustomRuleRecategorizePlan(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		recreate bool
+		computed bool
+	}{
+		{name: "recreated", recreate: true, computed: true},
+		{name: "updated in place"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":0},"hits":[]}}`})
+			state := saFakeRuleData(t)
+			if err := state.Set("rule_id", "r1"); err != nil {
+				t.Fatal(err)
+			}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"category":                    "cloudtrail",
+				"body":                        "title: Test\n",
+				"recreate_on_category_change": tc.recreate,
+			})
+
+			diff, err := resourceOpenSearchSaDetectorRule().Diff(context.Background(), state.State(), config, conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ruleID := diff.Attributes["rule_id"]
+			if computed := ruleID != nil && ruleID.NewComputed; computed != tc.computed {
+				t.Errorf("expected rule_id to be planned as unknown: %t, got %+v", tc.computed, ruleID)
+			}
+		})
+	}
+}
+
+func TestReplaceSaDetectorCustomRule(t *testing.T) {
+	detector := map[string]interface{}{"inputs": []interface{}{
+		map[string]interface{}{"detector_input": map[string]interface{}{"custom_rules": []interface{}{
+			map[string]interface{}{"id": "r2"},
+			map[string]interface{}{"id": "r3"},
+			map[string]interface{}{"id": "r1"},
+		}}},
+	}}
+	replaceSaDetectorCustomRule(detector, "r1", "r2")
+	if ids := saDetectorCustomRuleIDs(detector); strings.Join(ids, ",") != "r2,r3" {
+		t.Errorf("expected a single reference to r2, got %v", ids)
+	}
+}
+
+func TestAccOpensearchSaCustomRule_defaultCategory(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaCustomRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      strings.Replace(testAccOpensearchSaCustomRule, `  category   = "cloudtrail"`+"\n", "", 1),
+				ExpectError: regexp.MustCompile("category must be set"),
+			},
+			{
+				Config: `
+provider "opensearch" {
+  default_rule_category = "cloudtrail"
+}
+` + strings.Replace(testAccOpensearchSaCustomRule, `  category   = "cloudtrail"`+"\n", "", 1),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.test_rule"),
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.test_rule", "category", "cloudtrail"),
+				),
+			},
+			{
+				Config: `
+provider "opensearch" {
+  default_rule_category = "windows"
+}
+` + testAccOpensearchSaCustomRule,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaCustomRule_adoptExisting(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaCustomRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaCustomRule,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.test_rule"),
+				),
+			},
+			{
+				Config: testAccOpensearchSaCustomRule + testAccOpensearchSaCustomRuleAdopt,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("opensearch_sa_custom_rule.adopted", "id", "opensearch_sa_custom_rule.test_rule", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaCustomRule_sigma(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaCustomRuleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaCustomRuleSigma,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaCustomRuleExists("opensearch_sa_custom_rule.sigma"),
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.sigma", "status", "test"),
+					resource.TestCheckResourceAttr("opensearch_sa_custom_rule.sigma", "sigma.0.logsource.0.product", "aws"),
 				),
 			},
 		},
@@ -67,7 +582,10 @@ func testCheckOpensearchSaCustomRuleDestroy(s *terraform.State) error {
 		_, err = resourceOpensearchSaDetectorRuleGet(rs.Primary.ID, meta.(*ProviderConf))
 
 		if err != nil {
-			return nil // should be not found error
+			if IsSearchNotFound(err) {
+				return nil
+			}
+			return err
 		}
 
 		return fmt.Errorf("Rule %q still exists", rs.Primary.ID)
@@ -76,6 +594,171 @@ func testCheckOpensearchSaCustomRuleDestroy(s *terraform.State) error {
 	return nil
 }
 
+func TestParseSigmaRuleHeader(t *testing.T) {
+	header := parseSigmaRuleHeader("title: Test\nid: cb411bfe-e9f9-4eda-8276-414fe842261d\nstatus: deprecated\n")
+	if header.ID != "cb411bfe-e9f9-4eda-8276-414fe842261d" || header.Status != sigmaStatusDeprecated {
+		t.Errorf("unexpected header %+v", header)
+	}
+
+	if header := parseSigmaRuleHeader("title: [unterminated"); header != (sigmaRuleHeader{}) {
+		t.Errorf("expected an empty header for an invalid document, got %+v", header)
+	}
+}
+
+func TestSigmaRuleFields(t *testing.T) {
+	body := `title: Test
+detection:
+  selection:
+    eventSource:
+      - iam.amazonaws.com
+    errorCode|contains: Denied
+  filter:
+    - userIdentity.type: Root
+    - userIdentity.arn|endswith: ":root"
+  keywords:
+    - AccessDenied
+  condition: selection and not filter
+`
+	fields := sigmaRuleFields(body)
+	expected := []string{"errorCode", "eventSource", "userIdentity.arn", "userIdentity.type"}
+	if strings.Join(fields, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected fields %v, got %v", expected, fields)
+	}
+}
+
+func TestSigmaRuleJSON(t *testing.T) {
+	body := `title: Test
+date: 2023-04-01
+level: high
+tags:
+  - attack.t1078
+detection:
+  selection:
+    EventID: 4625
+    1: numeric key
+  condition: selection
+`
+	expected := `{"date":"2023-04-01","detection":{"condition":"selection","selection":{"1":"numeric key","EventID":4625}},"level":"high","tags":["attack.t1078"],"title":"Test"}`
+	if encoded := sigmaRuleJSON(body); encoded != expected {
+		t.Errorf("expected %s, got %s", expected, encoded)
+	}
+
+	for _, invalid := range []string{"", "title: [unclosed", "- not\n- a map"} {
+		if encoded := sigmaRuleJSON(invalid); encoded != "" {
+			t.Errorf("expected no JSON for %q, got %s", invalid, encoded)
+		}
+	}
+}
+
+func TestSigmaRuleTags(t *testing.T) {
+	tagged, err := addSigmaRuleTag("title: Test\n", "managed_by.terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "title: Test\ntags:\n- managed_by.terraform\n"; tagged != expected {
+		t.Errorf("expected %q, got %q", expected, tagged)
+	}
+	if again, _ := addSigmaRuleTag(tagged, "managed_by.terraform"); again != tagged {
+		t.Errorf("expected the tag to be added once, got %q", again)
+	}
+	if untagged := removeSigmaRuleTag(tagged, "managed_by.terraform"); untagged != "title: Test\n" {
+		t.Errorf("expected the tags field to be removed with its last tag, got %q", untagged)
+	}
+
+	body := "title: Test\ntags:\n  - attack.t1078\n  - managed_by.terraform\n"
+	if untagged := removeSigmaRuleTag(body, "managed_by.terraform"); untagged != "title: Test\ntags:\n- attack.t1078\n" {
+		t.Errorf("expected the other tags to be kept, got %q", untagged)
+	}
+	if untagged := removeSigmaRuleTag(body, "managed_by.other"); untagged != body {
+		t.Errorf("expected a rule without the tag to be left as is, got %q", untagged)
+	}
+}
+
+func TestSaCustomRuleManagedByTag(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"_id":"r1","_version":1,"rule":{"category":"windows"}}`})
+	conf.managedByTag = "managed_by.terraform"
+	if _, err := resourceOpensearchPostSaDetectorRule(saFakeRuleData(t), conf); err != nil {
+		t.Fatal(err)
+	}
+	if body := cluster.lastRequest(t).Body; body != "title: Test\ntags:\n- managed_by.terraform\n" {
+		t.Errorf("expected the rule to be tagged, got %q", body)
+	}
+
+	for remote, expected := range map[string]string{
+		`title: Test\ntags:\n- managed_by.terraform\n`:  "title: Test\n",
+		`title: Other\ntags:\n- managed_by.terraform\n`: "title: Other\n",
+	} {
+		_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":{"category":"windows","rule":"` + remote + `"}}}]}}`})
+		conf.managedByTag = "managed_by.terraform"
+		d := saFakeRuleData(t)
+		if diags := resourceOpensearchSaDetectorRuleRead(context.TODO(), d, conf); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if body := d.Get("body").(string); body != expected {
+			t.Errorf("expected the tag to be left out of %q, got %q", remote, body)
+		}
+	}
+}
+
+func TestSaSigmaRuleRoundTrip(t *testing.T) {
+	sigma := map[string]interface{}{
+		"title":       "Access Denied",
+		"id":          "25b9c01c-350d-4b95-bed1-836d04a4f324",
+		"status":      "test",
+		"description": "",
+		"author":      "",
+		"references":  []interface{}{},
+		"tags":        []interface{}{"attack.t1078"},
+		"logsource": []interface{}{map[string]interface{}{
+			"product": "aws", "service": "cloudtrail", "category": "", "definition": "",
+		}},
+		"detection":      `{"condition":"selection","selection":{"errorCode":["AccessDenied"],"eventVersion":1.08,"count":5}}`,
+		"falsepositives": []interface{}{},
+		"level":          "high",
+	}
+
+	body, err := expandSaSigmaRule(sigma)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body, "title: Access Denied\nid: 25b9c01c") || strings.Contains(body, "author") {
+		t.Errorf("unexpected rule document:\n%s", body)
+	}
+	if header := parseSigmaRuleHeader(body); header.Status != "test" {
+		t.Errorf("expected status test, got %q", header.Status)
+	}
+
+	flattened, err := flattenSaSigmaRule(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := flattened[0].(map[string]interface{})
+	if read["title"] != "Access Denied" || read["level"] != "high" {
+		t.Errorf("unexpected sigma block %v", read)
+	}
+	if !diffSuppressSigmaDetection("detection", sigma["detection"].(string), read["detection"].(string), nil) {
+		t.Errorf("expected detection to round trip, got %s", read["detection"])
+	}
+	if logSource := read["logsource"].([]interface{})[0].(map[string]interface{}); logSource["service"] != "cloudtrail" {
+		t.Errorf("unexpected logsource %v", logSource)
+	}
+}
+
+func TestDiffSuppressSaRuleBody(t *testing.T) {
+	old := "title: Test\nlevel: high\ntags:\n  - attack.cloudtrail\n"
+	for body, suppressed := range map[string]bool{
+		old: true,
+		"# reformatted\ntitle:   Test\nlevel: high\ntags: [attack.cloudtrail]\n": true,
+		"level: high\ntitle: Test\ntags:\n- attack.cloudtrail\n":                 true,
+		"title: Test\nlevel: high\ntags:\n  - attack.iam\n":                      false,
+		"title: [unterminated": false,
+	} {
+		if diffSuppressSaRuleBody("body", old, body, nil) != suppressed {
+			t.Errorf("expected suppression of %q to be %t", body, suppressed)
+		}
+	}
+}
+
 var testAccOpensearchSaCustomRule = `
 resource "opensearch_sa_custom_rule" "test_rule" {
   category   = "cloudtrail"
@@ -107,6 +790,38 @@ EOF
 }
 `
 
+var testAccOpensearchSaCustomRuleAdopt = `
+resource "opensearch_sa_custom_rule" "adopted" {
+  adopt_existing = true
+  category       = "cloudtrail"
+  body           = <<EOF
+title: Test AWS CloudTrail IAM Access Denied Events
+id: cb411bfe-e9f9-4eda-8276-414fe842261d
+description: Detects AWS CloudTrail events where users receive an Access Denied error.
+logsource:
+  product: cloudtrail
+tags:
+  - attack.cloudtrail
+  - attack.access-denied
+falsepositives:
+  - Administrative actions causing expected access denied errors
+level: high
+status: experimental
+references: []
+author: lvkins
+date: 2024/06/19
+modified: 2024/06/19
+detection:
+  condition: selection
+  selection:
+    eventSource:
+      - iam.amazonaws.com
+    errorCode:
+      - AccessDenied
+EOF
+}
+`
+
 var testAccOpensearchSaCustomRuleUpdate = `
 resource "opensearch_sa_custom_rule" "test_rule" {
   category   = "cloudtrail"
@@ -137,3 +852,30 @@ detection:
 EOF
 }
 `
+
+var testAccOpensearchSaCustomRuleSigma = `
+resource "opensearch_sa_custom_rule" "sigma" {
+  category = "cloudtrail"
+
+  sigma {
+    title  = "Test Sigma Block Access Denied Events"
+    id     = "8e2a4c1f-5b7d-4e3a-9c6b-1d0f2a3b4c5d"
+    status = "test"
+    tags   = ["attack.t1078"]
+
+    logsource {
+      product = "aws"
+      service = "cloudtrail"
+    }
+
+    detection = jsonencode({
+      selection = {
+        errorCode = ["AccessDenied"]
+      }
+      condition = "selection"
+    })
+
+    level = "high"
+  }
+}
+`
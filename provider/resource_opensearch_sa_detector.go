@@ -4,8 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
@@ -13,9 +22,12 @@ import (
 	elastic7 "github.com/olivere/elastic/v7"
 )
 
+// the first OpenSearch version whose detectors accept threat_intel_enabled
+const saThreatIntelMinVersion = "2.12.0"
+
 var saDetectorSchema = map[string]*schema.Schema{
 	"body": {
-		Description:      "The security analytics detector document",
+		Description:      "The security analytics detector document. It may contain `${name}` placeholders (written as `$${name}` in HCL strings) which are replaced with the values of `body_vars`.",
 		Type:             schema.TypeString,
 		Required:         true,
 		DiffSuppressFunc: diffSuppressSaDetector,
@@ -23,80 +35,1786 @@ var saDetectorSchema = map[string]*schema.Schema{
 			json, _ := structure.NormalizeJsonString(v)
 			return json
 		},
-		ValidateFunc: validation.StringIsJSON,
+		ValidateFunc: validateBodyTemplateJSON,
+	},
+	"backend_roles": {
+		Description: "The backend roles owning the detector. They are sent as the `rbac_roles` of the detector, which the cluster only honours when the provider's user is allowed to assign them, and read back from the user recorded on the detector. Without it, the detector is owned by the backend roles of the provider's user.",
+		Type:        schema.TypeSet,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"body_sha256": {
+		Description: "The SHA-256 hash of `normalized_body`, as read from the cluster. It only changes when the detector does, which makes it suitable to trigger the replacement of dependent resources.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"body_vars": {
+		Description: "Values substituted for the `${name}` placeholders of `body`. Values are inserted verbatim, so string values must be quoted in the body.",
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"body_overrides": {
+		Description: "Values replacing single fields of `body`, by JSON pointer such as `/inputs/0/detector_input/indices/0`. Each value is a JSON document, so strings must be quoted, for example `jsonencode(\"windows-prod\")`. The overrides apply after `body_vars`, and each pointer must resolve to an existing field of the body.",
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringIsJSON,
+		},
+	},
+	"is_threat_intel": {
+		Description: "Whether threat intelligence is enabled for the detector on the cluster.",
+		Type:        schema.TypeBool,
+		Computed:    true,
+	},
+	"managed_custom_rule_ids": {
+		Description: "The IDs of the custom rules managed by the configuration, for example `[for rule in opensearch_sa_custom_rule.all : rule.id]`. When set, custom rules referenced by the body but missing from this list are reported as warnings, since they may be deleted elsewhere without the detector being updated. The provider cannot see the other resources of the configuration, so the list has to be passed explicitly.",
+		Type:        schema.TypeSet,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"last_changed_paths": {
+		Description: "The paths of the fields of the normalized detector document changed by the last update, such as `triggers.0.severity`, for reviewing plans of large bodies. Lists whose length changed are reported as a whole.",
+		Type:        schema.TypeList,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"notification_channels": {
+		Description: "Notification channels referenced by name. Each `${key}` placeholder of `body` is replaced with the ID of the channel of the given name, looked up through the notifications API at apply, so that trigger actions can set `\"destination_id\": \"${key}\"` without environment-specific IDs. Like `body_vars`, the IDs are inserted verbatim. The IDs are only looked up again when these names change.",
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"notification_channel_ids": {
+		Description: "The IDs of the channels of `notification_channels`, by key.",
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"normalized_body": {
+		Description: "The detector document sent to the cluster, with `body_vars` substituted and `body_overrides` applied. Refreshed from the cluster on read.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"workflow_id": {
+		Description: "The ID of the composite workflow the cluster runs the monitors of the detector with. Empty on versions that do not create workflows for detectors.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"custom_rule_count": {
+		Description: "The number of distinct custom rules the inputs of the detector use, as read from the cluster.",
+		Type:        schema.TypeInt,
+		Computed:    true,
+	},
+	"prepackaged_rule_count": {
+		Description: "The number of distinct pre-packaged rules the inputs of the detector use, as read from the cluster.",
+		Type:        schema.TypeInt,
+		Computed:    true,
+	},
+	"server_fields": {
+		Description: "The server-managed fields of the detector listed in the `keep_server_fields` provider option, such as `last_update_time`, as the cluster returned them. String values are kept as is, other values are encoded as JSON. These fields are stripped from `body` and `normalized_body` either way.",
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"validate_field_aliases": {
+		Description: "Check before each create or update that every field used by the custom rules referenced by the detector is a field alias of its `detector_type`, as listed by the field mappings of the first index of the detector. Unknown fields are reported as warnings.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"validate_rollover_indices": {
+		Description: "Check before each create or update that the inputs of the detector do not reference an index rolled over through a rollover alias, such as `windows-000001` behind the write alias `windows`, which the next rollover leaves behind. Such indices are reported as warnings, the alias should be referenced instead. Aliases are sent to the cluster and compared as written, never resolved to the indices they point to.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"validate_rule_categories": {
+		Description: "Check before each create or update that the category of every custom rule referenced by the detector matches its `detector_type`. Mismatches are reported as warnings.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"validate_unique_name": {
+		Description: "Check before each create or update that no other detector of the cluster has the `name` of the body. The cluster allows several detectors with the same name, which makes looking them up by name ambiguous. Duplicates are reported as warnings.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"strict_unique_name": {
+		Description: "Fail the plan when another detector of the cluster has the `name` of the body, instead of the warnings of `validate_unique_name`.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"adopt_existing": {
+		Description: "On create, look for a detector with the `name` of the body and manage it instead of creating a second one, for example after an apply interrupted once the detector was created. The adopted detector is updated to match the configuration. Creation fails if several detectors have the name. Detector IDs are assigned by the cluster, which has no way of creating a detector with an ID of the client's choosing.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"search_index": {
+		Description: "Only match detector documents stored in this index when reading the detector back through the search endpoint. Useful on clusters where several indices hold detector documents.",
+		Type:        schema.TypeString,
+		Optional:    true,
+	},
+	"search_routing": {
+		Description: "Only match detector documents indexed with this routing value when reading the detector back through the search endpoint. Takes precedence over the `sa_routing` of the provider.",
+		Type:        schema.TypeString,
+		Optional:    true,
+	},
+	"query_filter": {
+		Description: "A query DSL clause, as JSON, scoping the documents evaluated by every input of the detector. It is set as the `query_filter` of each detector input, which must then be omitted from the body.",
+		Type:        schema.TypeString,
+		Optional:    true,
+		StateFunc: func(v interface{}) string {
+			json, _ := structure.NormalizeJsonString(v)
+			return json
+		},
+		ValidateFunc: validateJSONObject,
+	},
+	"schedule_cron": {
+		Description:  "A cron expression scheduling the detector. It replaces the `schedule` of the body, which must then be omitted. Detectors cannot be enabled during a time window only, restricting the hour and day-of-week fields, as in `*/5 9-17 * * 1-5`, runs them during that window instead.",
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validateCronExpression,
+	},
+	"schedule_timezone": {
+		Description: "The time zone `schedule_cron` is evaluated in.",
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     "UTC",
+	},
+	"schedule_jitter": {
+		Description:  "The maximum number of minutes the runs of `schedule_cron` are delayed by, to spread detectors sharing a schedule. Detectors cannot be scheduled with a jitter, so the minute field of the expression sent to the cluster is offset by a number of minutes between 0 and this value derived from the detector name, which keeps the offset stable across applies. The minute field must be a number, a list of numbers or a step such as `*/15`.",
+		Type:         schema.TypeInt,
+		Optional:     true,
+		RequiredWith: []string{"schedule_cron"},
+		ValidateFunc: validation.IntBetween(0, 59),
+	},
+	"schedule_jitter_offset": {
+		Description: "The number of minutes `schedule_jitter` offsets the runs of the detector by.",
+		Type:        schema.TypeInt,
+		Computed:    true,
+	},
+	"trigger": {
+		Description: "Triggers of the detector, raising alerts for the findings of the rules matching their criteria. A trigger with the `name` of a trigger of the body sets the criteria of that trigger, which the body must then omit, and keeps its actions. Other triggers are added to the body without actions. The criteria are read back into these blocks and left out of `body`. A trigger fires as soon as a single rule matching all of its criteria raises a finding, the cluster builds its condition from the criteria and has no way of requiring a number of rules.",
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Description:  "The name of the trigger.",
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringIsNotWhiteSpace,
+				},
+				"severity": {
+					Description:  "The severity of the alerts raised by the trigger, from `1`, the highest, to `5`.",
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "1",
+					ValidateFunc: validation.StringInSlice([]string{"1", "2", "3", "4", "5"}, false),
+				},
+				"rule_severity": {
+					Description: "Only fire for rules of these levels: `informational`, `low`, `medium`, `high` or `critical`. Any level when empty.",
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validation.StringInSlice(saRuleSeverities, false),
+					},
+				},
+				"log_types": {
+					Description: "Only fire for rules of these log types. Any log type when empty.",
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+				},
+				"rule_ids": {
+					Description: "Only fire for the rules of these IDs. Any rule when empty.",
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+				},
+				"tags": {
+					Description: "Only fire for rules with one of these tags, such as `attack.t1078`. Any rule when empty.",
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	},
+	"trigger_throttle_minutes": {
+		Description: "Suppresses repeated notifications of triggers, by trigger name. Every action of each trigger listed is throttled for the given number of minutes, the only throttle unit detectors support, so that an alert is not notified again within that window. The throttle of these actions must then be omitted from the body.",
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeInt,
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+	},
+	"check_monitor_health": {
+		Description: "Look up the alerting monitors running the detector and their errors on each read, to fill `monitors_healthy`, `monitor_statuses`, `last_run_error` and `last_run_error_time`. Off by default as it costs extra requests per read.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"monitors_healthy": {
+		Description: "Whether every monitor of the detector exists and is enabled, or disabled when the detector is. Only set when `check_monitor_health` is.",
+		Type:        schema.TypeBool,
+		Computed:    true,
+	},
+	"monitor_statuses": {
+		Description: "The status of each monitor of the detector by monitor ID: `enabled`, `disabled`, or `missing` when the monitor does not exist, for example because it could not be created. Only set when `check_monitor_health` is.",
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	},
+	"last_run_error": {
+		Description: "The error of the last failed run of the monitors of the detector, empty when they ran without errors since. Monitor runs that fail raise an alert in the `ERROR` state, which is resolved by the next successful run. The cluster does not record when monitors last ran, so a detector that stopped running can only be told apart through `monitors_healthy` or the time of its last findings. Only set when `check_monitor_health` is.",
+		Type:        schema.TypeString,
+		Computed:    true,
 	},
+	"last_run_error_time": {
+		Description: "The time, in RFC 3339 format, the run failing with `last_run_error` started. Only set when `check_monitor_health` is.",
+		Type:        schema.TypeString,
+		Computed:    true,
+	},
+	"skip_read_after_write": {
+		Description: "Store the detector returned by the create or update request instead of searching for it until the search returns the version written. Avoids waiting for the detector index to be refreshed, the next refresh reads the detector through the search endpoint as usual.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"strict_managed_custom_rules": {
+		Description: "Fail the plan when the body references custom rules missing from `managed_custom_rule_ids`, instead of warning during apply.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+	"strict_rule_categories": {
+		Description: "Report rule category mismatches found by `validate_rule_categories` as errors instead of warnings.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	},
+}
+
+func resourceOpenSearchSaDetector() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Provides an OpenSearch security analytics detection. Please refer to the OpenSearch security analytics documentation for details.\n\nDetectors are read back through the detector search endpoint, which only reflects a write once the detector index has been refreshed. After a create or update, the provider retries the search until it returns the version written, for up to the `create` or `update` timeout, unless `skip_read_after_write` is set.",
+		CreateContext: resourceOpensearchSaDetectorCreate,
+		ReadContext:   resourceOpensearchSaDetectorRead,
+		UpdateContext: resourceOpensearchSaDetectorUpdate,
+		DeleteContext: resourceOpensearchSaDetectorDelete,
+		Schema:        saDetectorSchema,
+		CustomizeDiff: resourceOpensearchSaDetectorCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceOpensearchSaDetectorImport,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Update: schema.DefaultTimeout(1 * time.Minute),
+		},
+	}
+}
+
+func resourceOpensearchSaDetectorCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.HasChange("notification_channels") {
+		if err := resourceOpensearchSaDetectorResolveChannels(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// the adopted detector is bound first, so that the name checks do not
+	// report it as a duplicate
+	if d.Get("adopt_existing").(bool) {
+		if err := resourceOpensearchSaDetectorAdopt(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	diags := resourceOpensearchSaDetectorCheckThreatIntel(d, m)
+	diags = append(diags, resourceOpensearchSaDetectorCheckRuleCategories(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckFieldAliases(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckRolloverIndices(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckManagedRules(d)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckActions(d)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckUniqueName(d, m)...)
+	if diags.HasError() {
+		d.SetId("")
+		return diags
+	}
+
+	var res *SaDetectorResponse
+	var err error
+	if d.Id() != "" {
+		res, err = resourceOpensearchPutSaDetector(d, m)
+	} else {
+		res, err = resourceOpensearchPostSaDetector(d, m)
+	}
+
+	if err != nil {
+		log.Printf("[INFO] Failed to put security analytics detector: %+v", err)
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	if err := d.Set("last_changed_paths", []string{}); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	d.SetId(res.ID)
+	log.Printf("[INFO] Object ID: %s", d.Id())
+
+	// the detector is created even when its indices do not exist yet, so
+	// warnings such as an index pattern matching nothing are only reported
+	for _, warning := range res.Warnings {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "The cluster returned a warning while creating the detector",
+			Detail:   warning,
+		})
+	}
+
+	if d.Get("skip_read_after_write").(bool) {
+		return append(diags, resourceOpensearchSaDetectorSetState(d, res, m)...)
+	}
+	return append(diags, resourceOpensearchSaDetectorReadVersion(ctx, d, m, res.Version, d.Timeout(schema.TimeoutCreate))...)
+}
+
+// resourceOpensearchSaDetectorAdopt binds the resource to the detector with
+// the name of the configured body, if there is one. The detector is then
+// updated by the create instead of a new one being created.
+func resourceOpensearchSaDetectorAdopt(d *schema.ResourceData, m interface{}) error {
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return err
+	}
+
+	ids, err := resourceOpensearchSaDetectorDuplicateNames("", body, m)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(ids) > 1 {
+		return fmt.Errorf("cannot adopt a detector, several detectors have the name of this detector: %s", strings.Join(ids, ", "))
+	}
+
+	d.SetId(ids[0])
+	log.Printf("[INFO] Adopted security analytics detector %s", d.Id())
+	return nil
+}
+
+// saDetectorImportNamePrefix marks the import IDs naming a detector rather
+// than giving its ID.
+const saDetectorImportNamePrefix = "name:"
+
+// resourceOpensearchSaDetectorImport resolves detectors imported by name and
+// sets the defaults of the provider-side options, the body itself is stored
+// normalized by the read that follows.
+func resourceOpensearchSaDetectorImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if name := strings.TrimPrefix(d.Id(), saDetectorImportNamePrefix); name != d.Id() {
+		detector, err := resourceOpensearchSaDetectorByName(name, m)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[INFO] Importing security analytics detector %s named %q", detector.ID, name)
+		d.SetId(detector.ID)
+	}
+
+	ds := &resourceDataSetter{d: d}
+	ds.set("validate_field_aliases", false)
+	ds.set("validate_rollover_indices", false)
+	ds.set("validate_rule_categories", false)
+	ds.set("strict_rule_categories", false)
+	ds.set("strict_managed_custom_rules", false)
+	ds.set("validate_unique_name", false)
+	ds.set("strict_unique_name", false)
+	ds.set("adopt_existing", false)
+	ds.set("skip_read_after_write", false)
+	ds.set("check_monitor_health", false)
+	ds.set("last_changed_paths", []string{})
+	ds.set("schedule_timezone", "UTC")
+	if ds.err != nil {
+		return nil, ds.err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceOpensearchSaDetectorRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var res *SaDetectorResponse
+	var err error
+	// detectors searched with their own options cannot share a batch
+	if batcher := m.(*ProviderConf).saDetectorReads; batcher != nil && resourceOpensearchSaDetectorSearchOptions(d) == (saDetectorSearchOptions{}) {
+		res, err = batcher.get(d.Id(), m)
+	} else {
+		res, err = resourceOpensearchSaDetectorSearchWithOptions(d.Id(), resourceOpensearchSaDetectorSearchOptions(d), m)
+	}
+
+	if err != nil {
+		if IsSearchNotFound(err) {
+			log.Printf("[WARN] Security Analytics Detector (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+
+		return diag.FromErr(err)
+	}
+
+	return resourceOpensearchSaDetectorSetState(d, res, m)
+}
+
+// resourceOpensearchSaDetectorReadVersion reads the detector back after a
+// write. The search endpoint only sees a write once the detector index has
+// been refreshed, so until then it may miss a new detector or return the
+// previous version of an updated one. The search is retried until it
+// returns at least the version written.
+func resourceOpensearchSaDetectorReadVersion(ctx context.Context, d *schema.ResourceData, m interface{}, version int, timeout time.Duration) diag.Diagnostics {
+	var res *SaDetectorResponse
+	err := retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		var err error
+		res, err = resourceOpensearchSaDetectorSearchWithOptions(d.Id(), resourceOpensearchSaDetectorSearchOptions(d), m)
+		if err != nil {
+			if IsSearchNotFound(err) {
+				return retry.RetryableError(err)
+			}
+			return retry.NonRetryableError(err)
+		}
+		if res.Version < version {
+			return retry.RetryableError(fmt.Errorf("search returned version %d of detector %s, expected version %d", res.Version, d.Id(), version))
+		}
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOpensearchSaDetectorSetState(d, res, m)
+}
+
+func resourceOpensearchSaDetectorSearchOptions(d *schema.ResourceData) saDetectorSearchOptions {
+	return saDetectorSearchOptions{
+		Index:   d.Get("search_index").(string),
+		Routing: d.Get("search_routing").(string),
+	}
+}
+
+func resourceOpensearchSaDetectorSetState(d *schema.ResourceData, res *SaDetectorResponse, m interface{}) diag.Diagnostics {
+	d.SetId(res.ID)
+
+	SaDetectorJSON, err := json.Marshal(res.Detector)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	SaDetectorJsonNormalized, err := structure.NormalizeJsonString(string(SaDetectorJSON))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ds := &resourceDataSetter{d: d}
+	ds.set("normalized_body", SaDetectorJsonNormalized)
+	ds.set("body_sha256", hashSum(SaDetectorJsonNormalized))
+	ds.set("is_threat_intel", res.ThreatIntelEnabled)
+	ds.set("workflow_id", res.WorkflowID)
+	ds.set("custom_rule_count", saDetectorRuleCount(res.Detector, "custom_rules"))
+	ds.set("prepackaged_rule_count", saDetectorRuleCount(res.Detector, "pre_packaged_rules"))
+	serverFields, err := flattenSaServerFields(res.ServerFields, m.(*ProviderConf).keepServerFields)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	ds.set("server_fields", serverFields)
+	if d.Get("backend_roles").(*schema.Set).Len() > 0 {
+		ds.set("backend_roles", res.BackendRoles)
+	}
+	if d.Get("check_monitor_health").(bool) {
+		statuses, err := resourceOpensearchSaMonitorStatuses(res.MonitorIDs, m)
+		if err != nil {
+			return diag.Errorf("error looking up the monitors of detector %s: %+v", res.ID, err)
+		}
+		enabled, _ := res.Detector["enabled"].(bool)
+		ds.set("monitor_statuses", statuses)
+		ds.set("monitors_healthy", saMonitorsHealthy(statuses, enabled))
+
+		lastError, err := resourceOpensearchSaMonitorsLastError(res.MonitorIDs, m)
+		if err != nil {
+			return diag.Errorf("error looking up the errors of the monitors of detector %s: %+v", res.ID, err)
+		}
+		ds.set("last_run_error", lastError.ErrorMessage)
+		ds.set("last_run_error_time", formatSaEpochMillis(lastError.StartTime))
+	}
+	if err := flattenSaDetectorFields(d, res.Detector); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// a templated body is kept as configured, drift is tracked through
+	// normalized_body instead
+	if len(d.Get("body_vars").(map[string]interface{})) == 0 && len(d.Get("notification_channels").(map[string]interface{})) == 0 && len(d.Get("body_overrides").(map[string]interface{})) == 0 {
+		SaDetectorJSON, err = json.Marshal(res.Detector)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		ds.set("body", string(SaDetectorJSON))
+	}
+	return diag.FromErr(ds.err)
+}
+
+// resourceOpensearchSaDetectorCustomizeDiff renders the body with the planned
+// body_vars so that changes to the variables alone show up in the plan.
+func resourceOpensearchSaDetectorCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.HasChange("check_monitor_health") {
+		if err := resourceOpensearchSaDetectorMonitorHealthComputed(d); err != nil {
+			return err
+		}
+	}
+
+	// renamed channels are looked up at apply
+	channelsChanged := !d.NewValueKnown("notification_channels") || d.HasChange("notification_channels")
+	if channelsChanged {
+		if err := d.SetNewComputed("notification_channel_ids"); err != nil {
+			return err
+		}
+	}
+
+	if !d.NewValueKnown("body") || !d.NewValueKnown("body_vars") || !d.NewValueKnown("body_overrides") || channelsChanged {
+		if err := d.SetNewComputed("body_sha256"); err != nil {
+			return err
+		}
+		if len(m.(*ProviderConf).keepServerFields) > 0 {
+			if err := d.SetNewComputed("server_fields"); err != nil {
+				return err
+			}
+		}
+		if err := d.SetNewComputed("last_changed_paths"); err != nil {
+			return err
+		}
+		if err := d.SetNewComputed("schedule_jitter_offset"); err != nil {
+			return err
+		}
+		if err := resourceOpensearchSaDetectorMonitorHealthComputed(d); err != nil {
+			return err
+		}
+		return d.SetNewComputed("normalized_body")
+	}
+
+	// the IDs of rules created in the same apply are only known then
+	if d.Get("strict_managed_custom_rules").(bool) && d.NewValueKnown("managed_custom_rule_ids") {
+		unmanaged, err := saDetectorUnmanagedCustomRules(d)
+		if err != nil {
+			return err
+		}
+		if len(unmanaged) > 0 {
+			return fmt.Errorf("the detector references custom rules missing from managed_custom_rule_ids: %s", strings.Join(unmanaged, ", "))
+		}
+	}
+
+	rendered, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return err
+	}
+
+	if d.Get("strict_unique_name").(bool) && (d.Id() == "" || d.HasChanges("body", "body_vars", "body_overrides", "strict_unique_name")) {
+		duplicates, err := resourceOpensearchSaDetectorDuplicateNames(d.Id(), rendered, m)
+		if err != nil {
+			return err
+		}
+		// the only detector with the name is the one adopt_existing takes over
+		adopted := d.Id() == "" && d.Get("adopt_existing").(bool) && len(duplicates) == 1
+		if len(duplicates) > 0 && !adopted {
+			return fmt.Errorf("other detectors have the name of this detector: %s", strings.Join(duplicates, ", "))
+		}
+	}
+
+	old, _ := d.GetChange("normalized_body")
+	if diffSuppressSaDetector("normalized_body", old.(string), rendered, nil) {
+		return nil
+	}
+
+	// the hash is taken from the detector as the cluster stores it
+	if err := d.SetNewComputed("body_sha256"); err != nil {
+		return err
+	}
+	// timestamps such as last_update_time change with each update
+	if len(m.(*ProviderConf).keepServerFields) > 0 {
+		if err := d.SetNewComputed("server_fields"); err != nil {
+			return err
+		}
+	}
+	if err := d.SetNew("last_changed_paths", saDetectorChangedPaths(old.(string), rendered)); err != nil {
+		return err
+	}
+	if err := d.SetNew("schedule_jitter_offset", saDetectorScheduleJitterOffset(d, rendered)); err != nil {
+		return err
+	}
+	if err := resourceOpensearchSaDetectorMonitorHealthComputed(d); err != nil {
+		return err
+	}
+	return d.SetNew("normalized_body", rendered)
+}
+
+// resourceOpensearchSaDetectorMonitorHealthComputed marks the monitor health
+// as unknown, since an update may replace the monitors of the detector.
+func resourceOpensearchSaDetectorMonitorHealthComputed(d *schema.ResourceDiff) error {
+	if !d.Get("check_monitor_health").(bool) {
+		return nil
+	}
+	for _, key := range []string{"monitor_statuses", "last_run_error", "last_run_error_time"} {
+		if err := d.SetNewComputed(key); err != nil {
+			return err
+		}
+	}
+	return d.SetNewComputed("monitors_healthy")
+}
+
+// resourceOpensearchSaMonitorStatuses returns the status of the given alerting
+// monitors by ID, see monitor_statuses.
+func resourceOpensearchSaMonitorStatuses(ids []string, m interface{}) (map[string]string, error) {
+	statuses := make(map[string]string, len(ids))
+	for _, id := range ids {
+		statuses[id] = "missing"
+	}
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+
+	result, err := saSearch("/_plugins/_alerting/monitors/_search", nil, map[string]interface{}{
+		"size": len(ids),
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{
+				"values": ids,
+			},
+		},
+	}, "monitor", m)
+	// the alerting config index is only created along with the first monitor
+	if IsSearchNotFound(err) {
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range result.Hits {
+		if enabled, _ := hit.Source["enabled"].(bool); enabled {
+			statuses[hit.ID] = "enabled"
+		} else {
+			statuses[hit.ID] = "disabled"
+		}
+	}
+	return statuses, nil
+}
+
+// resourceOpensearchSaMonitorsLastError returns the most recent alert in the
+// ERROR state of the given alerting monitors, or an empty alert when none of
+// them failed since their last successful run.
+func resourceOpensearchSaMonitorsLastError(ids []string, m interface{}) (*SaMonitorErrorAlert, error) {
+	last := new(SaMonitorErrorAlert)
+	if len(ids) == 0 {
+		return last, nil
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		params := url.Values{
+			"monitorId":  []string{id},
+			"alertState": []string{"ERROR"},
+			"sortString": []string{"start_time"},
+			"sortOrder":  []string{"desc"},
+			"size":       []string{"1"},
+		}
+		res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", "/_plugins/_alerting/monitors/alerts", params, ""))
+		// the alert index is only created along with the first alert
+		if IsSearchNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Alerts []SaMonitorErrorAlert `json:"alerts"`
+		}
+		if err := json.Unmarshal(res.Body, &response); err != nil {
+			return nil, fmt.Errorf("error unmarshalling alerts of monitor %s: %+v", id, err)
+		}
+		if len(response.Alerts) > 0 && response.Alerts[0].StartTime > last.StartTime {
+			last = &response.Alerts[0]
+		}
+	}
+	return last, nil
+}
+
+// formatSaEpochMillis formats a time in milliseconds since the epoch in RFC
+// 3339 format, or as an empty string for zero.
+func formatSaEpochMillis(millis int64) string {
+	if millis == 0 {
+		return ""
+	}
+	return time.UnixMilli(millis).UTC().Format(time.RFC3339)
+}
+
+// saMonitorsHealthy reports whether every monitor exists and is enabled as
+// the detector is.
+func saMonitorsHealthy(statuses map[string]string, detectorEnabled bool) bool {
+	expected := "disabled"
+	if detectorEnabled {
+		expected = "enabled"
+	}
+	for _, status := range statuses {
+		if status != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// saDetectorScheduleJitterOffset returns the offset schedule_jitter applies to
+// the schedule of a detector document.
+func saDetectorScheduleJitterOffset(d resourceGetter, body string) int {
+	if d.Get("schedule_cron").(string) == "" {
+		return 0
+	}
+	var detector struct {
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal([]byte(body), &detector)
+	return saScheduleJitterOffset(detector.Name, d.Get("schedule_jitter").(int))
+}
+
+// saDetectorChangedPaths returns the fields changed from the old to the new
+// normalized body. It is computed while planning the change, and again when
+// applying a change whose body was unknown at plan time.
+func saDetectorChangedPaths(old, new string) []string {
+	paths, err := saDetectorNormalizer.changedPathsJSON(old, new)
+	if err != nil {
+		// the change is still shown through normalized_body
+		log.Printf("[WARN] Could not compare detector bodies: %+v", err)
+		return []string{}
+	}
+	return paths
+}
+
+// resourceOpensearchSaDetectorBody returns the normalized detector document
+// to submit, built from the configured body, body_vars, body_overrides and
+// typed fields.
+func resourceOpensearchSaDetectorBody(d resourceGetter) (string, error) {
+	vars := make(map[string]interface{})
+	for key, value := range d.Get("body_vars").(map[string]interface{}) {
+		vars[key] = value
+	}
+	for key, id := range d.Get("notification_channel_ids").(map[string]interface{}) {
+		if _, ok := vars[key]; ok {
+			return "", fmt.Errorf("%s is set both in body_vars and notification_channels", key)
+		}
+		vars[key] = id
+	}
+	detector, err := resourceOpensearchSaDetectorDocument(d, vars)
+	if err != nil {
+		return "", err
+	}
+
+	if err := expandSaDetectorFields(d, detector); err != nil {
+		return "", err
+	}
+
+	normalized, err := json.Marshal(detector)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling detector body: %+v", err)
+	}
+	return string(normalized), nil
+}
+
+// resourceOpensearchSaDetectorDocument returns the configured body with the
+// given variables substituted and body_overrides applied, before the typed
+// fields are merged into it.
+func resourceOpensearchSaDetectorDocument(d resourceGetter, vars map[string]interface{}) (map[string]interface{}, error) {
+	rendered := renderBodyVars(d.Get("body").(string), vars)
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &detector); err != nil {
+		return nil, fmt.Errorf("detector body is not valid JSON after substituting body_vars: %+v", err)
+	}
+
+	overrides := d.Get("body_overrides").(map[string]interface{})
+	pointers := make([]string, 0, len(overrides))
+	for pointer := range overrides {
+		pointers = append(pointers, pointer)
+	}
+	// parents are replaced before the fields they contain
+	sort.Strings(pointers)
+	for _, pointer := range pointers {
+		var value interface{}
+		if err := json.Unmarshal([]byte(overrides[pointer].(string)), &value); err != nil {
+			return nil, fmt.Errorf("body_overrides value of %s is not valid JSON: %+v", pointer, err)
+		}
+		if err := setJSONPointer(detector, pointer, value); err != nil {
+			return nil, fmt.Errorf("error applying body_overrides: %+v", err)
+		}
+	}
+	return detector, nil
+}
+
+// saDetectorBodyConflicts returns the typed attributes that are set while the
+// detector body also defines the field they replace, along with that field.
+func saDetectorBodyConflicts(d resourceGetter, detector map[string]interface{}) []string {
+	var conflicts []string
+	if d.Get("schedule_cron").(string) != "" {
+		if _, ok := detector["schedule"]; ok {
+			conflicts = append(conflicts, "schedule_cron (schedule)")
+		}
+	}
+	if d.Get("backend_roles").(*schema.Set).Len() > 0 {
+		if _, ok := detector["rbac_roles"]; ok {
+			conflicts = append(conflicts, "backend_roles (rbac_roles)")
+		}
+	}
+	if d.Get("query_filter").(string) != "" {
+		for _, detectorInput := range saDetectorInputs(detector) {
+			if _, ok := detectorInput["query_filter"]; ok {
+				conflicts = append(conflicts, "query_filter (inputs.detector_input.query_filter)")
+				break
+			}
+		}
+	}
+	for _, raw := range d.Get("trigger").([]interface{}) {
+		name, _ := raw.(map[string]interface{})["name"].(string)
+		if trigger := saDetectorTrigger(detector, name); trigger != nil {
+			for _, field := range saTriggerCriteriaFields {
+				if _, ok := trigger[field]; ok {
+					conflicts = append(conflicts, fmt.Sprintf("trigger %s (triggers.%s)", name, field))
+				}
+			}
+		}
+	}
+	throttles := d.Get("trigger_throttle_minutes").(map[string]interface{})
+throttled:
+	for _, trigger := range saDetectorTriggers(detector) {
+		name, _ := trigger["name"].(string)
+		if _, ok := throttles[name]; !ok {
+			continue
+		}
+		for _, action := range saDetectorTriggerActions(trigger) {
+			_, enabled := action["throttle_enabled"]
+			_, throttle := action["throttle"]
+			if enabled || throttle {
+				conflicts = append(conflicts, "trigger_throttle_minutes (triggers.actions.throttle)")
+				break throttled
+			}
+		}
+	}
+	return conflicts
+}
+
+// expandSaDetectorFields merges the typed detector attributes into the
+// detector document. A field is either authored in the body or through its
+// typed attribute, setting both is an error.
+func expandSaDetectorFields(d resourceGetter, detector map[string]interface{}) error {
+	if conflicts := saDetectorBodyConflicts(d, detector); len(conflicts) > 0 {
+		return fmt.Errorf("the detector body and typed attributes both define %s: set each of these either in the body or through its attribute, not both", strings.Join(conflicts, ", "))
+	}
+
+	if cron := d.Get("schedule_cron").(string); cron != "" {
+		name, _ := detector["name"].(string)
+		expression, err := jitterCronExpression(cron, saScheduleJitterOffset(name, d.Get("schedule_jitter").(int)))
+		if err != nil {
+			return err
+		}
+		detector["schedule"] = map[string]interface{}{
+			"cron": map[string]interface{}{
+				"expression": expression,
+				"timezone":   d.Get("schedule_timezone").(string),
+			},
+		}
+	}
+
+	if roles := d.Get("backend_roles").(*schema.Set); roles.Len() > 0 {
+		detector["rbac_roles"] = roles.List()
+	}
+
+	if filter := d.Get("query_filter").(string); filter != "" {
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(filter), &query); err != nil {
+			return fmt.Errorf("error unmarshalling query_filter: %+v", err)
+		}
+		for _, detectorInput := range saDetectorInputs(detector) {
+			detectorInput["query_filter"] = query
+		}
+	}
+
+	expandSaDetectorTriggerBlocks(d.Get("trigger").([]interface{}), detector)
+
+	throttles := d.Get("trigger_throttle_minutes").(map[string]interface{})
+	for _, trigger := range saDetectorTriggers(detector) {
+		name, _ := trigger["name"].(string)
+		minutes, ok := throttles[name]
+		if !ok {
+			continue
+		}
+		actions := saDetectorTriggerActions(trigger)
+		if len(actions) == 0 {
+			return fmt.Errorf("trigger_throttle_minutes throttles trigger %q, which has no actions", name)
+		}
+		for _, action := range actions {
+			action["throttle_enabled"] = true
+			action["throttle"] = map[string]interface{}{
+				"value": minutes,
+				"unit":  "MINUTES",
+			}
+		}
+	}
+	for name := range throttles {
+		if saDetectorTrigger(detector, name) == nil {
+			return fmt.Errorf("trigger_throttle_minutes throttles trigger %q, which the detector body does not define", name)
+		}
+	}
+
+	return nil
+}
+
+// flattenSaDetectorFields sets the typed detector attributes in use from the
+// detector read back, and removes the parts they manage from the document so
+// they are not compared as part of the body.
+func flattenSaDetectorFields(d *schema.ResourceData, detector map[string]interface{}) error {
+	ds := &resourceDataSetter{d: d}
+
+	offset := 0
+	if d.Get("schedule_cron").(string) != "" {
+		schedule, _ := detector["schedule"].(map[string]interface{})
+		cron, _ := schedule["cron"].(map[string]interface{})
+		expression, _ := cron["expression"].(string)
+		// keep the configured expression when the cluster runs it with the
+		// jitter offset applied
+		name, _ := detector["name"].(string)
+		offset = saScheduleJitterOffset(name, d.Get("schedule_jitter").(int))
+		if jittered, err := jitterCronExpression(d.Get("schedule_cron").(string), offset); err == nil && jittered == expression {
+			expression = d.Get("schedule_cron").(string)
+		}
+		ds.set("schedule_cron", expression)
+		ds.set("schedule_timezone", cron["timezone"])
+		delete(detector, "schedule")
+	}
+	ds.set("schedule_jitter_offset", offset)
+
+	if d.Get("backend_roles").(*schema.Set).Len() > 0 {
+		delete(detector, "rbac_roles")
+	}
+
+	if d.Get("query_filter").(string) != "" {
+		for i, detectorInput := range saDetectorInputs(detector) {
+			if i == 0 {
+				filter := ""
+				if query, ok := detectorInput["query_filter"]; ok {
+					b, err := json.Marshal(query)
+					if err != nil {
+						return fmt.Errorf("error marshalling query_filter: %+v", err)
+					}
+					filter = string(b)
+				}
+				ds.set("query_filter", filter)
+			}
+			delete(detectorInput, "query_filter")
+		}
+	}
+
+	if configured := d.Get("trigger_throttle_minutes").(map[string]interface{}); len(configured) > 0 {
+		throttles := make(map[string]interface{}, len(configured))
+		for name := range configured {
+			trigger := saDetectorTrigger(detector, name)
+			if trigger == nil {
+				continue
+			}
+			for _, action := range saDetectorTriggerActions(trigger) {
+				throttle, _ := action["throttle"].(map[string]interface{})
+				value, isNumber := throttle["value"].(float64)
+				if enabled, _ := action["throttle_enabled"].(bool); enabled && isNumber {
+					if _, ok := throttles[name]; !ok {
+						throttles[name] = int(value)
+					}
+				}
+				delete(action, "throttle_enabled")
+				delete(action, "throttle")
+			}
+		}
+		ds.set("trigger_throttle_minutes", throttles)
+	}
+
+	if configured := d.Get("trigger").([]interface{}); len(configured) > 0 {
+		vars := make(map[string]interface{})
+		for key, value := range d.Get("body_vars").(map[string]interface{}) {
+			vars[key] = value
+		}
+		for key, id := range d.Get("notification_channel_ids").(map[string]interface{}) {
+			vars[key] = id
+		}
+		// the triggers added by the blocks are left out of the body, unlike
+		// the ones they set the criteria of
+		var bodyTriggers map[string]interface{}
+		if document, err := resourceOpensearchSaDetectorDocument(d, vars); err == nil {
+			bodyTriggers = document
+		}
+		ds.set("trigger", flattenSaDetectorTriggerBlocks(configured, detector, bodyTriggers))
+	}
+
+	return ds.err
+}
+
+// the levels of Sigma rules, which detector triggers can select rules by
+var saRuleSeverities = []string{"informational", "low", "medium", "high", "critical"}
+
+// the fields of detector triggers set by the trigger blocks
+var saTriggerCriteriaFields = []string{"severity", "sev_levels", "types", "ids", "tags"}
+
+// saTriggerCriteria maps the attributes of the trigger blocks to the fields
+// of detector triggers holding lists
+var saTriggerCriteria = []struct {
+	attribute string
+	field     string
+}{
+	{"rule_severity", "sev_levels"},
+	{"log_types", "types"},
+	{"rule_ids", "ids"},
+	{"tags", "tags"},
+}
+
+// expandSaDetectorTriggerBlocks sets the criteria of the trigger blocks on the
+// triggers of the detector document with their names, adding the missing
+// triggers without actions.
+func expandSaDetectorTriggerBlocks(configured []interface{}, detector map[string]interface{}) {
+	for _, raw := range configured {
+		block := raw.(map[string]interface{})
+		name := block["name"].(string)
+		trigger := saDetectorTrigger(detector, name)
+		if trigger == nil {
+			trigger = map[string]interface{}{
+				"name":    name,
+				"actions": []interface{}{},
+			}
+			triggers, _ := detector["triggers"].([]interface{})
+			detector["triggers"] = append(triggers, trigger)
+		}
+
+		trigger["severity"] = block["severity"]
+		for _, criterion := range saTriggerCriteria {
+			values := block[criterion.attribute].(*schema.Set).List()
+			sort.Slice(values, func(i, j int) bool {
+				return values[i].(string) < values[j].(string)
+			})
+			trigger[criterion.field] = values
+		}
+	}
+}
+
+// flattenSaDetectorTriggerBlocks reads the criteria of the configured trigger
+// blocks from the detector document, in the configured order, and removes
+// them from the document. Triggers missing from the body, a document before
+// the typed fields are merged, are removed altogether.
+func flattenSaDetectorTriggerBlocks(configured []interface{}, detector, body map[string]interface{}) []interface{} {
+	blocks := make([]interface{}, 0, len(configured))
+	removed := make(map[string]bool)
+	for _, raw := range configured {
+		name := raw.(map[string]interface{})["name"].(string)
+		trigger := saDetectorTrigger(detector, name)
+		if trigger == nil {
+			continue
+		}
+
+		block := map[string]interface{}{
+			"name":     name,
+			"severity": trigger["severity"],
+		}
+		for _, criterion := range saTriggerCriteria {
+			values, _ := trigger[criterion.field].([]interface{})
+			block[criterion.attribute] = values
+		}
+		blocks = append(blocks, block)
+
+		for _, field := range saTriggerCriteriaFields {
+			delete(trigger, field)
+		}
+		if body != nil && saDetectorTrigger(body, name) == nil {
+			removed[name] = true
+		}
+	}
+
+	if len(removed) > 0 {
+		kept := make([]interface{}, 0)
+		for _, trigger := range saDetectorTriggers(detector) {
+			if name, _ := trigger["name"].(string); !removed[name] {
+				kept = append(kept, trigger)
+			}
+		}
+		detector["triggers"] = kept
+	}
+	return blocks
+}
+
+// saDetectorTriggers returns the triggers of a detector document.
+func saDetectorTriggers(detector map[string]interface{}) []map[string]interface{} {
+	triggers := make([]map[string]interface{}, 0)
+	list, _ := detector["triggers"].([]interface{})
+	for _, t := range list {
+		if trigger, ok := t.(map[string]interface{}); ok {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+// saDetectorTrigger returns the trigger of a detector document with the given
+// name, or nil.
+func saDetectorTrigger(detector map[string]interface{}, name string) map[string]interface{} {
+	for _, trigger := range saDetectorTriggers(detector) {
+		if trigger["name"] == name {
+			return trigger
+		}
+	}
+	return nil
+}
+
+// saDetectorTriggerActions returns the actions of a detector trigger.
+func saDetectorTriggerActions(trigger map[string]interface{}) []map[string]interface{} {
+	actions := make([]map[string]interface{}, 0)
+	list, _ := trigger["actions"].([]interface{})
+	for _, a := range list {
+		if action, ok := a.(map[string]interface{}); ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// saScheduleJitterOffset returns the number of minutes, between 0 and jitter,
+// the schedule of the named detector is offset by.
+func saScheduleJitterOffset(name string, jitter int) int {
+	if jitter <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int(h.Sum32() % uint32(jitter+1))
+}
+
+// jitterCronExpression offsets the minute field of a cron expression by
+// offset minutes, wrapping around the hour. Numbers and lists of numbers are
+// shifted, and steps such as */15 start at the offset within their step.
+func jitterCronExpression(expression string, offset int) (string, error) {
+	if offset == 0 {
+		return expression, nil
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return "", fmt.Errorf("schedule_cron must have 5 fields, got %d", len(fields))
+	}
+
+	minute := fields[0]
+	if step := strings.TrimPrefix(minute, "*/"); step != minute {
+		n, err := strconv.Atoi(step)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("schedule_jitter cannot offset the minute field %q", minute)
+		}
+		fields[0] = fmt.Sprintf("%d-59/%d", offset%n, n)
+		return strings.Join(fields, " "), nil
+	}
+
+	minutes := strings.Split(minute, ",")
+	for i, m := range minutes {
+		n, err := strconv.Atoi(m)
+		if err != nil || n < 0 || n > 59 {
+			return "", fmt.Errorf("schedule_jitter cannot offset the minute field %q", minute)
+		}
+		minutes[i] = strconv.Itoa((n + offset) % 60)
+	}
+	fields[0] = strings.Join(minutes, ",")
+	return strings.Join(fields, " "), nil
+}
+
+// saDetectorInputs returns the detector_input objects of all inputs of a
+// detector document.
+func saDetectorInputs(detector map[string]interface{}) []map[string]interface{} {
+	detectorInputs := make([]map[string]interface{}, 0)
+	inputs, _ := detector["inputs"].([]interface{})
+	for _, i := range inputs {
+		input, _ := i.(map[string]interface{})
+		if detectorInput, ok := input["detector_input"].(map[string]interface{}); ok {
+			detectorInputs = append(detectorInputs, detectorInput)
+		}
+	}
+	return detectorInputs
+}
+
+func resourceOpensearchSaDetectorUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.HasChange("notification_channels") {
+		if err := resourceOpensearchSaDetectorResolveChannels(d, m); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	diags := resourceOpensearchSaDetectorCheckThreatIntel(d, m)
+	diags = append(diags, resourceOpensearchSaDetectorCheckRuleCategories(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckFieldAliases(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckRolloverIndices(d, m)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckManagedRules(d)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckActions(d)...)
+	diags = append(diags, resourceOpensearchSaDetectorCheckUniqueName(d, m)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	res, err := resourceOpensearchPutSaDetector(d, m)
+
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	if d.HasChange("normalized_body") {
+		old, _ := d.GetChange("normalized_body")
+		body, err := resourceOpensearchSaDetectorBody(d)
+		if err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		if err := d.Set("last_changed_paths", saDetectorChangedPaths(old.(string), body)); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	if d.Get("skip_read_after_write").(bool) {
+		return append(diags, resourceOpensearchSaDetectorSetState(d, res, m)...)
+	}
+	return append(diags, resourceOpensearchSaDetectorReadVersion(ctx, d, m, res.Version, d.Timeout(schema.TimeoutUpdate))...)
+}
+
+// resourceOpensearchSaDetectorResolveChannels looks up the IDs of the
+// notification channels referenced by name, substituted into the body.
+func resourceOpensearchSaDetectorResolveChannels(d *schema.ResourceData, m interface{}) error {
+	names := d.Get("notification_channels").(map[string]interface{})
+	ids := make(map[string]string, len(names))
+	for key, name := range names {
+		id, err := resourceOpensearchChannelConfigurationIDByName(name.(string), m)
+		if err != nil {
+			return fmt.Errorf("error resolving notification channel %s: %+v", key, err)
+		}
+		ids[key] = id
+	}
+	return d.Set("notification_channel_ids", ids)
+}
+
+// resourceOpensearchSaDetectorCheckThreatIntel fails before the detector is
+// sent when its body enables threat intelligence on a cluster too old to
+// support it, which would otherwise answer with a bare 400. The version is
+// the one set by opensearch_version, or the one detected when connecting.
+func resourceOpensearchSaDetectorCheckThreatIntel(d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return diag.Errorf("error unmarshalling detector body: %+v", err)
+	}
+	if enabled, _ := detector["threat_intel_enabled"].(bool); !enabled {
+		return nil
+	}
+
+	conf := m.(*ProviderConf)
+	if _, err := getClient(conf); err != nil {
+		return diag.FromErr(err)
+	}
+	if saThreatIntelSupported(conf.osVersion) {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "Threat intelligence is not supported by this cluster",
+		Detail:   fmt.Sprintf("The detector body sets threat_intel_enabled, which requires OpenSearch %s or later, but the cluster runs version %s. Remove threat_intel_enabled from the body, or set the opensearch_version provider option if the version was not detected correctly.", saThreatIntelMinVersion, conf.osVersion),
+	}}
+}
+
+// saThreatIntelSupported tells whether detectors of the given cluster
+// version can enable threat intelligence.
+func saThreatIntelSupported(osVersion string) bool {
+	return saVersionAtLeast(osVersion, saThreatIntelMinVersion)
+}
+
+// saVersionAtLeast tells whether the given cluster version, ignoring
+// pre-release suffixes, is at least minVersion. Versions that cannot be parsed
+// are assumed to be recent enough, leaving the decision to the cluster.
+func saVersionAtLeast(osVersion, minVersion string) bool {
+	v, err := version.NewVersion(osVersion)
+	if err != nil {
+		return true
+	}
+	return v.Core().GreaterThanOrEqual(version.Must(version.NewVersion(minVersion)))
+}
+
+// resourceOpensearchSaDetectorCheckRuleCategories looks up every custom rule
+// referenced by the detector body and compares its category with the
+// detector type. Mismatches are warnings unless strict_rule_categories is set.
+func resourceOpensearchSaDetectorCheckRuleCategories(d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("validate_rule_categories").(bool) {
+		return nil
+	}
+
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return diag.Errorf("error unmarshalling detector body: %+v", err)
+	}
+
+	severity := diag.Warning
+	if d.Get("strict_rule_categories").(bool) {
+		severity = diag.Error
+	}
+
+	detectorType, _ := detector["detector_type"].(string)
+	var diags diag.Diagnostics
+	for _, ruleID := range saDetectorCustomRuleIDs(detector) {
+		rule, err := resourceOpensearchSaDetectorRuleGet(ruleID, m)
+		if err != nil {
+			return append(diags, diag.Errorf("error fetching custom rule %s: %+v", ruleID, err)...)
+		}
+
+		category, _ := rule.Rule["category"].(string)
+		if !strings.EqualFold(category, detectorType) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: severity,
+				Summary:  "Custom rule category does not match detector type",
+				Detail:   fmt.Sprintf("Custom rule %s has category %q, but the detector type is %q. The rule will not match any documents evaluated by this detector.", ruleID, category, detectorType),
+			})
+		}
+	}
+
+	return diags
+}
+
+// resourceOpensearchSaDetectorCheckUniqueName warns about the other detectors
+// of the cluster sharing the name of the detector. In strict mode they are
+// already rejected by the plan.
+func resourceOpensearchSaDetectorCheckUniqueName(d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("validate_unique_name").(bool) || d.Get("strict_unique_name").(bool) {
+		return nil
+	}
+
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	duplicates, err := resourceOpensearchSaDetectorDuplicateNames(d.Id(), body, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Detector name is not unique",
+		Detail:   fmt.Sprintf("The detectors %s have the same name as this detector. Looking up the detector by name, for example with the opensearch_sa_detector data source, fails while several detectors have it.", strings.Join(duplicates, ", ")),
+	}}
+}
+
+// resourceOpensearchSaDetectorDuplicateNames returns the IDs of the detectors
+// other than id that have the name of the detector body.
+func resourceOpensearchSaDetectorDuplicateNames(id string, body string, m interface{}) ([]string, error) {
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return nil, fmt.Errorf("error unmarshalling detector body: %+v", err)
+	}
+	name, _ := detector["name"].(string)
+
+	duplicates := make([]string, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(res *SaDetectorResponse) error {
+		if res.ID != id && res.Detector["name"] == name {
+			duplicates = append(duplicates, res.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing detectors: %+v", err)
+	}
+	sort.Strings(duplicates)
+	return duplicates, nil
+}
+
+// resourceOpensearchSaDetectorCheckManagedRules warns about the custom rules
+// referenced by the detector that are missing from managed_custom_rule_ids.
+// In strict mode they are already rejected by the plan.
+func resourceOpensearchSaDetectorCheckManagedRules(d *schema.ResourceData) diag.Diagnostics {
+	unmanaged, err := saDetectorUnmanagedCustomRules(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(unmanaged) == 0 {
+		return nil
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Detector references custom rules not managed by the configuration",
+		Detail:   fmt.Sprintf("The custom rules %s are missing from managed_custom_rule_ids. If they are deleted outside of this configuration, the detector keeps referencing rules that no longer exist.", strings.Join(unmanaged, ", ")),
+	}}
 }
 
-func resourceOpenSearchSaDetector() *schema.Resource {
-	return &schema.Resource{
-		Description: "Provides an OpenSearch security analytics detection. Please refer to the OpenSearch security analytics documentation for details.",
-		Create:      resourceOpensearchSaDetectorCreate,
-		Read:        resourceOpensearchSaDetectorRead,
-		Update:      resourceOpensearchSaDetectorUpdate,
-		Delete:      resourceOpensearchSaDetectorDelete,
-		Schema:      saDetectorSchema,
-		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-		},
+// resourceOpensearchSaDetectorCheckActions warns about the trigger actions of
+// the detector that cannot notify anyone. The cluster accepts them, and they
+// only fail once the trigger fires.
+func resourceOpensearchSaDetectorCheckActions(d *schema.ResourceData) diag.Diagnostics {
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return diag.Errorf("error unmarshalling detector body: %+v", err)
+	}
+
+	var diags diag.Diagnostics
+	for _, problem := range saDetectorActionProblems(detector) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Detector trigger action will not notify",
+			Detail:   problem,
+		})
+	}
+	return diags
+}
+
+// saDetectorActionProblems describes the trigger actions of a detector
+// document missing a destination or a message, or with a template whose
+// mustache tags are not closed. Triggers without actions are fine.
+func saDetectorActionProblems(detector map[string]interface{}) []string {
+	var problems []string
+	for _, trigger := range saDetectorTriggers(detector) {
+		for i, action := range saDetectorTriggerActions(trigger) {
+			name := fmt.Sprintf("%d", i)
+			if n, _ := action["name"].(string); n != "" {
+				name = n
+			}
+			where := fmt.Sprintf("Action %q of trigger %q", name, trigger["name"])
+
+			destination, _ := action["destination_id"].(string)
+			switch {
+			case strings.TrimSpace(destination) == "":
+				problems = append(problems, where+" has no destination_id.")
+			case bodyVarPlaceholderRegexp.MatchString(destination):
+				problems = append(problems, fmt.Sprintf("%s has the destination_id %s, which neither body_vars nor notification_channels define.", where, destination))
+			}
+
+			message, _ := action["message_template"].(map[string]interface{})
+			if source, _ := message["source"].(string); strings.TrimSpace(source) == "" {
+				problems = append(problems, where+" has no message_template source.")
+			} else if !saMustacheBalanced(source) {
+				problems = append(problems, where+" has a message_template with unclosed mustache tags.")
+			}
+			subject, _ := action["subject_template"].(map[string]interface{})
+			if source, _ := subject["source"].(string); !saMustacheBalanced(source) {
+				problems = append(problems, where+" has a subject_template with unclosed mustache tags.")
+			}
+		}
+	}
+	return problems
+}
+
+// saMustacheBalanced reports whether every {{ of a mustache template is
+// closed by a }} before the next one opens.
+func saMustacheBalanced(template string) bool {
+	open := false
+	for i := 0; i+1 < len(template); i++ {
+		switch template[i : i+2] {
+		case "{{":
+			if open {
+				return false
+			}
+			open = true
+			i++
+		case "}}":
+			if !open {
+				return false
+			}
+			open = false
+			i++
+		}
 	}
+	return !open
 }
 
-func resourceOpensearchSaDetectorCreate(d *schema.ResourceData, m interface{}) error {
-	res, err := resourceOpensearchPostSaDetector(d, m)
+// saDetectorUnmanagedCustomRules returns the custom rules referenced by the
+// detector body that are missing from managed_custom_rule_ids, or nothing
+// when the list is not set.
+func saDetectorUnmanagedCustomRules(d resourceGetter) ([]string, error) {
+	managed := d.Get("managed_custom_rule_ids").(*schema.Set)
+	if managed.Len() == 0 {
+		return nil, nil
+	}
 
+	body, err := resourceOpensearchSaDetectorBody(d)
 	if err != nil {
-		log.Printf("[INFO] Failed to put security analytics detector: %+v", err)
-		return err
+		return nil, err
 	}
 
-	d.SetId(res.ID)
-	log.Printf("[INFO] Object ID: %s", d.Id())
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return nil, fmt.Errorf("error unmarshalling detector body: %+v", err)
+	}
 
-	return resourceOpensearchSaDetectorRead(d, m)
+	var unmanaged []string
+	for _, ruleID := range saDetectorCustomRuleIDs(detector) {
+		if !managed.Contains(ruleID) {
+			unmanaged = append(unmanaged, ruleID)
+		}
+	}
+	return unmanaged, nil
 }
 
-func resourceOpensearchSaDetectorRead(d *schema.ResourceData, m interface{}) error {
-	res, err := resourceOpensearchSaDetectorSearch(d.Id(), m)
+// resourceOpensearchSaDetectorCheckFieldAliases compares the fields used by
+// the detection of every custom rule referenced by the detector with the
+// field aliases of the detector type.
+func resourceOpensearchSaDetectorCheckFieldAliases(d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("validate_field_aliases").(bool) {
+		return nil
+	}
 
+	body, err := resourceOpensearchSaDetectorBody(d)
 	if err != nil {
-		if IsSearchNotFound(err) {
-			log.Printf("[WARN] Security Analytics Detector (%s) not found, removing from state", d.Id())
-			d.SetId("")
-			return nil
+		return diag.FromErr(err)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return diag.Errorf("error unmarshalling detector body: %+v", err)
+	}
+
+	detectorType, _ := detector["detector_type"].(string)
+	var index string
+	for _, detectorInput := range saDetectorInputs(detector) {
+		indices, _ := detectorInput["indices"].([]interface{})
+		if len(indices) > 0 {
+			index, _ = indices[0].(string)
+			break
 		}
+	}
+	if index == "" {
+		return nil
+	}
 
-		return err
+	aliases, err := resourceOpensearchSaFieldAliases(index, detectorType, m)
+	if err != nil {
+		return diag.Errorf("error fetching the field aliases of log type %s: %+v", detectorType, err)
 	}
 
-	d.SetId(res.ID)
+	var diags diag.Diagnostics
+	for _, ruleID := range saDetectorCustomRuleIDs(detector) {
+		rule, err := resourceOpensearchSaDetectorRuleGet(ruleID, m)
+		if err != nil {
+			return append(diags, diag.Errorf("error fetching custom rule %s: %+v", ruleID, err)...)
+		}
 
-	SaDetectorJSON, err := json.Marshal(res.Detector)
+		unknown := make([]string, 0)
+		for _, field := range sigmaRuleFields(rule.Rule["rule"]) {
+			if !aliases[field] {
+				unknown = append(unknown, field)
+			}
+		}
+		if len(unknown) > 0 {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Custom rule uses fields that are not field aliases of the detector type",
+				Detail:   fmt.Sprintf("Custom rule %s uses the fields %s, which are not field aliases of log type %q. The rule will not match these fields in documents evaluated by this detector.", ruleID, strings.Join(unknown, ", "), detectorType),
+			})
+		}
+	}
+
+	return diags
+}
+
+// saRolloverAliasSettings are the index settings naming the alias an index is
+// rolled over through, by ISM or by the lifecycle policies of older clusters.
+var saRolloverAliasSettings = []string{
+	"index.plugins.index_state_management.rollover_alias",
+	"index.opendistro.index_state_management.rollover_alias",
+	"index.lifecycle.rollover_alias",
+}
+
+// resourceOpensearchSaDetectorCheckRolloverIndices warns about the indices of
+// the detector inputs that are rolled over through an alias, which the
+// detector should reference instead.
+func resourceOpensearchSaDetectorCheckRolloverIndices(d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("validate_rollover_indices").(bool) {
+		return nil
+	}
+
+	body, err := resourceOpensearchSaDetectorBody(d)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
-	SaDetectorJsonNormalized, err := structure.NormalizeJsonString(string(SaDetectorJSON))
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		return diag.Errorf("error unmarshalling detector body: %+v", err)
+	}
+
+	// patterns match rolled indices on purpose
+	var names []string
+	for _, detectorInput := range saDetectorInputs(detector) {
+		indices, _ := detectorInput["indices"].([]interface{})
+		for _, i := range indices {
+			if name, _ := i.(string); name != "" && !strings.ContainsAny(name, "*?") && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	aliases, err := resourceOpensearchRolloverAliases(names, m)
 	if err != nil {
-		return err
+		return diag.Errorf("error fetching the settings of the detector indices: %+v", err)
 	}
-	err = d.Set("body", SaDetectorJsonNormalized)
-	return err
+
+	var diags diag.Diagnostics
+	for _, name := range names {
+		// aliases and missing indices are not returned under their own name
+		if alias, ok := aliases[name]; ok && alias != "" {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Detector references a rolled over index",
+				Detail:   fmt.Sprintf("The index %s of the detector is rolled over through the alias %s. Once it rolls over, the detector keeps reading %s only. Reference the alias %s instead.", name, alias, name, alias),
+			})
+		}
+	}
+	return diags
 }
 
-func resourceOpensearchSaDetectorUpdate(d *schema.ResourceData, m interface{}) error {
-	_, err := resourceOpensearchPutSaDetector(d, m)
+// resourceOpensearchRolloverAliases returns the rollover alias of each
+// concrete index among names, by index name. Indices rolled over through no
+// alias map to an empty string, aliases and missing indices are left out.
+func resourceOpensearchRolloverAliases(names []string, m interface{}) (map[string]string, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	escaped := make([]string, 0, len(names))
+	for _, name := range names {
+		escaped = append(escaped, url.PathEscape(name))
+	}
+	params := url.Values{}
+	params.Set("flat_settings", "true")
+	params.Set("ignore_unavailable", "true")
+	params.Set("allow_no_indices", "true")
 
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", joinURLPath(strings.Join(escaped, ","), "_settings"), params, ""))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var indices map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := json.Unmarshal(res.Body, &indices); err != nil {
+		return nil, fmt.Errorf("error unmarshalling index settings: %+v", err)
+	}
+
+	aliases := make(map[string]string, len(indices))
+	for index, settings := range indices {
+		aliases[index] = ""
+		for _, setting := range saRolloverAliasSettings {
+			if alias, _ := settings.Settings[setting].(string); alias != "" {
+				aliases[index] = alias
+				break
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// resourceOpensearchSaFieldAliases returns the field aliases of a log type,
+// both those mapped to a field of the index and those left unmapped.
+func resourceOpensearchSaFieldAliases(index string, logType string, m interface{}) (map[string]bool, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("index_name", index)
+	params.Set("rule_topic", logType)
+
+	var res *elastic7.Response
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", joinURLPath(saAPIPath, "mappings/view"), params, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	var view struct {
+		Properties           map[string]interface{} `json:"properties"`
+		UnmappedFieldAliases []string               `json:"unmapped_field_aliases"`
+	}
+	if err := json.Unmarshal(res.Body, &view); err != nil {
+		return nil, fmt.Errorf("error unmarshalling mappings view: %+v", err)
+	}
+
+	aliases := make(map[string]bool, len(view.Properties)+len(view.UnmappedFieldAliases))
+	for alias := range view.Properties {
+		aliases[alias] = true
+	}
+	for _, alias := range view.UnmappedFieldAliases {
+		aliases[alias] = true
+	}
+	return aliases, nil
+}
+
+// saDetectorCustomRuleIDs collects the IDs of the custom rules referenced by
+// all inputs of a detector document.
+func saDetectorCustomRuleIDs(detector map[string]interface{}) []string {
+	return saDetectorRuleIDs(detector, "custom_rules")
+}
+
+// saDetectorRuleIDs collects the IDs of the rules listed under kind, either
+// "custom_rules" or "pre_packaged_rules", in all inputs of a detector document.
+func saDetectorRuleIDs(detector map[string]interface{}, kind string) []string {
+	ids := make([]string, 0)
+	for _, detectorInput := range saDetectorInputs(detector) {
+		rules, _ := detectorInput[kind].([]interface{})
+		for _, r := range rules {
+			rule, _ := r.(map[string]interface{})
+			if id, ok := rule["id"].(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
 	}
+	return ids
+}
 
-	return resourceOpensearchSaDetectorRead(d, m)
+// saDetectorRuleCount returns the number of distinct rules listed under kind
+// in the inputs of a detector document, zero when it has none.
+func saDetectorRuleCount(detector map[string]interface{}, kind string) int {
+	distinct := make(map[string]bool)
+	for _, id := range saDetectorRuleIDs(detector, kind) {
+		distinct[id] = true
+	}
+	return len(distinct)
 }
 
 func resourceOpensearchSaDetectorGet(SaDetectorID string, m interface{}) (*SaDetectorResponse, error) {
 	var err error
 	response := new(SaDetectorResponse)
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/detectors/{id}", map[string]string{
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}"), map[string]string{
 		"id": SaDetectorID,
 	})
 	if err != nil {
@@ -109,10 +1827,7 @@ func resourceOpensearchSaDetectorGet(SaDetectorID string, m interface{}) (*SaDet
 		return nil, err
 	}
 	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method: "GET",
-		Path:   path,
-	})
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("GET", path, nil, ""))
 	if err != nil {
 		return response, err
 	}
@@ -122,79 +1837,311 @@ func resourceOpensearchSaDetectorGet(SaDetectorID string, m interface{}) (*SaDet
 		return response, fmt.Errorf("error unmarshalling detector body: %+v: %+v", err, body)
 	}
 	log.Printf("[INFO] Response: %+v", response)
-	normalizeSaDetector(response.Detector)
+	response.normalize()
 	log.Printf("[INFO] Response: %+v", response)
 	log.Printf("The version %v", response.Version)
 	return response, err
 }
 
+// saDetectorSearchOptions narrows down the documents matched when looking up
+// a detector by ID through the search endpoint.
+type saDetectorSearchOptions struct {
+	Index   string
+	Routing string
+}
+
+// saSearchPath returns the path and query parameters of a search for
+// security analytics objects: defaultPath with defaultParams, unless index
+// names an index or alias to search instead, which does not take the
+// parameters of the plugin endpoints.
+func saSearchPath(index string, defaultPath string, defaultParams url.Values) (string, url.Values, error) {
+	if index == "" {
+		return defaultPath, defaultParams, nil
+	}
+
+	path, err := uritemplates.Expand(joinURLPath("{index}", "_search"), map[string]string{
+		"index": index,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error building URL path for security analytics search: %+v", err)
+	}
+	return path, nil, nil
+}
+
 func resourceOpensearchSaDetectorSearch(SaDetectorID string, m interface{}) (*SaDetectorResponse, error) {
-	var err error
-	response := new(SaDetectorResponse)
+	return resourceOpensearchSaDetectorSearchWithOptions(SaDetectorID, saDetectorSearchOptions{}, m)
+}
+
+// saDetectorSearchQuery builds the query matching a detector by ID. Without
+// options it is a plain ids query.
+func saDetectorSearchQuery(SaDetectorID string, opts saDetectorSearchOptions) map[string]interface{} {
+	return map[string]interface{}{
+		"size":    1,
+		"version": true,
+		"query":   saIDsQuery([]string{SaDetectorID}, opts.Index, opts.Routing),
+	}
+}
+
+// resourceOpensearchSaDetectorSearchWithOptions looks up a detector by ID.
+// The sa_routing of the provider applies when opts sets no routing.
+func resourceOpensearchSaDetectorSearchWithOptions(SaDetectorID string, opts saDetectorSearchOptions, m interface{}) (*SaDetectorResponse, error) {
+	if opts.Routing == "" {
+		opts.Routing = m.(*ProviderConf).saRouting
+	}
+
+	path, params, err := saSearchPath(m.(*ProviderConf).saDetectorsIndex, joinURLPath(saAPIPath, "detectors/_search"), nil)
+	if err != nil {
+		return nil, err
+	}
+	hit, err := saSearchOne(path, params, saDetectorSearchQuery(SaDetectorID, opts), "detector", SaDetectorID, m)
+	if err != nil {
+		return new(SaDetectorResponse), err
+	}
+
+	response := &SaDetectorResponse{
+		ID:       hit.ID,
+		Version:  hit.Version,
+		Detector: hit.Source,
+	}
+	response.normalize()
+	log.Printf("[INFO] Response: %+v", response)
+	return response, nil
+}
+
+// resourceOpensearchSaDetectorEach calls fn with every detector on the
+// cluster, paging through the detector search endpoint so that a single page
+// is held in memory at a time. The detectors index only exists once a detector
+// has been created, until then fn is never called.
+func resourceOpensearchSaDetectorEach(m interface{}, fn func(*SaDetectorResponse) error) error {
+	path, params, err := saSearchPath(m.(*ProviderConf).saDetectorsIndex, joinURLPath(saAPIPath, "detectors/_search"), nil)
+	if err != nil {
+		return err
+	}
 
 	query := map[string]interface{}{
-		"size": 1,
 		"query": map[string]interface{}{
-			"ids": map[string]interface{}{
-				"values": []string{SaDetectorID},
-			},
+			"match_all": map[string]interface{}{},
 		},
 	}
+	seen := 0
+	err = saSearchEach(path, params, query, "detector", m, func(hit saHit) error {
+		seen++
+		response := &SaDetectorResponse{
+			ID:       hit.ID,
+			Version:  hit.Version,
+			Detector: hit.Source,
+		}
+		response.normalize()
+		return fn(response)
+	})
+	// no detector has been created yet
+	if seen == 0 && IsSearchNotFound(err) {
+		return nil
+	}
+	return err
+}
 
-	queryBody, err := json.Marshal(query)
+// resourceOpensearchSaDetectorByName returns the detector with the given
+// name, failing unless exactly one detector has it.
+func resourceOpensearchSaDetectorByName(name string, m interface{}) (*SaDetectorResponse, error) {
+	var detector *SaDetectorResponse
+	var ids []string
+	err := resourceOpensearchSaDetectorEach(m, func(res *SaDetectorResponse) error {
+		if res.Detector["name"] == name {
+			detector = res
+			ids = append(ids, res.ID)
+		}
+		return nil
+	})
 	if err != nil {
-		return response, fmt.Errorf("error marshalling query body: %+v", err)
+		return nil, fmt.Errorf("error listing detectors: %+v", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no detector named %q found", name)
 	}
+	if len(ids) > 1 {
+		return nil, fmt.Errorf("several detectors are named %q: %s", name, strings.Join(ids, ", "))
+	}
+	return detector, nil
+}
 
-	log.Printf("[DEBUG] queryBody=%s", queryBody)
+// resourceOpensearchSaDetectorsReferencingRule returns the IDs of the
+// detectors using the given custom rule in any of their inputs.
+func resourceOpensearchSaDetectorsReferencingRule(ruleID string, m interface{}) ([]string, error) {
+	ids := make([]string, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		if containsString(saDetectorCustomRuleIDs(detector.Detector), ruleID) {
+			ids = append(ids, detector.ID)
+		}
+		return nil
+	})
+	return ids, err
+}
 
-	osClient, err := getClient(m.(*ProviderConf))
+// resourceOpensearchSaDetectorsReplaceRule updates the detectors referencing
+// the custom rule oldID to reference newID instead, and returns their IDs.
+// Detectors already referencing newID are left with a single reference to it.
+func resourceOpensearchSaDetectorsReplaceRule(oldID, newID string, m interface{}) ([]string, error) {
+	detectors := make([]*SaDetectorResponse, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		if containsString(saDetectorCustomRuleIDs(detector.Detector), oldID) {
+			detectors = append(detectors, detector)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method:      "POST",
-		Path:        "/_plugins/_security_analytics/detectors/_search",
-		Body:        string(queryBody),
-		ContentType: "application/json",
+
+	ids := make([]string, 0, len(detectors))
+	for _, detector := range detectors {
+		replaceSaDetectorCustomRule(detector.Detector, oldID, newID)
+		// the owner is read from the user recorded on the detector, which is
+		// stripped from the document
+		if len(detector.BackendRoles) > 0 {
+			detector.Detector["rbac_roles"] = detector.BackendRoles
+		}
+		if err := resourceOpensearchPutSaDetectorDocument(detector.ID, detector.Detector, m); err != nil {
+			return ids, fmt.Errorf("error updating detector %s: %+v", detector.ID, err)
+		}
+		ids = append(ids, detector.ID)
+	}
+	return ids, nil
+}
+
+// replaceSaDetectorCustomRule replaces the references to the custom rule
+// oldID in every input of a detector document with references to newID.
+func replaceSaDetectorCustomRule(detector map[string]interface{}, oldID, newID string) {
+	for _, detectorInput := range saDetectorInputs(detector) {
+		rules, _ := detectorInput["custom_rules"].([]interface{})
+		replaced := make([]interface{}, 0, len(rules))
+		seen := false
+		for _, r := range rules {
+			rule, _ := r.(map[string]interface{})
+			id, _ := rule["id"].(string)
+			if id == oldID || id == newID {
+				if seen {
+					continue
+				}
+				seen = true
+				rule = map[string]interface{}{"id": newID}
+			}
+			replaced = append(replaced, rule)
+		}
+		detectorInput["custom_rules"] = replaced
+	}
+}
+
+// resourceOpensearchPutSaDetectorDocument updates a detector, outside of the
+// resource managing it, with the given document.
+func resourceOpensearchPutSaDetectorDocument(id string, detector map[string]interface{}, m interface{}) error {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	body, err := json.Marshal(detector)
+	if err != nil {
+		return fmt.Errorf("error marshalling detector: %+v", err)
+	}
+
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}"), map[string]string{
+		"id": id,
 	})
 	if err != nil {
-		return response, err
+		return fmt.Errorf("error building URL path for detector: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return err
 	}
+	_, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationUpdate, saRequestOptions("PUT", path, nil, string(body)))
+	return err
+}
 
-	var searchResult querySearchResult
+// saDetectorSizeCheckThreshold is the size of the detector bodies above which
+// the http.max_content_length of the cluster is looked up before sending them.
+// The limit is far higher on most clusters, smaller bodies rejected by lower
+// ones are reported from the response.
+const saDetectorSizeCheckThreshold = 1 << 20
 
-	if err := json.Unmarshal(res.Body, &searchResult); err != nil {
-		return response, fmt.Errorf("error unmarshalling search result: %+v", err)
+// resourceOpensearchSaDetectorCheckSize fails when a detector body exceeds
+// the http.max_content_length of the cluster. The limit is only looked up for
+// bodies above saDetectorSizeCheckThreshold, and the check is skipped when
+// it cannot be.
+func resourceOpensearchSaDetectorCheckSize(body string, m interface{}) error {
+	if len(body) <= saDetectorSizeCheckThreshold {
+		return nil
 	}
 
-	if searchResult.Hits.Total.Value == 0 {
-		return response, fmt.Errorf("no search results found for ID: %s", SaDetectorID)
+	settings, err := resourceOpensearchGetSaClusterSettings(m)
+	if err != nil {
+		log.Printf("[WARN] Could not look up http.max_content_length, sending the detector body of %d bytes as is: %+v", len(body), err)
+		return nil
+	}
+	value, _ := settings.effective("http.max_content_length")
+	limit, err := parseSaByteSize(fmt.Sprintf("%v", value))
+	if err != nil {
+		log.Printf("[WARN] Could not parse http.max_content_length, sending the detector body of %d bytes as is: %+v", len(body), err)
+		return nil
+	}
+	if int64(len(body)) > limit {
+		return saDetectorTooLargeError(len(body), limit)
 	}
+	return nil
+}
 
-	var detector map[string]interface{}
-	if err := json.Unmarshal(searchResult.Hits.Hits[0].Source, &detector); err != nil {
-		return response, fmt.Errorf("error unmarshalling detector source: %+v", err)
+// saDetectorTooLargeError describes a detector body of size bytes rejected
+// by a cluster accepting at most limit bytes, or an unknown limit when zero.
+func saDetectorTooLargeError(size int, limit int64) error {
+	accepted := "the http.max_content_length the cluster accepts"
+	if limit > 0 {
+		accepted = fmt.Sprintf("the http.max_content_length of %d bytes the cluster accepts", limit)
 	}
+	return fmt.Errorf("the detector body is %d bytes, more than %s: split the rules of the detector across several detectors, or raise http.max_content_length in the configuration of the cluster nodes. Compressing requests does not help, the limit applies to the decompressed body", size, accepted)
+}
 
-	response.ID = searchResult.Hits.Hits[0].ID
-	response.Version = searchResult.Hits.Hits[0].Version
-	response.Detector = detector
-	log.Printf("[INFO] Response: %+v", response)
-	normalizeSaDetector(response.Detector)
-	log.Printf("[INFO] Response: %+v", response)
-	log.Printf("The version %v", response.Version)
-	return response, err
+// saByteSizeUnits are the units of OpenSearch byte size values, in bytes.
+var saByteSizeUnits = map[string]int64{
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+	"pb": 1 << 50,
+}
+
+// parseSaByteSize parses an OpenSearch byte size value such as "100mb".
+func parseSaByteSize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	number := strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyz")
+	unit := value[len(number):]
+	if unit == "" {
+		unit = "b"
+	}
+	multiplier, ok := saByteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown byte size unit in %q", value)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+	return int64(n * float64(multiplier)), nil
 }
 
 func resourceOpensearchPostSaDetector(d *schema.ResourceData, m interface{}) (*SaDetectorResponse, error) {
-	SaDetectorJSON := d.Get("body").(string)
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	SaDetectorJSON, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := resourceOpensearchSaDetectorCheckSize(SaDetectorJSON, m); err != nil {
+		return nil, err
+	}
 
-	var err error
 	response := new(SaDetectorResponse)
 
-	path := "/_plugins/_security_analytics/detectors"
+	path := joinURLPath(saAPIPath, "detectors")
 
 	var body json.RawMessage
 	osClient, err := getClient(m.(*ProviderConf))
@@ -202,11 +2149,10 @@ func resourceOpensearchPostSaDetector(d *schema.ResourceData, m interface{}) (*S
 		return nil, err
 	}
 	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method: "POST",
-		Path:   path,
-		Body:   SaDetectorJSON,
-	})
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationCreate, saRequestOptions("POST", path, nil, SaDetectorJSON))
+	if saRequestTooLarge(err) {
+		return response, saDetectorTooLargeError(len(SaDetectorJSON), 0)
+	}
 	if err != nil {
 		return response, err
 	}
@@ -215,17 +2161,25 @@ func resourceOpensearchPostSaDetector(d *schema.ResourceData, m interface{}) (*S
 	if err := json.Unmarshal(body, response); err != nil {
 		return response, fmt.Errorf("error unmarshalling detector body: %+v: %+v", err, body)
 	}
-	normalizeSaDetector(response.Detector)
+	response.normalize()
+	response.Warnings = saResponseWarnings(res.Header)
 	return response, nil
 }
 
 func resourceOpensearchPutSaDetector(d *schema.ResourceData, m interface{}) (*SaDetectorResponse, error) {
-	SaDetectorJSON := d.Get("body").(string)
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	SaDetectorJSON, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := resourceOpensearchSaDetectorCheckSize(SaDetectorJSON, m); err != nil {
+		return nil, err
+	}
 
-	var err error
 	response := new(SaDetectorResponse)
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/detectors/{id}", map[string]string{
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}"), map[string]string{
 		"id": d.Id(),
 	})
 	if err != nil {
@@ -238,11 +2192,10 @@ func resourceOpensearchPutSaDetector(d *schema.ResourceData, m interface{}) (*Sa
 		return nil, err
 	}
 	var res *elastic7.Response
-	res, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method: "PUT",
-		Path:   path,
-		Body:   SaDetectorJSON,
-	})
+	res, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationUpdate, saRequestOptions("PUT", path, nil, SaDetectorJSON))
+	if saRequestTooLarge(err) {
+		return response, saDetectorTooLargeError(len(SaDetectorJSON), 0)
+	}
 	if err != nil {
 		return response, err
 	}
@@ -251,34 +2204,119 @@ func resourceOpensearchPutSaDetector(d *schema.ResourceData, m interface{}) (*Sa
 	if err := json.Unmarshal(body, response); err != nil {
 		return response, fmt.Errorf("error unmarshalling detector body: %+v: %+v", err, body)
 	}
-
+	response.normalize()
 	return response, nil
 }
 
-func resourceOpensearchSaDetectorDelete(d *schema.ResourceData, m interface{}) error {
+func resourceOpensearchSaDetectorDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
 	var err error
 
-	path, err := uritemplates.Expand("/_plugins/_security_analytics/detectors/{id}", map[string]string{
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}"), map[string]string{
 		"id": d.Id(),
 	})
 	if err != nil {
-		return fmt.Errorf("error building URL path for detector: %+v", err)
+		return diag.Errorf("error building URL path for detector: %+v", err)
 	}
 
 	osClient, err := getClient(m.(*ProviderConf))
 	if err != nil {
-		return err
+		return diag.FromErr(err)
+	}
+	_, err = performSaRequest(ctx, m.(*ProviderConf), osClient, saOperationDelete, saRequestOptions("DELETE", path, nil, ""))
+	// a retried delete may find the detector deleted by the first attempt
+	if IsSearchNotFound(err) {
+		log.Printf("[WARN] Security Analytics Detector (%s) already deleted", d.Id())
+		return nil
 	}
-	_, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
-		Method: "DELETE",
-		Path:   path,
-	})
 
-	return err
+	return diag.FromErr(err)
+}
+
+type SaMonitorErrorAlert struct {
+	MonitorID    string `json:"monitor_id"`
+	ErrorMessage string `json:"error_message"`
+	StartTime    int64  `json:"start_time"`
 }
 
 type SaDetectorResponse struct {
 	Version  int                    `json:"_version"`
 	ID       string                 `json:"_id"`
 	Detector map[string]interface{} `json:"detector"`
+
+	// server state captured before the detector is normalized
+	ThreatIntelEnabled bool     `json:"-"`
+	BackendRoles       []string `json:"-"`
+	WorkflowID         string   `json:"-"`
+	MonitorIDs         []string `json:"-"`
+	// the top-level server-managed fields, see saDetectorServerFields
+	ServerFields map[string]interface{} `json:"-"`
+
+	// the warnings the cluster sent along with the response
+	Warnings []string `json:"-"`
+}
+
+// normalize records the server-managed fields exposed as computed attributes
+// and strips them from the detector document.
+func (r *SaDetectorResponse) normalize() {
+	if r.Detector == nil {
+		return
+	}
+
+	// clusters without threat intel support omit the field
+	r.ThreatIntelEnabled, _ = r.Detector["threat_intel_enabled"].(bool)
+
+	r.MonitorIDs = make([]string, 0)
+	monitorIDs, _ := r.Detector["monitor_id"].([]interface{})
+	for _, id := range monitorIDs {
+		if id, ok := id.(string); ok {
+			r.MonitorIDs = append(r.MonitorIDs, id)
+		}
+	}
+
+	// a detector runs a single workflow, on versions that create one
+	r.WorkflowID = ""
+	if workflowIDs, ok := r.Detector["workflow_ids"].([]interface{}); ok && len(workflowIDs) > 0 {
+		r.WorkflowID, _ = workflowIDs[0].(string)
+	}
+
+	r.ServerFields = make(map[string]interface{})
+	for _, field := range saDetectorServerFields() {
+		if value, ok := r.Detector[field]; ok {
+			r.ServerFields[field] = value
+		}
+	}
+
+	r.BackendRoles = make([]string, 0)
+	user, _ := r.Detector["user"].(map[string]interface{})
+	roles, _ := user["backend_roles"].([]interface{})
+	for _, role := range roles {
+		if role, ok := role.(string); ok {
+			r.BackendRoles = append(r.BackendRoles, role)
+		}
+	}
+	saDetectorNormalizer.normalize(r.Detector)
+}
+
+// flattenSaServerFields returns the server-managed fields listed in keep,
+// with the values that are not strings encoded as JSON.
+func flattenSaServerFields(fields map[string]interface{}, keep []string) (map[string]string, error) {
+	flattened := make(map[string]string, len(keep))
+	for _, field := range keep {
+		value, ok := fields[field]
+		if !ok {
+			continue
+		}
+		if str, ok := value.(string); ok {
+			flattened[field] = str
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling server field %s: %+v", field, err)
+		}
+		flattened[field] = string(encoded)
+	}
+	return flattened, nil
 }
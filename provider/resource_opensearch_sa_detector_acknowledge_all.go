@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceOpenSearchSaDetectorAcknowledgeAll() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Acknowledges every active security analytics alert of a detector, for example as a post-incident cleanup step. The alerts are acknowledged when the resource is created, which does nothing when no active alert remains. Destroying the resource only removes it from the state; replace it, for example with `terraform apply -replace`, to acknowledge the alerts raised since.",
+		CreateContext: resourceOpensearchSaDetectorAcknowledgeAllCreate,
+		ReadContext:   resourceOpensearchSaDetectorAcknowledgeAllRead,
+		DeleteContext: resourceOpensearchSaDetectorAcknowledgeAllDelete,
+		Schema: map[string]*schema.Schema{
+			"detector_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The ID of the detector to acknowledge the alerts of.",
+			},
+			"acknowledged_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of alerts acknowledged.",
+			},
+			"failed_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of active alerts the cluster failed to acknowledge or could no longer find.",
+			},
+		},
+	}
+}
+
+func resourceOpensearchSaDetectorAcknowledgeAllCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	detectorID := d.Get("detector_id").(string)
+
+	ids, err := resourceOpensearchSaAlertsSearch(detectorID, saAlertsFilter{}, m)
+	if err != nil {
+		return diag.Errorf("error searching alerts of detector %s: %+v", detectorID, err)
+	}
+
+	acknowledged, failed, err := resourceOpensearchSaAlertsAcknowledgeBatches(detectorID, ids, m)
+	log.Printf("[INFO] Acknowledged %d of the %d active alerts of detector %s", len(acknowledged), len(ids), detectorID)
+
+	// even on failure, the alerts acknowledged are counted
+	d.SetId(detectorID)
+	ds := &resourceDataSetter{d: d}
+	ds.set("acknowledged_count", len(acknowledged))
+	ds.set("failed_count", len(failed))
+	if ds.err != nil {
+		return diag.FromErr(ds.err)
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return saAlertsNotAcknowledgedDiagnostics(detectorID, failed)
+}
+
+func resourceOpensearchSaDetectorAcknowledgeAllRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceOpensearchSaDetectorAcknowledgeAllDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
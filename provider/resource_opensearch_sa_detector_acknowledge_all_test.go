@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestSaDetectorAcknowledgeAll(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_alerts":2,"alerts":[{"id":"a1","start_time":"not a date"}]}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_alerts":2,"alerts":[{"id":"a2"}]}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"acknowledged":[{"id":"a1"},{"id":"a2"}],"failed":[],"missing":[]}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorAcknowledgeAll().Schema, map[string]interface{}{
+		"detector_id": "d1",
+	})
+	if diags := resourceOpensearchSaDetectorAcknowledgeAllCreate(context.Background(), d, conf); len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+
+	if len(cluster.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(cluster.requests))
+	}
+	if query := cluster.requests[0].Query; query.Get("alertState") != "ACTIVE" || query.Get("severityLevel") != "ALL" {
+		t.Errorf("unexpected query: %v", query)
+	}
+	if body := cluster.lastRequest(t).Body; body != `{"alerts":["a1","a2"]}` {
+		t.Errorf("unexpected acknowledge request body: %s", body)
+	}
+	if d.Id() != "d1" || d.Get("acknowledged_count").(int) != 2 || d.Get("failed_count").(int) != 0 {
+		t.Errorf("unexpected state: id %s, acknowledged %v, failed %v", d.Id(), d.Get("acknowledged_count"), d.Get("failed_count"))
+	}
+}
+
+func TestSaDetectorAcknowledgeAllNoActiveAlerts(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"total_alerts":0,"alerts":[]}`},
+	)
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorAcknowledgeAll().Schema, map[string]interface{}{
+		"detector_id": "d1",
+	})
+	if diags := resourceOpensearchSaDetectorAcknowledgeAllCreate(context.Background(), d, conf); len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+
+	// only the search is sent
+	if len(cluster.requests) != 1 || cluster.requests[0].Method != "GET" {
+		t.Errorf("unexpected requests: %+v", cluster.requests)
+	}
+	if d.Get("acknowledged_count").(int) != 0 {
+		t.Errorf("expected no alert acknowledged, got %v", d.Get("acknowledged_count"))
+	}
+}
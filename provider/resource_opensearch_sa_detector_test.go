@@ -0,0 +1,1366 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+func TestAccOpensearchSaDetector(t *testing.T) {
+	var detectorID string
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaDetectorDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetector,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaDetectorExists("opensearch_sa_detector.test_detector"),
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "body_sha256", func(v string) error {
+						if len(v) != 64 {
+							return fmt.Errorf("expected a SHA-256 hex digest, got %q", v)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "id", func(v string) error {
+						detectorID = v
+						return nil
+					}),
+				),
+			},
+			{
+				Config: testAccOpensearchSaDetectorUpdate,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaDetectorExists("opensearch_sa_detector.test_detector"),
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "normalized_body", func(v string) error {
+						if !strings.Contains(v, `"interval":5`) {
+							return fmt.Errorf("expected interval 5 in %s", v)
+						}
+						if !strings.Contains(v, `"severity":"2"`) {
+							return fmt.Errorf("expected trigger severity 2 in %s", v)
+						}
+						return nil
+					}),
+					resource.TestCheckTypeSetElemAttr("opensearch_sa_detector.test_detector", "last_changed_paths.*", "triggers.0.severity"),
+					// the detector is updated in place rather than replaced
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "id", func(v string) error {
+						if v != detectorID {
+							return fmt.Errorf("expected detector %s to be updated in place, got %s", detectorID, v)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaDetector_importBasic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaDetectorDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetector,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaDetectorExists("opensearch_sa_detector.test_detector"),
+					resource.TestCheckResourceAttr("opensearch_sa_detector.test_detector", "is_threat_intel", "false"),
+					resource.TestCheckResourceAttr("opensearch_sa_detector.test_detector", "custom_rule_count", "0"),
+					resource.TestCheckResourceAttr("opensearch_sa_detector.test_detector", "prepackaged_rule_count", "0"),
+				),
+			},
+			{
+				ResourceName:      "opensearch_sa_detector.test_detector",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:      "opensearch_sa_detector.test_detector",
+				ImportState:       true,
+				ImportStateId:     "name:test-detector",
+				ImportStateVerify: true,
+			},
+			{
+				Config:   testAccOpensearchSaDetector,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaDetector_skipReadAfterWrite(t *testing.T) {
+	config := strings.Replace(testAccOpensearchSaDetector, "  depends_on = [opensearch_index.windows]", "  skip_read_after_write = true\n\n  depends_on = [opensearch_index.windows]", 1)
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaDetectorDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaDetectorExists("opensearch_sa_detector.test_detector"),
+					resource.TestCheckResourceAttr("opensearch_sa_detector.test_detector", "skip_read_after_write", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccOpensearchSaDetector_bodyVars(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaDetectorDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccOpensearchSaDetectorBodyVars, 1),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckOpensearchSaDetectorExists("opensearch_sa_detector.test_detector"),
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "normalized_body", func(v string) error {
+						if !strings.Contains(v, `"interval":1`) {
+							return fmt.Errorf("expected interval 1 in %s", v)
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccOpensearchSaDetectorBodyVars, 5),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrWith("opensearch_sa_detector.test_detector", "normalized_body", func(v string) error {
+						if !strings.Contains(v, `"interval":5`) {
+							return fmt.Errorf("expected interval 5 in %s", v)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func testCheckOpensearchSaDetectorExists(name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("Not found: %s", name)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No detector ID is set")
+		}
+
+		meta := testAccOpendistroProvider.Meta()
+
+		var err error
+		_, err = resourceOpensearchSaDetectorSearch(rs.Primary.ID, meta.(*ProviderConf))
+
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func testCheckOpensearchSaDetectorDestroy(s *terraform.State) error {
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "opensearch_sa_detector" {
+			continue
+		}
+
+		meta := testAccOpendistroProvider.Meta()
+
+		var err error
+		_, err = resourceOpensearchSaDetectorSearch(rs.Primary.ID, meta.(*ProviderConf))
+
+		if err != nil {
+			if IsSearchNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		return fmt.Errorf("Detector %q still exists", rs.Primary.ID)
+	}
+
+	return nil
+}
+
+func TestIsSearchNotFound(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "no search results", err: searchNotFoundError("abc"), expected: true},
+		{name: "wrapped", err: fmt.Errorf("reading detector: %w", searchNotFoundError("abc")), expected: true},
+		{name: "http not found", err: &elastic7.Error{Status: 404}, expected: true},
+		{name: "missing index", err: &elastic7.Error{Status: 500, Details: &elastic7.ErrorDetails{Type: "index_not_found_exception"}}, expected: true},
+		{name: "server error", err: &elastic7.Error{Status: 500, Details: &elastic7.ErrorDetails{Type: "exception"}}, expected: false},
+		{name: "other error", err: fmt.Errorf("connection refused"), expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if notFound := IsSearchNotFound(c.err); notFound != c.expected {
+				t.Errorf("expected IsSearchNotFound to be %t, got %t", c.expected, notFound)
+			}
+		})
+	}
+}
+
+func TestSaSearchPath(t *testing.T) {
+	defaultPath := "/_plugins/_security_analytics/detectors/_search"
+	for index, expected := range map[string]string{
+		"":                   defaultPath,
+		"sap-detectors":      "/sap-detectors/_search",
+		"detectors,archived": "/detectors%2Carchived/_search",
+	} {
+		path, params, err := saSearchPath(index, defaultPath, url.Values{"pre_packaged": []string{"false"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != expected {
+			t.Errorf("expected path %s for index %q, got %s", expected, index, path)
+		}
+		// indices do not take the parameters of the plugin endpoints
+		if (len(params) > 0) != (index == "") {
+			t.Errorf("unexpected parameters %v for index %q", params, index)
+		}
+	}
+}
+
+func TestUnwrapSearchSource(t *testing.T) {
+	for source, expected := range map[string]string{
+		`{"detector":{"name":"a"}}`:            `{"name":"a"}`,
+		`{"name":"a"}`:                         `{"name":"a"}`,
+		`{"detector":{"name":"a"},"other":1}`:  `{"detector":{"name":"a"},"other":1}`,
+		`{"rule":"title: a","category":"dns"}`: `{"category":"dns","rule":"title: a"}`,
+	} {
+		wrapper := "detector"
+		if strings.Contains(source, "rule") {
+			wrapper = "rule"
+		}
+		doc, err := unwrapSearchSource(json.RawMessage(source), wrapper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, _ := json.Marshal(doc)
+		if string(actual) != expected {
+			t.Errorf("expected %s for %s, got %s", expected, source, actual)
+		}
+	}
+}
+
+func TestDiffSuppressSaDetectorNumbers(t *testing.T) {
+	server, err := os.ReadFile("./test-fixtures/sa_detector_float_schedule.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := `{
+  "name": "test-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {"period": {"interval": %s, "unit": "MINUTES"}},
+  "inputs": [{"detector_input": {"description": "", "indices": ["windows"], "custom_rules": [], "pre_packaged_rules": []}}],
+  "triggers": [{"name": "test-trigger", "severity": "1", "types": ["windows"], "ids": [], "sev_levels": [], "tags": [], "actions": []}]
+}`
+
+	if !diffSuppressSaDetector("body", string(server), fmt.Sprintf(config, "5"), nil) {
+		t.Errorf("expected interval 5.0 read from the cluster to match 5 in the configuration")
+	}
+	if diffSuppressSaDetector("body", string(server), fmt.Sprintf(config, "5.5"), nil) {
+		t.Errorf("expected interval 5.0 not to match 5.5")
+	}
+	if diffSuppressSaDetector("body", `{"last_seen": 9007199254740993}`, `{"last_seen": 9007199254740992}`, nil) {
+		t.Errorf("expected large integers to be compared exactly")
+	}
+}
+
+func TestRenderBodyVars(t *testing.T) {
+	body := `{"name": "${name}", "schedule": {"period": {"interval": ${interval}}}, "message": "${unknown}"}`
+	vars := map[string]interface{}{
+		"name":     "detector-a",
+		"interval": "5",
+	}
+
+	expected := `{"name": "detector-a", "schedule": {"period": {"interval": 5}}, "message": "${unknown}"}`
+	if rendered := renderBodyVars(body, vars); rendered != expected {
+		t.Errorf("expected %s, got %s", expected, rendered)
+	}
+
+	if _, errs := validateBodyTemplateJSON(body, "body"); len(errs) > 0 {
+		t.Errorf("expected template to validate, got %v", errs)
+	}
+}
+
+func TestSaDetectorScheduleCron(t *testing.T) {
+	for expr, valid := range map[string]bool{
+		"*/5 * * * *":    true,
+		"0 9-17 * * 1-5": true,
+		"0 9 * *":        false,
+		"0 9 * * MON;":   false,
+	} {
+		if _, errs := validateCronExpression(expr, "schedule_cron"); (len(errs) == 0) != valid {
+			t.Errorf("expected validity of %q to be %t, got errors %v", expr, valid, errs)
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":          `{"name": "test"}`,
+		"schedule_cron": "*/5 * * * *",
+	})
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"test","schedule":{"cron":{"expression":"*/5 * * * *","timezone":"UTC"}}}`
+	if body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	d = schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":          `{"name": "test", "schedule": {"period": {"interval": 1, "unit": "MINUTES"}}}`,
+		"schedule_cron": "*/5 * * * *",
+	})
+	if _, err := resourceOpensearchSaDetectorBody(d); err == nil {
+		t.Error("expected an error when both schedule_cron and a body schedule are set")
+	}
+}
+
+func TestSaDetectorScheduleJitter(t *testing.T) {
+	for _, c := range []struct {
+		expression string
+		offset     int
+		expected   string
+	}{
+		{"0 * * * *", 0, "0 * * * *"},
+		{"0 * * * *", 7, "7 * * * *"},
+		{"0,30 9 * * 1-5", 7, "7,37 9 * * 1-5"},
+		{"55 * * * *", 7, "2 * * * *"},
+		{"*/15 * * * *", 7, "7-59/15 * * * *"},
+		{"*/5 * * * *", 7, "2-59/5 * * * *"},
+	} {
+		jittered, err := jitterCronExpression(c.expression, c.offset)
+		if err != nil || jittered != c.expected {
+			t.Errorf("expected %q offset by %d to be %q, got %q (%v)", c.expression, c.offset, c.expected, jittered, err)
+		}
+	}
+	for _, expression := range []string{"* * * * *", "0-30 * * * *", "*/x * * * *"} {
+		if _, err := jitterCronExpression(expression, 3); err == nil {
+			t.Errorf("expected %q to be rejected", expression)
+		}
+	}
+
+	if offset := saScheduleJitterOffset("test", 0); offset != 0 {
+		t.Errorf("expected no offset without jitter, got %d", offset)
+	}
+	offset := saScheduleJitterOffset("test", 10)
+	if offset < 0 || offset > 10 || offset != saScheduleJitterOffset("test", 10) {
+		t.Errorf("expected a stable offset between 0 and 10, got %d", offset)
+	}
+
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":            `{"name": "test"}`,
+		"schedule_cron":   "0 * * * *",
+		"schedule_jitter": 10,
+	})
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf(`{"name":"test","schedule":{"cron":{"expression":"%d * * * *","timezone":"UTC"}}}`, offset)
+	if body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &detector); err != nil {
+		t.Fatal(err)
+	}
+	if err := flattenSaDetectorFields(d, detector); err != nil {
+		t.Fatal(err)
+	}
+	if cron := d.Get("schedule_cron").(string); cron != "0 * * * *" {
+		t.Errorf("expected the configured expression to be read back, got %q", cron)
+	}
+	if got := d.Get("schedule_jitter_offset").(int); got != offset {
+		t.Errorf("expected schedule_jitter_offset %d, got %d", offset, got)
+	}
+	if _, ok := detector["schedule"]; ok {
+		t.Errorf("expected the schedule to be removed from the body read back, got %v", detector)
+	}
+}
+
+func TestSaDetectorNotificationChannels(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":                  `{"triggers": [{"actions": [{"destination_id": "${ops}"}]}]}`,
+		"notification_channels": map[string]interface{}{"ops": "ops-slack"},
+	})
+	if err := d.Set("notification_channel_ids", map[string]string{"ops": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"triggers":[{"actions":[{"destination_id":"abc"}]}]}`
+	if body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	if err := d.Set("body_vars", map[string]string{"ops": `"x"`}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resourceOpensearchSaDetectorBody(d); err == nil {
+		t.Error("expected an error when a placeholder is set both in body_vars and notification_channels")
+	}
+}
+
+func TestSaThreatIntelSupported(t *testing.T) {
+	for osVersion, supported := range map[string]bool{
+		"2.11.1":          false,
+		"2.12.0":          true,
+		"2.12.0-SNAPSHOT": true,
+		"3.0.0":           true,
+		"1.3.14":          false,
+		"":                true,
+	} {
+		if saThreatIntelSupported(osVersion) != supported {
+			t.Errorf("expected threat intel support of %q to be %t", osVersion, supported)
+		}
+	}
+}
+
+func TestSaDetectorQueryFilter(t *testing.T) {
+	body := `{"name": "test", "inputs": [{"detector_input": {"indices": ["a"]}}, {"detector_input": {"indices": ["b"]}}]}`
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":         body,
+		"query_filter": `{"term": {"host": "web-1"}}`,
+	})
+	rendered, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"inputs":[{"detector_input":{"indices":["a"],"query_filter":{"term":{"host":"web-1"}}}},{"detector_input":{"indices":["b"],"query_filter":{"term":{"host":"web-1"}}}}],"name":"test"}`
+	if rendered != expected {
+		t.Errorf("expected %s, got %s", expected, rendered)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &detector); err != nil {
+		t.Fatal(err)
+	}
+	if err := flattenSaDetectorFields(d, detector); err != nil {
+		t.Fatal(err)
+	}
+	if !diffSuppressSaDetector("body", body, mustMarshal(t, detector), nil) {
+		t.Errorf("expected query_filter to be removed from the body read back, got %v", detector)
+	}
+	if filter := d.Get("query_filter").(string); filter != `{"term":{"host":"web-1"}}` {
+		t.Errorf("unexpected query_filter %s", filter)
+	}
+
+	d = schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":         `{"name": "test", "inputs": [{"detector_input": {"query_filter": {}}}]}`,
+		"query_filter": `{"match_all": {}}`,
+	})
+	if _, err := resourceOpensearchSaDetectorBody(d); err == nil {
+		t.Error("expected an error when both query_filter and a body query_filter are set")
+	}
+
+	if _, errs := validateJSONObject(`["not", "an", "object"]`, "query_filter"); len(errs) == 0 {
+		t.Error("expected a JSON array to be rejected")
+	}
+}
+
+func TestSaDetectorTriggerThrottle(t *testing.T) {
+	body := `{"name": "test", "triggers": [
+  {"name": "noisy", "actions": [{"name": "page"}, {"name": "mail"}]},
+  {"name": "quiet", "actions": [{"name": "page", "throttle_enabled": false}]}
+]}`
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":                     body,
+		"trigger_throttle_minutes": map[string]interface{}{"noisy": 30},
+	})
+	rendered, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"test","triggers":[{"actions":[{"name":"page","throttle":{"unit":"MINUTES","value":30},"throttle_enabled":true},{"name":"mail","throttle":{"unit":"MINUTES","value":30},"throttle_enabled":true}],"name":"noisy"},{"actions":[{"name":"page","throttle_enabled":false}],"name":"quiet"}]}`
+	if rendered != expected {
+		t.Errorf("expected %s, got %s", expected, rendered)
+	}
+
+	var detector map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.Replace(rendered, `"value":30},"throttle_enabled":true},{"name":"mail"`, `"value":45},"throttle_enabled":true},{"name":"mail"`, 1)), &detector); err != nil {
+		t.Fatal(err)
+	}
+	if err := flattenSaDetectorFields(d, detector); err != nil {
+		t.Fatal(err)
+	}
+	if !diffSuppressSaDetector("body", body, mustMarshal(t, detector), nil) {
+		t.Errorf("expected the throttles to be removed from the body read back, got %v", detector)
+	}
+	if throttles := d.Get("trigger_throttle_minutes").(map[string]interface{}); !reflect.DeepEqual(throttles, map[string]interface{}{"noisy": 45}) {
+		t.Errorf("expected the throttle of the first action to be read back, got %v", throttles)
+	}
+
+	for config, message := range map[string]string{
+		`{"triggers": [{"name": "noisy", "actions": [{"throttle_enabled": true}]}]}`: "both define trigger_throttle_minutes",
+		`{"triggers": [{"name": "noisy", "actions": []}]}`:                           "has no actions",
+		`{"triggers": [{"name": "other", "actions": [{"name": "page"}]}]}`:           "does not define",
+	} {
+		d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+			"body":                     config,
+			"trigger_throttle_minutes": map[string]interface{}{"noisy": 30},
+		})
+		if _, err := resourceOpensearchSaDetectorBody(d); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q for %s, got %v", message, config, err)
+		}
+	}
+}
+
+func TestSaDetectorTriggerBlocks(t *testing.T) {
+	body := `{"name": "test", "triggers": [{"name": "page", "actions": [{"name": "oncall"}]}]}`
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body": body,
+		"trigger": []interface{}{
+			map[string]interface{}{
+				"name":          "page",
+				"rule_severity": []interface{}{"high", "critical"},
+				"log_types":     []interface{}{"windows"},
+			},
+			map[string]interface{}{
+				"name":     "record",
+				"severity": "3",
+				"tags":     []interface{}{"attack.t1078"},
+			},
+		},
+	})
+	rendered, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"test","triggers":[{"actions":[{"name":"oncall"}],"ids":[],"name":"page","sev_levels":["critical","high"],"severity":"1","tags":[],"types":["windows"]},{"actions":[],"ids":[],"name":"record","sev_levels":[],"severity":"3","tags":["attack.t1078"],"types":[]}]}`
+	if rendered != expected {
+		t.Errorf("expected %s, got %s", expected, rendered)
+	}
+
+	// the cluster returns the triggers in another order, with IDs
+	res := &SaDetectorResponse{ID: "d1", Detector: map[string]interface{}{"name": "test", "triggers": []interface{}{
+		map[string]interface{}{"id": "t2", "name": "record", "severity": "3", "sev_levels": []interface{}{}, "types": []interface{}{}, "ids": []interface{}{}, "tags": []interface{}{"attack.t1078"}, "actions": []interface{}{}},
+		map[string]interface{}{"id": "t1", "name": "page", "severity": "2", "sev_levels": []interface{}{"critical", "high"}, "types": []interface{}{"windows"}, "ids": []interface{}{}, "tags": []interface{}{}, "actions": []interface{}{map[string]interface{}{"name": "oncall"}}},
+	}}}
+	res.normalize()
+	if diags := resourceOpensearchSaDetectorSetState(d, res, &ProviderConf{}); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if !diffSuppressSaDetector("body", body, d.Get("body").(string), nil) {
+		t.Errorf("expected the criteria and added triggers to be left out of the body, got %s", d.Get("body"))
+	}
+	triggers := d.Get("trigger").([]interface{})
+	if len(triggers) != 2 || triggers[0].(map[string]interface{})["name"] != "page" || triggers[0].(map[string]interface{})["severity"] != "2" {
+		t.Fatalf("expected the triggers to be read back in the configured order, got %v", triggers)
+	}
+	if severities := triggers[0].(map[string]interface{})["rule_severity"].(*schema.Set); severities.Len() != 2 || !severities.Contains("high") {
+		t.Errorf("expected the rule severities to be read back, got %v", severities.List())
+	}
+
+	d = schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":    `{"triggers": [{"name": "page", "sev_levels": ["high"], "actions": []}]}`,
+		"trigger": []interface{}{map[string]interface{}{"name": "page"}},
+	})
+	if _, err := resourceOpensearchSaDetectorBody(d); err == nil || !strings.Contains(err.Error(), "trigger page (triggers.sev_levels)") {
+		t.Errorf("expected an error about the criteria set in the body, got %v", err)
+	}
+}
+
+func TestSaDetectorMonitorHealth(t *testing.T) {
+	res := &SaDetectorResponse{Detector: map[string]interface{}{"enabled": true, "monitor_id": []interface{}{"m1", "m2", "m3"}}}
+	res.normalize()
+	if !reflect.DeepEqual(res.MonitorIDs, []string{"m1", "m2", "m3"}) {
+		t.Errorf("expected the monitor IDs to be captured, got %v", res.MonitorIDs)
+	}
+
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[
+  {"_id":"m1","_source":{"monitor":{"name":"m1","enabled":true}}},
+  {"_id":"m2","_source":{"monitor":{"name":"m2","enabled":false}}}
+]}}`})
+	statuses, err := resourceOpensearchSaMonitorStatuses(res.MonitorIDs, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request := cluster.lastRequest(t); request.Method != "POST" || request.Path != "/_plugins/_alerting/monitors/_search" || request.Body != `{"query":{"ids":{"values":["m1","m2","m3"]}},"size":3}` {
+		t.Errorf("unexpected request %+v", request)
+	}
+	expected := map[string]string{"m1": "enabled", "m2": "disabled", "m3": "missing"}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected %v, got %v", expected, statuses)
+	}
+
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	statuses, err = resourceOpensearchSaMonitorStatuses([]string{"m1"}, conf)
+	if err != nil || !reflect.DeepEqual(statuses, map[string]string{"m1": "missing"}) {
+		t.Errorf("expected the monitor to be missing without an alerting index, got %v (%v)", statuses, err)
+	}
+
+	for _, c := range []struct {
+		statuses map[string]string
+		enabled  bool
+		healthy  bool
+	}{
+		{map[string]string{"m1": "enabled", "m2": "enabled"}, true, true},
+		{map[string]string{"m1": "enabled", "m2": "missing"}, true, false},
+		{map[string]string{"m1": "disabled"}, true, false},
+		{map[string]string{"m1": "disabled"}, false, true},
+		{map[string]string{}, true, true},
+	} {
+		if healthy := saMonitorsHealthy(c.statuses, c.enabled); healthy != c.healthy {
+			t.Errorf("expected %v of a detector enabled=%t to be healthy=%t", c.statuses, c.enabled, c.healthy)
+		}
+	}
+}
+
+func TestSaDetectorLastRunError(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusOK, Body: `{"alerts":[{"id":"a1","monitor_id":"m1","state":"ERROR","error_message":"older","start_time":1718800000000}],"totalAlerts":1}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"alerts":[],"totalAlerts":0}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"alerts":[{"id":"a3","monitor_id":"m3","state":"ERROR","error_message":"index not found","start_time":1718803600000}],"totalAlerts":1}`},
+	)
+	lastError, err := resourceOpensearchSaMonitorsLastError([]string{"m1", "m2", "m3"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request := cluster.lastRequest(t); request.Path != "/_plugins/_alerting/monitors/alerts" || request.Query.Get("monitorId") != "m3" || request.Query.Get("alertState") != "ERROR" {
+		t.Errorf("unexpected request %s?%s", request.Path, request.Query.Encode())
+	}
+	if lastError.ErrorMessage != "index not found" || formatSaEpochMillis(lastError.StartTime) != "2024-06-19T13:26:40Z" {
+		t.Errorf("expected the most recent error, got %+v", lastError)
+	}
+
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	lastError, err = resourceOpensearchSaMonitorsLastError([]string{"m1"}, conf)
+	if err != nil || lastError.ErrorMessage != "" || formatSaEpochMillis(lastError.StartTime) != "" {
+		t.Errorf("expected no error without an alert index, got %+v (%v)", lastError, err)
+	}
+}
+
+func TestSaDetectorBodyOverrides(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":      `{"name": ${name}, "inputs": [{"detector_input": {"indices": ["windows"]}}], "a/b": {"~c": 1}}`,
+		"body_vars": map[string]interface{}{"name": `"shared"`},
+		"body_overrides": map[string]interface{}{
+			"/inputs/0/detector_input/indices/0": `"windows-prod"`,
+			"/name":                              `"prod"`,
+			"/a~1b/~0c":                          `{"d": [true]}`,
+		},
+	})
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"a/b":{"~c":{"d":[true]}},"inputs":[{"detector_input":{"indices":["windows-prod"]}}],"name":"prod"}`
+	if body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	for pointer, message := range map[string]string{
+		"name":                               "must start with /",
+		"/missing":                           `no member "missing"`,
+		"/inputs/1":                          `no element "1" in a list of 1`,
+		"/inputs/01":                         `no element "01"`,
+		"/inputs/0/detector_input/indices/x": `no element "x"`,
+		"/name/first":                        "is not in an object or list",
+	} {
+		d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+			"body":           `{"name": "test", "inputs": [{"detector_input": {"indices": ["windows"]}}]}`,
+			"body_overrides": map[string]interface{}{pointer: `"x"`},
+		})
+		if _, err := resourceOpensearchSaDetectorBody(d); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q for %s, got %v", message, pointer, err)
+		}
+	}
+}
+
+func TestSaDetectorUniqueName(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":3},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"test"}}},
+  {"_id":"d2","_source":{"detector":{"name":"test"}}},
+  {"_id":"d3","_source":{"detector":{"name":"other"}}}
+]}}`})
+	duplicates, err := resourceOpensearchSaDetectorDuplicateNames("d1", `{"name": "test"}`, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(duplicates, []string{"d2"}) {
+		t.Errorf("expected the other detector named test, got %v", duplicates)
+	}
+
+	d := saFakeDetectorData(t)
+	if diags := resourceOpensearchSaDetectorCheckUniqueName(d, conf); len(diags) != 0 {
+		t.Errorf("expected no check unless validate_unique_name is set, got %+v", diags)
+	}
+	if err := d.Set("validate_unique_name", true); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceOpensearchSaDetectorCheckUniqueName(d, conf); len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Errorf("expected a warning about the duplicate name, got %+v", diags)
+	}
+
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	duplicates, err = resourceOpensearchSaDetectorDuplicateNames("", `{"name": "test"}`, conf)
+	if err != nil || len(duplicates) != 0 {
+		t.Errorf("expected no duplicates without a detector index, got %v (%v)", duplicates, err)
+	}
+}
+
+func TestSaDetectorImportByName(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":3},"hits":[
+  {"_id":"d1","_source":{"detector":{"name":"test"}}},
+  {"_id":"d2","_source":{"detector":{"name":"other"}}},
+  {"_id":"d3","_source":{"detector":{"name":"other"}}}
+]}}`})
+
+	for _, tc := range []struct {
+		id       string
+		expected string
+		err      string
+	}{
+		{id: "name:test", expected: "d1"},
+		{id: "d2", expected: "d2"},
+		{id: "name:other", err: `several detectors are named "other": d2, d3`},
+		{id: "name:missing", err: `no detector named "missing" found`},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{})
+			d.SetId(tc.id)
+			imported, err := resourceOpensearchSaDetectorImport(context.Background(), d, conf)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id := imported[0].Id(); id != tc.expected {
+				t.Errorf("expected ID %s, got %s", tc.expected, id)
+			}
+		})
+	}
+}
+
+func TestSaDetectorAdoptExisting(t *testing.T) {
+	created := saFakeResponse{Status: http.StatusOK, Body: `{"_id":"d1","_version":2,"detector":` + saFakeDetector + `}`}
+	for _, tc := range []struct {
+		name     string
+		search   string
+		method   string
+		path     string
+		err      string
+		requests int
+	}{
+		{
+			name:     "retried create",
+			search:   `{"hits":{"total":{"value":2},"hits":[{"_id":"d1","_source":{"detector":{"name":"test"}}},{"_id":"d2","_source":{"detector":{"name":"other"}}}]}}`,
+			method:   "PUT",
+			path:     "/_plugins/_security_analytics/detectors/d1",
+			requests: 2,
+		},
+		{
+			name:     "first create",
+			search:   `{"hits":{"total":{"value":1},"hits":[{"_id":"d2","_source":{"detector":{"name":"other"}}}]}}`,
+			method:   "POST",
+			path:     "/_plugins/_security_analytics/detectors",
+			requests: 2,
+		},
+		{
+			name:     "ambiguous name",
+			search:   `{"hits":{"total":{"value":2},"hits":[{"_id":"d1","_source":{"detector":{"name":"test"}}},{"_id":"d2","_source":{"detector":{"name":"test"}}}]}}`,
+			err:      "cannot adopt a detector, several detectors have the name of this detector: d1, d2",
+			requests: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: tc.search}, created)
+			d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+				"body":                  `{"name": "test", "detector_type": "windows", "enabled": true}`,
+				"adopt_existing":        true,
+				"skip_read_after_write": true,
+			})
+
+			diags := resourceOpensearchSaDetectorCreate(context.Background(), d, conf)
+			if len(cluster.requests) != tc.requests {
+				t.Fatalf("expected %d requests, got %+v", tc.requests, cluster.requests)
+			}
+			if tc.err != "" {
+				if !diags.HasError() || diags[0].Summary != tc.err {
+					t.Errorf("expected error %q, got %+v", tc.err, diags)
+				}
+				if d.Id() != "" {
+					t.Errorf("expected no ID, got %s", d.Id())
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %+v", diags)
+			}
+			if request := cluster.lastRequest(t); request.Method != tc.method || request.Path != tc.path {
+				t.Errorf("expected %s %s, got %s %s", tc.method, tc.path, request.Method, request.Path)
+			}
+			if d.Id() != "d1" {
+				t.Errorf("expected ID d1, got %s", d.Id())
+			}
+		})
+	}
+}
+
+func TestSaDetectorBackendRoles(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":          `{"name": "test"}`,
+		"backend_roles": []interface{}{"analysts"},
+	})
+	body, err := resourceOpensearchSaDetectorBody(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"name":"test","rbac_roles":["analysts"]}`; body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	res := &SaDetectorResponse{Detector: map[string]interface{}{
+		"name": "test",
+		"user": map[string]interface{}{
+			"name":          "terraform",
+			"backend_roles": []interface{}{"analysts"},
+		},
+	}}
+	res.normalize()
+	if res.WorkflowID != "" {
+		t.Errorf("expected no workflow ID without workflow_ids, got %q", res.WorkflowID)
+	}
+	if len(res.BackendRoles) != 1 || res.BackendRoles[0] != "analysts" {
+		t.Errorf("expected backend roles to be captured from the user, got %v", res.BackendRoles)
+	}
+	if _, ok := res.Detector["user"]; ok {
+		t.Errorf("expected the user to be removed from the detector, got %v", res.Detector)
+	}
+}
+
+func TestSaDetectorUnmanagedCustomRules(t *testing.T) {
+	body := `{"inputs": [{"detector_input": {"custom_rules": [{"id": "managed"}, {"id": "external"}]}}]}`
+
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":                    body,
+		"managed_custom_rule_ids": []interface{}{"managed"},
+	})
+	unmanaged, err := saDetectorUnmanagedCustomRules(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unmanaged) != 1 || unmanaged[0] != "external" {
+		t.Errorf("expected only the external rule to be reported, got %v", unmanaged)
+	}
+
+	d = schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{"body": body})
+	if unmanaged, _ := saDetectorUnmanagedCustomRules(d); len(unmanaged) != 0 {
+		t.Errorf("expected no check without managed_custom_rule_ids, got %v", unmanaged)
+	}
+}
+
+func TestSaDetectorBodyConflicts(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":          `{"schedule": {"period": {"interval": 1, "unit": "MINUTES"}}, "rbac_roles": ["a"], "inputs": [{"detector_input": {"query_filter": {}}}]}`,
+		"schedule_cron": "0 * * * *",
+		"backend_roles": []interface{}{"analysts"},
+		"query_filter":  `{"term": {"a": "b"}}`,
+	})
+
+	_, err := resourceOpensearchSaDetectorBody(d)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, field := range []string{"schedule_cron (schedule)", "backend_roles (rbac_roles)", "query_filter (inputs.detector_input.query_filter)"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected the error to name %s, got %q", field, err)
+		}
+	}
+
+	d = schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body":          `{"name": "test"}`,
+		"schedule_cron": "0 * * * *",
+	})
+	if _, err := resourceOpensearchSaDetectorBody(d); err != nil {
+		t.Errorf("expected no conflict, got %v", err)
+	}
+}
+
+func TestSaDetectorWorkflowID(t *testing.T) {
+	res := &SaDetectorResponse{Detector: map[string]interface{}{
+		"name":         "test",
+		"workflow_ids": []interface{}{"w1"},
+	}}
+	res.normalize()
+	if res.WorkflowID != "w1" {
+		t.Errorf("expected workflow ID w1, got %q", res.WorkflowID)
+	}
+	if _, ok := res.Detector["workflow_ids"]; ok {
+		t.Errorf("expected workflow_ids to be removed from the detector, got %v", res.Detector)
+	}
+}
+
+func TestSaDetectorServerFields(t *testing.T) {
+	res := &SaDetectorResponse{Detector: map[string]interface{}{
+		"name":             "test",
+		"last_update_time": float64(1700000000000),
+		"type":             "detector",
+		"user":             map[string]interface{}{"name": "admin"},
+	}}
+	res.normalize()
+
+	fields, err := flattenSaServerFields(res.ServerFields, []string{"last_update_time", "user", "enabled_time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"last_update_time": "1700000000000",
+		"user":             `{"name":"admin"}`,
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %v, got %v", expected, fields)
+	}
+	if _, ok := res.Detector["last_update_time"]; ok {
+		t.Errorf("expected last_update_time to be removed from the detector, got %v", res.Detector)
+	}
+}
+
+func TestSaDeleteNotFound(t *testing.T) {
+	var deletes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			deletes++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"type":"resource_not_found_exception","reason":"not found"},"status":404}`))
+	}))
+	defer server.Close()
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	cases := []struct {
+		name     string
+		resource *schema.Resource
+		delete   func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics
+	}{
+		{"detector", resourceOpenSearchSaDetector(), resourceOpensearchSaDetectorDelete},
+		{"custom rule", resourceOpenSearchSaDetectorRule(), resourceOpensearchSaDetectorRuleDelete},
+	}
+	for _, c := range cases {
+		deletes = 0
+		d := c.resource.TestResourceData()
+		d.SetId("missing")
+		if diags := c.delete(context.TODO(), d, conf); diags.HasError() {
+			t.Errorf("%s: expected deleting a missing object to succeed, got %v", c.name, diags)
+		}
+		if deletes != 1 {
+			t.Errorf("%s: expected a single delete request, got %d", c.name, deletes)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSaDetectorSearchQuery(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     saDetectorSearchOptions
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: `{"query":{"ids":{"values":["abc"]}},"size":1,"version":true}`,
+		},
+		{
+			name:     "index",
+			opts:     saDetectorSearchOptions{Index: ".opensearch-sap-detectors-config"},
+			expected: `{"query":{"bool":{"filter":[{"ids":{"values":["abc"]}},{"term":{"_index":".opensearch-sap-detectors-config"}}]}},"size":1,"version":true}`,
+		},
+		{
+			name:     "index and routing",
+			opts:     saDetectorSearchOptions{Index: "detectors", Routing: "tenant-a"},
+			expected: `{"query":{"bool":{"filter":[{"ids":{"values":["abc"]}},{"term":{"_index":"detectors"}},{"term":{"_routing":"tenant-a"}}]}},"size":1,"version":true}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := json.Marshal(saDetectorSearchQuery("abc", c.opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(query) != c.expected {
+				t.Errorf("expected query %s, got %s", c.expected, query)
+			}
+		})
+	}
+}
+
+var testAccOpensearchSaDetector = `
+resource "opensearch_index" "windows" {
+  name               = "windows"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "test_detector" {
+  body = <<EOF
+{
+  "name": "test-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": [
+    {
+      "name": "test-trigger",
+      "severity": "1",
+      "types": ["windows"],
+      "ids": [],
+      "sev_levels": [],
+      "tags": [],
+      "actions": []
+    }
+  ]
+}
+EOF
+
+  depends_on = [opensearch_index.windows]
+}
+`
+
+var testAccOpensearchSaDetectorUpdate = `
+resource "opensearch_index" "windows" {
+  name               = "windows"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "test_detector" {
+  body = <<EOF
+{
+  "name": "test-detector",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 5,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": [
+    {
+      "name": "test-trigger",
+      "severity": "2",
+      "types": ["windows"],
+      "ids": [],
+      "sev_levels": [],
+      "tags": [],
+      "actions": []
+    }
+  ]
+}
+EOF
+
+  depends_on = [opensearch_index.windows]
+}
+`
+
+var testAccOpensearchSaDetectorBodyVars = `
+resource "opensearch_index" "windows" {
+  name               = "windows"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "test_detector" {
+  body_vars = {
+    interval = "%d"
+  }
+
+  body = <<EOF
+{
+  "name": "test-detector-vars",
+  "detector_type": "windows",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": $${interval},
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["windows"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.windows]
+}
+`
+
+func TestSaDetectorActionProblems(t *testing.T) {
+	var detector map[string]interface{}
+	err := json.Unmarshal([]byte(`{"triggers":[
+  {"name":"no-actions","actions":[]},
+  {"name":"t1","actions":[
+    {"name":"ok","destination_id":"c1","subject_template":{"source":"{{ctx.trigger.name}}"},"message_template":{"source":"{{#ctx.results}}{{{.}}}{{/ctx.results}}"}},
+    {"name":"no-destination","message_template":{"source":"Alert"}},
+    {"name":"placeholder","destination_id":"${slack}","message_template":{"source":"Alert"}},
+    {"destination_id":"c1","message_template":{"source":" "}},
+    {"name":"unclosed","destination_id":"c1","subject_template":{"source":"{{ctx.trigger.name"},"message_template":{"source":"Alert {{ctx.monitor.name}"}}
+  ]}
+]}`), &detector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`Action "no-destination" of trigger "t1" has no destination_id.`,
+		`Action "placeholder" of trigger "t1" has the destination_id ${slack}, which neither body_vars nor notification_channels define.`,
+		`Action "3" of trigger "t1" has no message_template source.`,
+		`Action "unclosed" of trigger "t1" has a message_template with unclosed mustache tags.`,
+		`Action "unclosed" of trigger "t1" has a subject_template with unclosed mustache tags.`,
+	}
+	if problems := saDetectorActionProblems(detector); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected %q, got %q", expected, problems)
+	}
+}
+
+func TestSaDetectorRuleCount(t *testing.T) {
+	var detector map[string]interface{}
+	err := json.Unmarshal([]byte(`{"inputs":[
+  {"detector_input":{"custom_rules":[{"id":"r1"},{"id":"r2"}],"pre_packaged_rules":[{"id":"p1"}]}},
+  {"detector_input":{"custom_rules":[{"id":"r1"}]}}
+]}`), &detector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := saDetectorRuleCount(detector, "custom_rules"); count != 2 {
+		t.Errorf("expected 2 distinct custom rules, got %d", count)
+	}
+	if count := saDetectorRuleCount(detector, "pre_packaged_rules"); count != 1 {
+		t.Errorf("expected 1 pre-packaged rule, got %d", count)
+	}
+	if count := saDetectorRuleCount(map[string]interface{}{}, "custom_rules"); count != 0 {
+		t.Errorf("expected no rules without inputs, got %d", count)
+	}
+}
+
+func TestSaDetectorCheckRolloverIndices(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{
+  "windows-000001": {"settings": {"index.plugins.index_state_management.rollover_alias": "windows"}},
+  "logs-000003": {"settings": {"index.plugins.index_state_management.rollover_alias": "logs"}},
+  "static": {"settings": {}}
+}`})
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"validate_rollover_indices": true,
+		"body":                      `{"name":"test","detector_type":"windows","inputs":[{"detector_input":{"indices":["windows-000001","logs","static","win-*"]}}]}`,
+	})
+
+	diags := resourceOpensearchSaDetectorCheckRolloverIndices(d, conf)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, "Reference the alias windows instead") {
+		t.Errorf("expected a single warning about windows-000001, got %v", diags)
+	}
+	request := cluster.lastRequest(t)
+	if request.Path != "/windows-000001,logs,static/_settings" || request.Query.Get("ignore_unavailable") != "true" {
+		t.Errorf("expected the settings of the named indices, got %+v", request)
+	}
+}
+
+func TestParseSaByteSize(t *testing.T) {
+	for value, expected := range map[string]int64{"100mb": 100 << 20, "512": 512, "1.5KB": 1536, " 2gb ": 2 << 30} {
+		if size, err := parseSaByteSize(value); err != nil || size != expected {
+			t.Errorf("expected %q to be %d bytes, got %d, %v", value, expected, size, err)
+		}
+	}
+	for _, value := range []string{"", "mb", "10zb", "-1kb"} {
+		if _, err := parseSaByteSize(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestSaDetectorBodyTooLarge(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusRequestEntityTooLarge, Body: ``})
+	_, err := resourceOpensearchPostSaDetector(saFakeDetectorData(t), conf)
+	if err == nil || !strings.Contains(err.Error(), "more than the http.max_content_length the cluster accepts") {
+		t.Errorf("expected an error about the size of the body, got %v", err)
+	}
+	if len(cluster.requests) != 1 {
+		t.Errorf("expected a small body to be sent without looking up the limit, got %+v", cluster.requests)
+	}
+
+	cluster, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"persistent":{},"transient":{},"defaults":{"http.max_content_length":"1mb"}}`})
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body": `{"name": "test", "detector_type": "windows", "description": "` + strings.Repeat("a", saDetectorSizeCheckThreshold) + `"}`,
+	})
+	d.SetId("d1")
+	_, err = resourceOpensearchPutSaDetector(d, conf)
+	if err == nil || !strings.Contains(err.Error(), "of 1048576 bytes the cluster accepts") {
+		t.Errorf("expected an error naming the limit, got %v", err)
+	}
+	if len(cluster.requests) != 1 || cluster.lastRequest(t).Path != "/_cluster/settings" {
+		t.Errorf("expected the body not to be sent, got %d requests", len(cluster.requests))
+	}
+}
+
+func TestSaDetectorReadOutOfBandDisable(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":3,"_source":{"detector":{"name":"test","detector_type":"windows","enabled":false,"enabled_time":null,"last_update_time":1700000000000}}}]}}`})
+	conf.keepServerFields = []string{"enabled_time"}
+	d := saFakeDetectorData(t)
+	configured := d.Get("body").(string)
+
+	if diags := resourceOpensearchSaDetectorRead(context.Background(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+	for _, key := range []string{"body", "normalized_body"} {
+		if value := d.Get(key).(string); !strings.Contains(value, `"enabled":false`) {
+			t.Errorf("expected %s to disable the detector, got %s", key, value)
+		}
+	}
+	if diffSuppressSaDetector("body", d.Get("body").(string), configured, d) {
+		t.Errorf("expected the detector disabled out-of-band to differ from %s", configured)
+	}
+	if fields := d.Get("server_fields").(map[string]interface{}); fields["enabled_time"] != "null" {
+		t.Errorf("expected the enabled_time of the disabled detector in server_fields, got %v", fields)
+	}
+}
+
+func TestSaDetectorEachNotFound(t *testing.T) {
+	_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	err := resourceOpensearchSaDetectorEach(conf, func(*SaDetectorResponse) error {
+		t.Error("expected no detector before any is created")
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected a cluster without detectors index to have no detectors, got %v", err)
+	}
+
+	// errors of the callback are never mistaken for a missing index
+	_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_source":{"detector":{"name":"test"}}}]}}`})
+	err = resourceOpensearchSaDetectorEach(conf, func(*SaDetectorResponse) error {
+		return searchNotFoundError("r1")
+	})
+	if !IsSearchNotFound(err) {
+		t.Errorf("expected the error of the callback, got %v", err)
+	}
+}
+
+func TestSaDetectorCheckRuleCategories(t *testing.T) {
+	body := `{"name":"test","detector_type":"windows","enabled":true,"inputs":[{"detector_input":{"custom_rules":[{"id":"r1"}]}}]}`
+	rule := func(category string) saFakeResponse {
+		return saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":1,"_source":{"rule":{"category":"` + category + `","rule":"title: Test\n"}}}]}}`}
+	}
+	for _, tc := range []struct {
+		name     string
+		category string
+		strict   bool
+		severity diag.Severity
+		problems int
+	}{
+		{name: "mismatched category", category: "cloudtrail", severity: diag.Warning, problems: 1},
+		{name: "strict mismatched category", category: "cloudtrail", strict: true, severity: diag.Error, problems: 1},
+		{name: "case-insensitive match", category: "Windows"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, rule(tc.category))
+			d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+				"body":                     body,
+				"validate_rule_categories": true,
+				"strict_rule_categories":   tc.strict,
+			})
+
+			diags := resourceOpensearchSaDetectorCheckRuleCategories(d, conf)
+			if len(diags) != tc.problems {
+				t.Fatalf("expected %d diagnostics, got %+v", tc.problems, diags)
+			}
+			if tc.problems > 0 && (diags[0].Severity != tc.severity || !strings.Contains(diags[0].Detail, `category "cloudtrail"`)) {
+				t.Errorf("expected a diagnostic of severity %v about category cloudtrail, got %+v", tc.severity, diags[0])
+			}
+			if len(cluster.requests) != 1 || cluster.lastRequest(t).Path != "/_plugins/_security_analytics/rules/_search" {
+				t.Errorf("expected the custom rule to be looked up, got %+v", cluster.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/olivere/elastic/uritemplates"
+)
+
+// the number of detectors updated at the same time, further bounded by
+// sa_max_concurrent_writes
+const saDetectorPauseConcurrency = 4
+
+func resourceOpenSearchSaDetectorsPause() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Pauses security analytics detectors, for example during cluster maintenance. Creating the resource disables every enabled detector of the cluster, or those listed in `detector_ids`, and records which ones it disabled. Destroying it enables those detectors again. The security analytics plugin has no dedicated enable or disable endpoint, so each detector is updated with its current document and the `enabled` flag changed. Detectors managed by `opensearch_sa_detector` show a diff while paused.",
+		CreateContext: resourceOpensearchSaDetectorsPauseCreate,
+		ReadContext:   resourceOpensearchSaDetectorsPauseRead,
+		DeleteContext: resourceOpensearchSaDetectorsPauseDelete,
+		Schema: map[string]*schema.Schema{
+			"detector_ids": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the detectors to pause. All detectors of the cluster are paused when omitted.",
+			},
+			"paused_detector_ids": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the detectors that were enabled and have been disabled by this resource. They are enabled again when it is destroyed.",
+			},
+		},
+	}
+}
+
+func resourceOpensearchSaDetectorsPauseCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	selected := d.Get("detector_ids").(*schema.Set)
+
+	detectors := make([]*SaDetectorResponse, 0)
+	err := resourceOpensearchSaDetectorEach(m, func(detector *SaDetectorResponse) error {
+		if enabled, _ := detector.Detector["enabled"].(bool); !enabled {
+			return nil
+		}
+		if selected.Len() > 0 && !selected.Contains(detector.ID) {
+			return nil
+		}
+		detectors = append(detectors, detector)
+		return nil
+	})
+	if err != nil {
+		return diag.Errorf("error listing detectors: %+v", err)
+	}
+
+	var mu sync.Mutex
+	paused := make([]string, 0, len(detectors))
+	err = forEachConcurrently(len(detectors), saDetectorPauseConcurrency, func(i int) error {
+		if err := resourceOpensearchSaDetectorSetEnabled(detectors[i], false, m); err != nil {
+			return fmt.Errorf("error disabling detector %s: %+v", detectors[i].ID, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		paused = append(paused, detectors[i].ID)
+		return nil
+	})
+	sort.Strings(paused)
+	log.Printf("[INFO] Paused security analytics detectors %v", paused)
+
+	// even on failure, the paused detectors are recorded so that a destroy
+	// enables them again
+	d.SetId(hashSum(strings.Join(paused, ",")))
+	if setErr := d.Set("paused_detector_ids", paused); setErr != nil {
+		return diag.FromErr(setErr)
+	}
+	return diag.FromErr(err)
+}
+
+func resourceOpensearchSaDetectorsPauseRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return nil
+}
+
+func resourceOpensearchSaDetectorsPauseDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	paused := expandStringList(d.Get("paused_detector_ids").(*schema.Set).List())
+
+	var mu sync.Mutex
+	var diags diag.Diagnostics
+	err := forEachConcurrently(len(paused), saDetectorPauseConcurrency, func(i int) error {
+		detector, err := resourceOpensearchSaDetectorSearchWithOptions(paused[i], saDetectorSearchOptions{}, m)
+		if err != nil {
+			if IsSearchNotFound(err) {
+				mu.Lock()
+				defer mu.Unlock()
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Paused detector no longer exists",
+					Detail:   fmt.Sprintf("Detector %s was deleted while paused and is not enabled again.", paused[i]),
+				})
+				return nil
+			}
+			return fmt.Errorf("error reading detector %s: %+v", paused[i], err)
+		}
+
+		if err := resourceOpensearchSaDetectorSetEnabled(detector, true, m); err != nil {
+			return fmt.Errorf("error enabling detector %s: %+v", paused[i], err)
+		}
+		return nil
+	})
+
+	return append(diags, diag.FromErr(err)...)
+}
+
+// resourceOpensearchSaDetectorSetEnabled updates a detector read from the
+// cluster with its enabled flag set. The server-managed fields stripped when
+// the detector was read are sent back where the update would otherwise
+// reset them.
+func resourceOpensearchSaDetectorSetEnabled(detector *SaDetectorResponse, enabled bool, m interface{}) error {
+	defer m.(*ProviderConf).acquireSaWrite()()
+
+	doc := make(map[string]interface{}, len(detector.Detector)+3)
+	for key, value := range detector.Detector {
+		doc[key] = value
+	}
+	doc["enabled"] = enabled
+	if detector.ThreatIntelEnabled {
+		doc["threat_intel_enabled"] = true
+	}
+	if len(detector.BackendRoles) > 0 {
+		doc["rbac_roles"] = detector.BackendRoles
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error marshalling detector body: %+v", err)
+	}
+
+	path, err := uritemplates.Expand(joinURLPath(saAPIPath, "detectors/{id}"), map[string]string{
+		"id": detector.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error building URL path for detector: %+v", err)
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return err
+	}
+
+	_, err = performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationUpdate, saRequestOptions("PUT", path, nil, string(body)))
+	return err
+}
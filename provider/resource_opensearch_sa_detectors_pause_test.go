@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOpensearchSaDetectorsPause(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaDetectorsPause,
+				// the paused detector differs from its configured body
+				ExpectNonEmptyPlan: true,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_sa_detectors_pause.test", "paused_detector_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttrPair("opensearch_sa_detectors_pause.test", "paused_detector_ids.*", "opensearch_sa_detector.test", "id"),
+					testCheckOpensearchSaDetectorEnabled("opensearch_sa_detector.test", false),
+				),
+			},
+			{
+				// removing the pause enables the detector again
+				Config: testAccOpensearchSaDetectorsPauseDetector,
+				Check:  testCheckOpensearchSaDetectorEnabled("opensearch_sa_detector.test", true),
+			},
+		},
+	})
+}
+
+func testCheckOpensearchSaDetectorEnabled(name string, expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("Not found: %s", name)
+		}
+
+		res, err := resourceOpensearchSaDetectorSearchWithOptions(rs.Primary.ID, saDetectorSearchOptions{}, testAccOpendistroProvider.Meta())
+		if err != nil {
+			return err
+		}
+		if enabled, _ := res.Detector["enabled"].(bool); enabled != expected {
+			return fmt.Errorf("expected detector %s to have enabled %t, got %v", rs.Primary.ID, expected, res.Detector["enabled"])
+		}
+		return nil
+	}
+}
+
+var testAccOpensearchSaDetectorsPauseDetector = `
+resource "opensearch_index" "cloudtrail" {
+  name               = "cloudtrail"
+  number_of_shards   = 1
+  number_of_replicas = 0
+}
+
+resource "opensearch_sa_detector" "test" {
+  body = <<EOF
+{
+  "name": "test-pause-detector",
+  "detector_type": "cloudtrail",
+  "enabled": true,
+  "schedule": {
+    "period": {
+      "interval": 1,
+      "unit": "MINUTES"
+    }
+  },
+  "inputs": [
+    {
+      "detector_input": {
+        "description": "",
+        "indices": ["cloudtrail"],
+        "custom_rules": [],
+        "pre_packaged_rules": []
+      }
+    }
+  ],
+  "triggers": []
+}
+EOF
+
+  depends_on = [opensearch_index.cloudtrail]
+}
+`
+
+var testAccOpensearchSaDetectorsPause = testAccOpensearchSaDetectorsPauseDetector + `
+resource "opensearch_sa_detectors_pause" "test" {
+  detector_ids = [opensearch_sa_detector.test.id]
+}
+`
+
+func TestSaDetectorsPauseNoDetectors(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`})
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaDetectorsPause().Schema, map[string]interface{}{})
+	if diags := resourceOpensearchSaDetectorsPauseCreate(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %+v", diags)
+	}
+	if d.Id() == "" {
+		t.Error("expected the pause to be created")
+	}
+	if paused := d.Get("paused_detector_ids").(*schema.Set); paused.Len() != 0 {
+		t.Errorf("expected no paused detectors, got %v", paused.List())
+	}
+	if len(cluster.requests) != 1 {
+		t.Errorf("expected only the detector search, got %+v", cluster.requests)
+	}
+}
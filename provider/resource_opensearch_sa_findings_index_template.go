@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+// the names the security analytics plugin gives to the findings indices of a
+// log type, and to the alias it writes findings through
+const (
+	saFindingsIndexPrefix = ".opensearch-sap-"
+	saFindingsIndexSuffix = "-findings"
+)
+
+func resourceOpenSearchSaFindingsIndexTemplate() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Provides a composable index template for the indices security analytics detectors of a log type write their findings to. The plugin creates these indices, along with their write alias, when a detector of the log type is created. On clusters where the indices cannot be created with the default settings, for example because of the number of replicas allowed, this resource provides the settings applied to them. Detectors should depend on it so that the template exists before they are created.",
+		CreateContext: resourceOpensearchSaFindingsIndexTemplateCreate,
+		ReadContext:   resourceOpensearchSaFindingsIndexTemplateRead,
+		UpdateContext: resourceOpensearchSaFindingsIndexTemplateUpdate,
+		DeleteContext: resourceOpensearchSaFindingsIndexTemplateDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceOpensearchSaFindingsIndexTemplateImport,
+		},
+		Schema: map[string]*schema.Schema{
+			"log_type": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The log type (`detector_type`) of the detectors writing to the findings indices.",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the index template. Defaults to `opensearch-sap-<log_type>-findings`.",
+			},
+			"priority": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     100,
+				Description: "The priority of the index template over other templates matching the findings indices.",
+			},
+			"settings": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "{}",
+				DiffSuppressFunc: diffSuppressSaFindingsIndexSettings,
+				StateFunc: func(v interface{}) string {
+					json, _ := structure.NormalizeJsonString(v)
+					return json
+				},
+				ValidateFunc: validateJSONObject,
+				Description:  "The index settings, as JSON, applied to the findings indices.",
+			},
+			"index_pattern": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The pattern of the findings indices matched by the template.",
+			},
+			"findings_alias": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The alias the detectors of the log type write their findings through.",
+			},
+		},
+	}
+}
+
+func saFindingsIndexAlias(logType string) string {
+	return saFindingsIndexPrefix + logType + saFindingsIndexSuffix
+}
+
+func resourceOpensearchSaFindingsIndexTemplateCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	name := d.Get("name").(string)
+	if name == "" {
+		name = strings.TrimPrefix(saFindingsIndexAlias(d.Get("log_type").(string)), ".")
+	}
+
+	if err := resourceOpensearchPutSaFindingsIndexTemplate(name, d, m, true); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(name)
+
+	return resourceOpensearchSaFindingsIndexTemplateRead(ctx, d, m)
+}
+
+func resourceOpensearchSaFindingsIndexTemplateRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	result, err := elastic7GetIndexTemplate(osClient, d.Id())
+	if err != nil {
+		if elastic7.IsNotFound(err) {
+			log.Printf("[WARN] Security analytics findings index template (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+
+	var template struct {
+		IndexPatterns []string `json:"index_patterns"`
+		Priority      int      `json:"priority"`
+		Template      struct {
+			Settings map[string]interface{} `json:"settings"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal([]byte(result), &template); err != nil {
+		return diag.Errorf("error unmarshalling index template: %+v", err)
+	}
+
+	settings := template.Template.Settings
+	if settings == nil {
+		settings = map[string]interface{}{}
+	}
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ds := &resourceDataSetter{d: d}
+	ds.set("name", d.Id())
+	ds.set("priority", template.Priority)
+	ds.set("settings", string(settingsJSON))
+	if len(template.IndexPatterns) > 0 {
+		ds.set("index_pattern", template.IndexPatterns[0])
+	}
+	ds.set("findings_alias", saFindingsIndexAlias(d.Get("log_type").(string)))
+	return diag.FromErr(ds.err)
+}
+
+func resourceOpensearchSaFindingsIndexTemplateUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := resourceOpensearchPutSaFindingsIndexTemplate(d.Id(), d, m, false); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOpensearchSaFindingsIndexTemplateRead(ctx, d, m)
+}
+
+func resourceOpensearchSaFindingsIndexTemplateDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := elastic7DeleteIndexTemplate(osClient, d.Id()); err != nil && !elastic7.IsNotFound(err) {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// resourceOpensearchSaFindingsIndexTemplateImport recovers the log type from
+// the index pattern of the imported template.
+func resourceOpensearchSaFindingsIndexTemplateImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := elastic7GetIndexTemplate(osClient, d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	var template struct {
+		IndexPatterns []string `json:"index_patterns"`
+	}
+	if err := json.Unmarshal([]byte(result), &template); err != nil {
+		return nil, fmt.Errorf("error unmarshalling index template: %+v", err)
+	}
+
+	for _, pattern := range template.IndexPatterns {
+		logType := strings.TrimSuffix(strings.TrimPrefix(pattern, saFindingsIndexPrefix), saFindingsIndexSuffix+"*")
+		if logType != pattern && saFindingsIndexAlias(logType)+"*" == pattern {
+			return []*schema.ResourceData{d}, d.Set("log_type", logType)
+		}
+	}
+	return nil, fmt.Errorf("index template %s does not match the findings indices of a log type", d.Id())
+}
+
+func resourceOpensearchPutSaFindingsIndexTemplate(name string, d *schema.ResourceData, m interface{}, create bool) error {
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(d.Get("settings").(string)), &settings); err != nil {
+		return fmt.Errorf("error unmarshalling settings: %+v", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"index_patterns": []string{saFindingsIndexAlias(d.Get("log_type").(string)) + "*"},
+		"priority":       d.Get("priority").(int),
+		"template": map[string]interface{}{
+			"settings": settings,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return err
+	}
+
+	return elastic7PutIndexTemplate(osClient, name, string(body), create)
+}
+
+// diffSuppressSaFindingsIndexSettings compares index settings the way the
+// cluster stores them, flattened and prefixed with `index.`.
+func diffSuppressSaFindingsIndexSettings(k, old, new string, d *schema.ResourceData) bool {
+	var oo, no map[string]interface{}
+	if err := json.Unmarshal([]byte(old), &oo); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(new), &no); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(normalizedIndexSettings(oo), normalizedIndexSettings(no))
+}
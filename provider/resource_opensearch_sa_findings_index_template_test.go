@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccOpensearchSaFindingsIndexTemplate(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccOpendistroProviders,
+		CheckDestroy: testCheckOpensearchSaFindingsIndexTemplateDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaFindingsIndexTemplate,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_sa_findings_index_template.test", "id", "opensearch-sap-cloudtrail-findings"),
+					resource.TestCheckResourceAttr("opensearch_sa_findings_index_template.test", "index_pattern", ".opensearch-sap-cloudtrail-findings*"),
+					resource.TestCheckResourceAttr("opensearch_sa_findings_index_template.test", "findings_alias", ".opensearch-sap-cloudtrail-findings"),
+				),
+			},
+			{
+				ResourceName:      "opensearch_sa_findings_index_template.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testCheckOpensearchSaFindingsIndexTemplateDestroy(s *terraform.State) error {
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "opensearch_sa_findings_index_template" {
+			continue
+		}
+
+		osClient, err := getClient(testAccOpendistroProvider.Meta().(*ProviderConf))
+		if err != nil {
+			return err
+		}
+
+		if _, err := osClient.IndexGetIndexTemplate(rs.Primary.ID).Do(context.TODO()); err == nil {
+			return fmt.Errorf("Index template %q still exists", rs.Primary.ID)
+		}
+	}
+
+	return nil
+}
+
+func TestDiffSuppressSaFindingsIndexSettings(t *testing.T) {
+	if !diffSuppressSaFindingsIndexSettings("settings", `{"index":{"number_of_replicas":"0"}}`, `{"number_of_replicas":0}`, nil) {
+		t.Error("expected flattened and nested settings to be equal")
+	}
+	if diffSuppressSaFindingsIndexSettings("settings", `{"index":{"number_of_replicas":"0"}}`, `{"number_of_replicas":1}`, nil) {
+		t.Error("expected a changed setting to be a diff")
+	}
+}
+
+var testAccOpensearchSaFindingsIndexTemplate = `
+resource "opensearch_sa_findings_index_template" "test" {
+  log_type = "cloudtrail"
+  settings = jsonencode({
+    number_of_replicas = 0
+  })
+}
+`
@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+// the cluster settings controlling the findings history indices of the
+// security analytics plugin, by attribute, and their defaults
+var saFindingsRetentionSettings = []struct {
+	attribute    string
+	setting      string
+	defaultValue interface{}
+}{
+	{"history_enabled", "plugins.security_analytics.finding_history_enabled", true},
+	{"max_docs", "plugins.security_analytics.finding_history_max_docs", 1000},
+	{"max_age", "plugins.security_analytics.finding_history_max_age", "1d"},
+	{"rollover_period", "plugins.security_analytics.finding_history_rollover_period", "12h"},
+	{"retention_period", "plugins.security_analytics.finding_history_retention_period", "60d"},
+}
+
+var saTimeValueRegexp = regexp.MustCompile(`^[0-9]+(d|h|m|s|ms|micros|nanos)$`)
+
+func resourceOpenSearchSaFindingsRetention() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Manages how the security analytics plugin rolls over and retains the history indices of findings. These are cluster settings shared by every detector, so the resource should be declared once per cluster. Destroying it resets the settings to the defaults of the plugin.",
+		CreateContext: resourceOpensearchSaFindingsRetentionCreate,
+		ReadContext:   resourceOpensearchSaFindingsRetentionRead,
+		UpdateContext: resourceOpensearchSaFindingsRetentionUpdate,
+		DeleteContext: resourceOpensearchSaFindingsRetentionDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: saFindingsRetentionSchema(false),
+	}
+}
+
+// saFindingsRetentionSchema returns the attributes of the findings retention
+// settings, configurable unless computed is set.
+func saFindingsRetentionSchema(computed bool) map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"history_enabled": {
+			Type:        schema.TypeBool,
+			Description: "Whether findings are kept in history indices once their detector run is over.",
+		},
+		"max_docs": {
+			Type:         schema.TypeInt,
+			ValidateFunc: validation.IntAtLeast(1),
+			Description:  "The number of findings after which the history index is rolled over.",
+		},
+		"max_age": {
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringMatch(saTimeValueRegexp, "must be a time value such as 12h or 30d"),
+			Description:  "The age after which the history index is rolled over, such as `1d`.",
+		},
+		"rollover_period": {
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringMatch(saTimeValueRegexp, "must be a time value such as 12h or 30d"),
+			Description:  "How often the history index is checked for a rollover, such as `12h`.",
+		},
+		"retention_period": {
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringMatch(saTimeValueRegexp, "must be a time value such as 12h or 30d"),
+			Description:  "How long rolled over history indices are kept before they are deleted, such as `60d`.",
+		},
+	}
+	for _, setting := range saFindingsRetentionSettings {
+		attr := s[setting.attribute]
+		if computed {
+			attr.Computed = true
+			attr.ValidateFunc = nil
+		} else {
+			attr.Optional = true
+			attr.Default = setting.defaultValue
+			attr.Description += fmt.Sprintf(" Defaults to `%v`.", setting.defaultValue)
+		}
+	}
+	return s
+}
+
+func resourceOpensearchSaFindingsRetentionCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	settings := make(map[string]interface{}, len(saFindingsRetentionSettings))
+	for _, setting := range saFindingsRetentionSettings {
+		settings[setting.setting] = d.Get(setting.attribute)
+	}
+	if err := resourceOpensearchPutSaClusterSettings(settings, m); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId("sa-findings-retention")
+
+	return resourceOpensearchSaFindingsRetentionRead(ctx, d, m)
+}
+
+func resourceOpensearchSaFindingsRetentionRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return diag.FromErr(resourceOpensearchSaFindingsRetentionSetState(d, m))
+}
+
+func resourceOpensearchSaFindingsRetentionUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceOpensearchSaFindingsRetentionCreate(ctx, d, m)
+}
+
+func resourceOpensearchSaFindingsRetentionDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	settings := make(map[string]interface{}, len(saFindingsRetentionSettings))
+	for _, setting := range saFindingsRetentionSettings {
+		settings[setting.setting] = nil
+	}
+	return diag.FromErr(resourceOpensearchPutSaClusterSettings(settings, m))
+}
+
+// resourceOpensearchSaFindingsRetentionSetState sets the attributes from the
+// values in effect on the cluster.
+func resourceOpensearchSaFindingsRetentionSetState(d *schema.ResourceData, m interface{}) error {
+	clusterSettings, err := resourceOpensearchGetSaClusterSettings(m)
+	if err != nil {
+		return err
+	}
+
+	ds := &resourceDataSetter{d: d}
+	for _, setting := range saFindingsRetentionSettings {
+		value, ok := clusterSettings.effective(setting.setting)
+		if !ok {
+			continue
+		}
+
+		parsed, err := parseSaFindingsRetentionSetting(value, setting.defaultValue)
+		if err != nil {
+			return fmt.Errorf("error parsing cluster setting %s: %+v", setting.setting, err)
+		}
+		ds.set(setting.attribute, parsed)
+	}
+	return ds.err
+}
+
+// parseSaFindingsRetentionSetting converts a flat setting value, which the
+// cluster returns as a string, to the type of its default.
+func parseSaFindingsRetentionSetting(value interface{}, defaultValue interface{}) (interface{}, error) {
+	str := fmt.Sprintf("%v", value)
+	switch defaultValue.(type) {
+	case bool:
+		return strconv.ParseBool(str)
+	case int:
+		return strconv.Atoi(str)
+	default:
+		return str, nil
+	}
+}
+
+// saClusterSettings holds the flat cluster settings, by scope.
+type saClusterSettings struct {
+	Persistent map[string]interface{} `json:"persistent"`
+	Transient  map[string]interface{} `json:"transient"`
+	Defaults   map[string]interface{} `json:"defaults"`
+}
+
+// effective returns the value of a setting in effect on the cluster:
+// transient settings override persistent ones, which override the defaults
+// of the plugin.
+func (s *saClusterSettings) effective(key string) (interface{}, bool) {
+	for _, scope := range []map[string]interface{}{s.Transient, s.Persistent, s.Defaults} {
+		if value, ok := scope[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func resourceOpensearchGetSaClusterSettings(m interface{}) (*saClusterSettings, error) {
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+	res, err := osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/_cluster/settings?flat_settings=true&include_defaults=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clusterSettings := new(saClusterSettings)
+	if err := json.Unmarshal(res.Body, clusterSettings); err != nil {
+		return nil, fmt.Errorf("error unmarshalling cluster settings: %+v", err)
+	}
+	return clusterSettings, nil
+}
+
+// resourceOpensearchPutSaClusterSettings sets persistent cluster settings,
+// resetting the ones set to nil to their defaults.
+func resourceOpensearchPutSaClusterSettings(settings map[string]interface{}, m interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"persistent": settings,
+	})
+	if err != nil {
+		return err
+	}
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return err
+	}
+	_, err = osClient.PerformRequest(context.TODO(), elastic7.PerformRequestOptions{
+		Method: "PUT",
+		Path:   "/_cluster/settings",
+		Body:   string(body),
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOpensearchSaFindingsRetention(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaFindingsRetention,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_sa_findings_retention.test", "retention_period", "90d"),
+					resource.TestCheckResourceAttr("opensearch_sa_findings_retention.test", "max_docs", "1000"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_findings_retention.test", "retention_period", "90d"),
+					resource.TestCheckResourceAttr("data.opensearch_sa_findings_retention.test", "history_enabled", "true"),
+				),
+			},
+			{
+				ResourceName:      "opensearch_sa_findings_retention.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestParseSaFindingsRetentionSetting(t *testing.T) {
+	for _, c := range []struct {
+		value        interface{}
+		defaultValue interface{}
+		expected     interface{}
+	}{
+		{"false", true, false},
+		{"5000", 1000, 5000},
+		{"30d", "60d", "30d"},
+	} {
+		parsed, err := parseSaFindingsRetentionSetting(c.value, c.defaultValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != c.expected {
+			t.Errorf("expected %v, got %v", c.expected, parsed)
+		}
+	}
+
+	if _, err := parseSaFindingsRetentionSetting("many", 1000); err == nil {
+		t.Error("expected an error for a non-numeric max_docs")
+	}
+}
+
+var testAccOpensearchSaFindingsRetention = `
+resource "opensearch_sa_findings_retention" "test" {
+  retention_period = "90d"
+}
+
+data "opensearch_sa_findings_retention" "test" {
+  depends_on = [opensearch_sa_findings_retention.test]
+}
+`
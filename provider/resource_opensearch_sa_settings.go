@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// the dynamic cluster settings of the security analytics plugin the
+// opensearch_sa_settings resource manages. The findings history settings are
+// managed by opensearch_sa_findings_retention.
+var saSettingsAllowed = []string{
+	"plugins.security_analytics.action_throttle_max_value",
+	"plugins.security_analytics.alert_history_enabled",
+	"plugins.security_analytics.alert_history_max_age",
+	"plugins.security_analytics.alert_history_max_docs",
+	"plugins.security_analytics.alert_history_retention_period",
+	"plugins.security_analytics.alert_history_rollover_period",
+	"plugins.security_analytics.correlation_history_enabled",
+	"plugins.security_analytics.correlation_history_max_age",
+	"plugins.security_analytics.correlation_history_max_docs",
+	"plugins.security_analytics.correlation_history_retention_period",
+	"plugins.security_analytics.correlation_history_rollover_period",
+	"plugins.security_analytics.correlation_time_window",
+	"plugins.security_analytics.enable_workflow_usage",
+	"plugins.security_analytics.filter_by_backend_roles",
+	"plugins.security_analytics.request_timeout",
+}
+
+func resourceOpenSearchSaSettings() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Manages cluster settings of the security analytics plugin, such as the retention of alert history indices. The settings are persistent cluster settings shared by every detector, so each of them should be managed by a single resource. Destroying the resource, or removing a setting from it, resets the setting to the default of the plugin. The plugin has no setting turning it on or off, and the findings history settings are managed by `opensearch_sa_findings_retention`.",
+		CreateContext: resourceOpensearchSaSettingsCreate,
+		ReadContext:   resourceOpensearchSaSettingsRead,
+		UpdateContext: resourceOpensearchSaSettingsUpdate,
+		DeleteContext: resourceOpensearchSaSettingsDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"settings": {
+				Type:         schema.TypeMap,
+				Required:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ValidateFunc: validateSaSettingsKeys,
+				Description:  "The settings by key, such as `plugins.security_analytics.alert_history_retention_period`, with their values as strings, such as `30d`, `1000` or `true`. The values in effect on the cluster are read back. On import, the settings of the plugin set on the cluster are imported.",
+			},
+		},
+	}
+}
+
+func resourceOpensearchSaSettingsCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := resourceOpensearchPutSaClusterSettings(d.Get("settings").(map[string]interface{}), m); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId("sa-settings")
+
+	return resourceOpensearchSaSettingsRead(ctx, d, m)
+}
+
+func resourceOpensearchSaSettingsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	clusterSettings, err := resourceOpensearchGetSaClusterSettings(m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	keys := make([]string, 0)
+	for key := range d.Get("settings").(map[string]interface{}) {
+		keys = append(keys, key)
+	}
+	// nothing is managed yet on import, so every setting of the plugin set on
+	// the cluster is taken over
+	if len(keys) == 0 {
+		for _, key := range saSettingsAllowed {
+			if _, ok := clusterSettings.Persistent[key]; ok {
+				keys = append(keys, key)
+			} else if _, ok := clusterSettings.Transient[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	settings := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := clusterSettings.effective(key); ok {
+			settings[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return diag.FromErr(d.Set("settings", settings))
+}
+
+func resourceOpensearchSaSettingsUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	old, new := d.GetChange("settings")
+	if err := resourceOpensearchPutSaClusterSettings(saSettingsChanges(old.(map[string]interface{}), new.(map[string]interface{})), m); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOpensearchSaSettingsRead(ctx, d, m)
+}
+
+func resourceOpensearchSaSettingsDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	settings := make(map[string]interface{})
+	for key := range d.Get("settings").(map[string]interface{}) {
+		settings[key] = nil
+	}
+	return diag.FromErr(resourceOpensearchPutSaClusterSettings(settings, m))
+}
+
+// saSettingsChanges returns the settings to put for an update, where the
+// removed settings are reset to their defaults.
+func saSettingsChanges(old, new map[string]interface{}) map[string]interface{} {
+	settings := make(map[string]interface{}, len(new))
+	for key, value := range new {
+		settings[key] = value
+	}
+	for key := range old {
+		if _, ok := settings[key]; !ok {
+			settings[key] = nil
+		}
+	}
+	return settings
+}
+
+func validateSaSettingsKeys(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(map[string]interface{})
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be a map", k))
+		return warnings, errors
+	}
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case containsString(saSettingsAllowed, key):
+		case strings.HasPrefix(key, "plugins.security_analytics.finding_history_"):
+			errors = append(errors, fmt.Errorf("%s cannot set %q, the findings history settings are managed by opensearch_sa_findings_retention", k, key))
+		default:
+			errors = append(errors, fmt.Errorf("%s cannot set %q, expected one of %s", k, key, strings.Join(saSettingsAllowed, ", ")))
+		}
+	}
+	return warnings, errors
+}
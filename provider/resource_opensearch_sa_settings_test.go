@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAccOpensearchSaSettings(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers: testAccOpendistroProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOpensearchSaSettings,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_sa_settings.test", "settings.plugins.security_analytics.alert_history_retention_period", "30d"),
+					resource.TestCheckResourceAttr("opensearch_sa_settings.test", "settings.plugins.security_analytics.alert_history_max_docs", "5000"),
+				),
+			},
+			{
+				ResourceName:      "opensearch_sa_settings.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestValidateSaSettingsKeys(t *testing.T) {
+	_, errors := validateSaSettingsKeys(map[string]interface{}{
+		"plugins.security_analytics.alert_history_enabled":    "true",
+		"plugins.security_analytics.finding_history_max_docs": "1000",
+		"cluster.routing.allocation.enable":                   "all",
+	}, "settings")
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errors)
+	}
+	if !strings.Contains(errors[0].Error(), "cluster.routing.allocation.enable") || !strings.Contains(errors[1].Error(), "opensearch_sa_findings_retention") {
+		t.Errorf("unexpected errors %v", errors)
+	}
+}
+
+func TestSaSettingsChanges(t *testing.T) {
+	changes := saSettingsChanges(map[string]interface{}{
+		"plugins.security_analytics.alert_history_max_docs": "5000",
+		"plugins.security_analytics.request_timeout":        "30s",
+	}, map[string]interface{}{
+		"plugins.security_analytics.alert_history_max_docs": "2000",
+	})
+	expected := map[string]interface{}{
+		"plugins.security_analytics.alert_history_max_docs": "2000",
+		"plugins.security_analytics.request_timeout":        nil,
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestSaSettingsRead(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{
+  "persistent": {"plugins.security_analytics.alert_history_max_docs": "5000", "cluster.routing.allocation.enable": "all"},
+  "transient": {},
+  "defaults": {"plugins.security_analytics.alert_history_max_docs": "1000", "plugins.security_analytics.request_timeout": "10s"}
+}`})
+
+	d := schema.TestResourceDataRaw(t, resourceOpenSearchSaSettings().Schema, map[string]interface{}{
+		"settings": map[string]interface{}{"plugins.security_analytics.request_timeout": "30s"},
+	})
+	if diags := resourceOpensearchSaSettingsRead(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if request := cluster.lastRequest(t); request.Path != "/_cluster/settings" || request.Query.Get("include_defaults") != "true" {
+		t.Errorf("unexpected request %s?%s", request.Path, request.Query.Encode())
+	}
+	expected := map[string]interface{}{"plugins.security_analytics.request_timeout": "10s"}
+	if settings := d.Get("settings").(map[string]interface{}); !reflect.DeepEqual(settings, expected) {
+		t.Errorf("expected the value in effect %v, got %v", expected, settings)
+	}
+
+	// on import, the settings of the plugin set on the cluster are read
+	d = schema.TestResourceDataRaw(t, resourceOpenSearchSaSettings().Schema, map[string]interface{}{})
+	if diags := resourceOpensearchSaSettingsRead(context.TODO(), d, conf); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	expected = map[string]interface{}{"plugins.security_analytics.alert_history_max_docs": "5000"}
+	if settings := d.Get("settings").(map[string]interface{}); !reflect.DeepEqual(settings, expected) {
+		t.Errorf("expected the persistent setting to be imported, got %v", settings)
+	}
+}
+
+var testAccOpensearchSaSettings = `
+resource "opensearch_sa_settings" "test" {
+  settings = {
+    "plugins.security_analytics.alert_history_retention_period" = "30d"
+    "plugins.security_analytics.alert_history_max_docs"         = "5000"
+  }
+}
+`
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+// saRetryStatusCodes are the response codes passed to the retrier of
+// security analytics requests, see shouldRetrySaRequest.
+var saRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// the bounds of the wait between two attempts of a security analytics request
+const (
+	saRetryInitialWait = 100 * time.Millisecond
+	saRetryMaxWait     = 30 * time.Second
+)
+
+// saRetrier retries security analytics requests with an exponential backoff
+// as long as shouldRetrySaRequest classifies the failure as transient.
+type saRetrier struct {
+	backoff    elastic7.Backoff
+	idempotent bool
+	maxRetries int
+}
+
+// newSaRetrier returns the retrier for security analytics requests, giving
+// up after maxRetries retries. Requests that are not idempotent, such as the
+// POST creating an object, are only retried when they certainly did not
+// reach the cluster.
+func newSaRetrier(idempotent bool, maxRetries int) *saRetrier {
+	return &saRetrier{
+		backoff:    elastic7.NewExponentialBackoff(saRetryInitialWait, saRetryMaxWait),
+		idempotent: idempotent,
+		maxRetries: maxRetries,
+	}
+}
+
+func (r *saRetrier) Retry(ctx context.Context, retry int, req *http.Request, resp *http.Response, err error) (time.Duration, bool, error) {
+	if retry > r.maxRetries || !shouldRetrySaRequest(resp, err, r.idempotent) {
+		return 0, false, nil
+	}
+
+	wait, ok := r.backoff.Next(retry)
+	if !ok {
+		wait = saRetryMaxWait
+	}
+
+	// waiting past the deadline of the request would only delay its failure
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false, nil
+	}
+	return wait, true, nil
+}
+
+// shouldRetrySaRequest decides whether a failed request is worth retrying.
+// Throttling and gateway errors are retried, as are network errors such as
+// connection resets or unexpected EOFs seen while nodes restart. Any other
+// client or server error is returned as is.
+func shouldRetrySaRequest(resp *http.Response, err error, idempotent bool) bool {
+	if err == nil {
+		if resp == nil {
+			return false
+		}
+		// a gateway error may come after the cluster processed the request,
+		// only a throttled request certainly was not
+		if !idempotent {
+			return resp.StatusCode == http.StatusTooManyRequests
+		}
+		for _, code := range saRetryStatusCodes {
+			if resp.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// the connection was never established, so the request was not sent
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	if !idempotent {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShouldRetrySaRequest(t *testing.T) {
+	cases := []struct {
+		name       string
+		resp       *http.Response
+		err        error
+		idempotent bool
+		expected   bool
+	}{
+		{name: "too many requests", resp: &http.Response{StatusCode: 429}, idempotent: true, expected: true},
+		{name: "service unavailable", resp: &http.Response{StatusCode: 503}, idempotent: true, expected: true},
+		{name: "too many requests on create", resp: &http.Response{StatusCode: 429}, expected: true},
+		{name: "bad gateway on create", resp: &http.Response{StatusCode: 502}, expected: false},
+		{name: "service unavailable on create", resp: &http.Response{StatusCode: 503}, expected: false},
+		{name: "gateway timeout on create", resp: &http.Response{StatusCode: 504}, expected: false},
+		{name: "bad request", resp: &http.Response{StatusCode: 400}, idempotent: true, expected: false},
+		{name: "not found", resp: &http.Response{StatusCode: 404}, idempotent: true, expected: false},
+		{name: "eof", err: fmt.Errorf("reading response: %w", io.EOF), idempotent: true, expected: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, idempotent: true, expected: true},
+		{name: "connection reset", err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, idempotent: true, expected: true},
+		{name: "connection reset on create", err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, expected: false},
+		{name: "connection refused on create", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: true},
+		{name: "canceled", err: context.Canceled, idempotent: true, expected: false},
+		{name: "other error", err: fmt.Errorf("x509: certificate signed by unknown authority"), idempotent: true, expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if retry := shouldRetrySaRequest(c.resp, c.err, c.idempotent); retry != c.expected {
+				t.Errorf("expected retry to be %t, got %t", c.expected, retry)
+			}
+		})
+	}
+}
+
+func TestSaRetrierMaxRetries(t *testing.T) {
+	retrier := newSaRetrier(true, 2)
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	for retry := 1; retry <= 2; retry++ {
+		if _, ok, _ := retrier.Retry(context.Background(), retry, nil, resp, nil); !ok {
+			t.Errorf("expected retry %d to be allowed", retry)
+		}
+	}
+	if _, ok, _ := retrier.Retry(context.Background(), 3, nil, resp, nil); ok {
+		t.Error("expected retries to stop after max_retries")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, ok, _ := newSaRetrier(true, 20).Retry(ctx, 10, nil, resp, nil); ok {
+		t.Error("expected no retry waiting past the deadline")
+	}
+}
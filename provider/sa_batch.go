@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// saDetectorReadBatchWindow is the time a detector read waits for the reads
+// running alongside it to join its batch.
+const saDetectorReadBatchWindow = 20 * time.Millisecond
+
+// saDetectorBatcher coalesces the detector reads running at the same time,
+// such as those of a refresh, into searches for several IDs at once.
+type saDetectorBatcher struct {
+	size   int
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *saDetectorBatch
+}
+
+// saDetectorBatch is a search for the detectors of ids. done is closed once
+// results or err are set.
+type saDetectorBatch struct {
+	ids     []string
+	timer   *time.Timer
+	once    sync.Once
+	done    chan struct{}
+	results map[string][]*SaDetectorResponse
+	err     error
+}
+
+func newSaDetectorBatcher(size int) *saDetectorBatcher {
+	return &saDetectorBatcher{size: size, window: saDetectorReadBatchWindow}
+}
+
+// get reads the detector of id as part of the pending batch, starting one if
+// there is none. The batch is sent once it holds size IDs, or once the batch
+// window has elapsed. A batch failing as a whole, or finding a detector more
+// than once, falls back to reading the detector on its own, which reports the
+// error for that detector.
+func (b *saDetectorBatcher) get(id string, m interface{}) (*SaDetectorResponse, error) {
+	b.mu.Lock()
+	batch := b.pending
+	if batch == nil {
+		batch = &saDetectorBatch{done: make(chan struct{})}
+		b.pending = batch
+		batch.timer = time.AfterFunc(b.window, func() { b.send(batch, m) })
+	}
+	if !containsString(batch.ids, id) {
+		batch.ids = append(batch.ids, id)
+	}
+	full := len(batch.ids) >= b.size
+	if full {
+		b.pending = nil
+	}
+	b.mu.Unlock()
+
+	if full && batch.timer.Stop() {
+		go b.send(batch, m)
+	}
+	<-batch.done
+
+	if batch.err != nil {
+		log.Printf("[WARN] Batched read of detectors failed, reading detector %s on its own: %+v", id, batch.err)
+		return resourceOpensearchSaDetectorSearch(id, m)
+	}
+	switch hits := batch.results[id]; len(hits) {
+	case 0:
+		return new(SaDetectorResponse), searchNotFoundError(id)
+	case 1:
+		return hits[0], nil
+	default:
+		return resourceOpensearchSaDetectorSearch(id, m)
+	}
+}
+
+// send searches for the detectors of batch, once.
+func (b *saDetectorBatcher) send(batch *saDetectorBatch, m interface{}) {
+	batch.once.Do(func() {
+		b.mu.Lock()
+		if b.pending == batch {
+			b.pending = nil
+		}
+		ids := batch.ids
+		b.mu.Unlock()
+
+		batch.results, batch.err = resourceOpensearchSaDetectorSearchIDs(ids, m)
+		close(batch.done)
+	})
+}
+
+// resourceOpensearchSaDetectorSearchIDs looks up the detectors of ids with a
+// single search, returning the detectors found by ID. The sa_routing of the
+// provider applies.
+func resourceOpensearchSaDetectorSearchIDs(ids []string, m interface{}) (map[string][]*SaDetectorResponse, error) {
+	path, params, err := saSearchPath(m.(*ProviderConf).saDetectorsIndex, joinURLPath(saAPIPath, "detectors/_search"), nil)
+	if err != nil {
+		return nil, err
+	}
+	query := map[string]interface{}{
+		// a second hit of an ID is enough to tell that it is not unique
+		"size":    2 * len(ids),
+		"version": true,
+		"query":   saIDsQuery(ids, "", m.(*ProviderConf).saRouting),
+	}
+	result, err := saSearch(path, params, query, "detector", m)
+	if err != nil {
+		return nil, err
+	}
+	// IDs matched several times may push others out of the page, which must
+	// not be mistaken for missing detectors
+	if result.Total > len(result.Hits) {
+		return nil, fmt.Errorf("the search for the detectors %s matched %d documents, more than the %d returned", strings.Join(ids, ", "), result.Total, len(result.Hits))
+	}
+
+	detectors := make(map[string][]*SaDetectorResponse, len(result.Hits))
+	for _, hit := range result.Hits {
+		response := &SaDetectorResponse{
+			ID:       hit.ID,
+			Version:  hit.Version,
+			Detector: hit.Source,
+		}
+		response.normalize()
+		detectors[hit.ID] = append(detectors[hit.ID], response)
+	}
+	return detectors, nil
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSaDetectorBatcher(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":2},"hits":[
+  {"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}},
+  {"_id":"d2","_version":1,"_source":{"detector":` + saFakeDetector + `}}
+]}}`})
+	batcher := newSaDetectorBatcher(10)
+	batcher.window = 50 * time.Millisecond
+
+	ids := []string{"d1", "d2", "d3"}
+	responses := make([]*SaDetectorResponse, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			responses[i], errs[i] = batcher.get(id, conf)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(cluster.requests) != 1 {
+		t.Fatalf("expected a single search, got %+v", cluster.requests)
+	}
+	for _, id := range ids {
+		if !strings.Contains(cluster.requests[0].Body, `"`+id+`"`) {
+			t.Errorf("expected the search to look up %s, got %s", id, cluster.requests[0].Body)
+		}
+	}
+	for i, version := range []int{3, 1} {
+		if errs[i] != nil || responses[i].ID != ids[i] || responses[i].Version != version {
+			t.Errorf("expected version %d of detector %s, got %+v, %v", version, ids[i], responses[i], errs[i])
+		}
+	}
+	if !IsSearchNotFound(errs[2]) {
+		t.Errorf("expected detector d3 not to be found, got %v", errs[2])
+	}
+}
+
+func TestSaDetectorBatcherFull(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}}]}}`})
+	batcher := newSaDetectorBatcher(1)
+	batcher.window = time.Hour
+
+	if _, err := batcher.get("d1", conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.requests) != 1 {
+		t.Errorf("expected a full batch to be sent right away, got %+v", cluster.requests)
+	}
+}
+
+func TestSaDetectorBatcherFallback(t *testing.T) {
+	cluster, conf := newSaFakeCluster(t,
+		saFakeResponse{Status: http.StatusBadRequest, Body: `{"error":{"type":"illegal_argument_exception","reason":"bad query"},"status":400}`},
+		saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}}]}}`},
+	)
+	batcher := newSaDetectorBatcher(1)
+
+	res, err := batcher.get("d1", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "d1" || res.Version != 3 {
+		t.Errorf("expected version 3 of detector d1, got %+v", res)
+	}
+	if len(cluster.requests) != 2 || cluster.lastRequest(t).Body != `{"query":{"ids":{"values":["d1"]}},"size":2,"version":true}` {
+		t.Errorf("expected the detector to be read on its own after the failed batch, got %+v", cluster.requests)
+	}
+}
+
+func TestSaDetectorBatcherTruncated(t *testing.T) {
+	duplicate := `{"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}}`
+	var mu sync.Mutex
+	var searches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		searches = append(searches, string(body))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		// d1 is held by three indices behind the alias, pushing d2 out of the page
+		case strings.Contains(string(body), `"d1","d2"`) || strings.Contains(string(body), `"d2","d1"`):
+			_, _ = w.Write([]byte(`{"hits":{"total":{"value":4},"hits":[` + duplicate + `,` + duplicate + `,` + duplicate + `]}}`))
+		case strings.Contains(string(body), `"d1"`):
+			_, _ = w.Write([]byte(`{"hits":{"total":{"value":3},"hits":[` + duplicate + `,` + duplicate + `]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"hits":{"total":{"value":1},"hits":[{"_id":"d2","_version":1,"_source":{"detector":` + saFakeDetector + `}}]}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+
+	batcher := newSaDetectorBatcher(2)
+	batcher.window = time.Hour
+	ids := []string{"d1", "d2"}
+	responses := make([]*SaDetectorResponse, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			responses[i], errs[i] = batcher.get(id, conf)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(searches) != 3 {
+		t.Errorf("expected every detector of the truncated batch to be read on its own, got %v", searches)
+	}
+	if errs[0] == nil || IsSearchNotFound(errs[0]) {
+		t.Errorf("expected detector d1 to be reported as matched several times, got %v", errs[0])
+	}
+	if errs[1] != nil || responses[1].ID != "d2" {
+		t.Errorf("expected detector d2 to be found, got %+v, %v", responses[1], errs[1])
+	}
+}
@@ -0,0 +1,382 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// saFakeRequest is a request received by a fake security analytics cluster.
+type saFakeRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   string
+}
+
+// saFakeResponse is a response sent by a fake security analytics cluster.
+type saFakeResponse struct {
+	Status int
+	Body   string
+}
+
+// saFakeCluster answers security analytics requests with the given responses
+// in turn, repeating the last one, and records the requests it receives.
+type saFakeCluster struct {
+	mu        sync.Mutex
+	responses []saFakeResponse
+	requests  []saFakeRequest
+}
+
+func newSaFakeCluster(t *testing.T, responses ...saFakeResponse) (*saFakeCluster, *ProviderConf) {
+	cluster := &saFakeCluster{responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		cluster.mu.Lock()
+		cluster.requests = append(cluster.requests, saFakeRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Body:   string(body),
+		})
+		response := cluster.responses[len(cluster.responses)-1]
+		if len(cluster.requests) <= len(cluster.responses) {
+			response = cluster.responses[len(cluster.requests)-1]
+		}
+		cluster.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.Status)
+		_, _ = w.Write([]byte(response.Body))
+	}))
+	t.Cleanup(server.Close)
+
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single retry keeps the backoff of throttled requests short
+	settings := make(map[saOperation]saRequestSettings, len(saOperations))
+	for _, op := range saOperations {
+		settings[op] = saRequestSettings{maxRetries: 1}
+	}
+	return cluster, &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0", saRequestSettings: settings}
+}
+
+func (c *saFakeCluster) lastRequest(t *testing.T) saFakeRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		t.Fatal("expected a request")
+	}
+	return c.requests[len(c.requests)-1]
+}
+
+const saFakeDetector = `{"name":"test","detector_type":"windows","enabled":true,"last_update_time":1718800000000,"monitor_id":["m1"]}`
+
+const saFakeRule = `{"category":"windows","rule":"title: Test\n"}`
+
+func saFakeDetectorData(t *testing.T) *schema.ResourceData {
+	d := schema.TestResourceDataRaw(t, saDetectorSchema, map[string]interface{}{
+		"body": `{"name": "test", "detector_type": "windows", "enabled": true}`,
+	})
+	d.SetId("d1")
+	return d
+}
+
+func saFakeRuleData(t *testing.T) *schema.ResourceData {
+	d := schema.TestResourceDataRaw(t, saDetectorRuleSchema, map[string]interface{}{
+		"category": "windows",
+		"body":     "title: Test\n",
+	})
+	d.SetId("r1")
+	return d
+}
+
+// saFakeOperations are the CRUD requests of detectors and custom rules, along
+// with the request each is expected to send and a response to answer it with.
+func saFakeOperations(t *testing.T) []struct {
+	name     string
+	call     func(conf *ProviderConf) (interface{}, error)
+	request  saFakeRequest
+	response string
+	expected interface{}
+} {
+	return []struct {
+		name     string
+		call     func(conf *ProviderConf) (interface{}, error)
+		request  saFakeRequest
+		response string
+		expected interface{}
+	}{
+		{
+			name: "post detector",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchPostSaDetector(saFakeDetectorData(t), conf)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"id": res.ID, "version": res.Version, "detector": res.Detector}, nil
+			},
+			request: saFakeRequest{
+				Method: "POST",
+				Path:   "/_plugins/_security_analytics/detectors",
+				Query:  url.Values{},
+				Body:   `{"detector_type":"windows","enabled":true,"name":"test"}`,
+			},
+			response: `{"_id":"d1","_version":1,"detector":` + saFakeDetector + `}`,
+			expected: map[string]interface{}{"id": "d1", "version": 1, "detector": map[string]interface{}{"name": "test", "detector_type": "windows", "enabled": true}},
+		},
+		{
+			name: "put detector",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchPutSaDetector(saFakeDetectorData(t), conf)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"id": res.ID, "version": res.Version, "detector": res.Detector}, nil
+			},
+			request: saFakeRequest{
+				Method: "PUT",
+				Path:   "/_plugins/_security_analytics/detectors/d1",
+				Query:  url.Values{},
+				Body:   `{"detector_type":"windows","enabled":true,"name":"test"}`,
+			},
+			response: `{"_id":"d1","_version":2,"detector":` + saFakeDetector + `}`,
+			expected: map[string]interface{}{"id": "d1", "version": 2, "detector": map[string]interface{}{"name": "test", "detector_type": "windows", "enabled": true}},
+		},
+		{
+			name: "search detector",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchSaDetectorSearch("d1", conf)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"id": res.ID, "version": res.Version, "detector": res.Detector}, nil
+			},
+			request: saFakeRequest{
+				Method: "POST",
+				Path:   "/_plugins/_security_analytics/detectors/_search",
+				Query:  url.Values{},
+				Body:   `{"query":{"ids":{"values":["d1"]}},"size":2,"version":true}`,
+			},
+			response: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}}]}}`,
+			expected: map[string]interface{}{"id": "d1", "version": 3, "detector": map[string]interface{}{"name": "test", "detector_type": "windows", "enabled": true}},
+		},
+		{
+			name: "post rule",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchPostSaDetectorRule(saFakeRuleData(t), conf)
+				if err != nil {
+					return nil, err
+				}
+				return *res, nil
+			},
+			request: saFakeRequest{
+				Method: "POST",
+				Path:   "/_plugins/_security_analytics/rules",
+				Query:  url.Values{"category": []string{"windows"}},
+				Body:   "title: Test\n",
+			},
+			response: `{"_id":"r1","_version":1,"rule":` + saFakeRule + `}`,
+			expected: SaDetectorRuleResponse{ID: "r1", Version: 1, Rule: map[string]interface{}{"category": "windows", "rule": "title: Test\n"}},
+		},
+		{
+			name: "put rule",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchPutSaDetectorRule(saFakeRuleData(t), conf)
+				if err != nil {
+					return nil, err
+				}
+				return *res, nil
+			},
+			request: saFakeRequest{
+				Method: "PUT",
+				Path:   "/_plugins/_security_analytics/rules/r1",
+				Query:  url.Values{"category": []string{"windows"}, "forced": []string{"true"}},
+				Body:   "title: Test\n",
+			},
+			response: `{"_id":"r1","_version":2,"rule":` + saFakeRule + `}`,
+			expected: SaDetectorRuleResponse{ID: "r1", Version: 2, Rule: map[string]interface{}{"category": "windows", "rule": "title: Test\n"}},
+		},
+		{
+			name: "get rule",
+			call: func(conf *ProviderConf) (interface{}, error) {
+				res, err := resourceOpensearchSaDetectorRuleGet("r1", conf)
+				if err != nil {
+					return nil, err
+				}
+				return *res, nil
+			},
+			request: saFakeRequest{
+				Method: "POST",
+				Path:   "/_plugins/_security_analytics/rules/_search",
+				Query:  url.Values{"pre_packaged": []string{"false"}},
+				Body:   `{"query":{"ids":{"values":["r1"]}},"size":2}`,
+			},
+			response: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":3,"_source":{"rule":` + saFakeRule + `}}]}}`,
+			expected: SaDetectorRuleResponse{ID: "r1", Version: 3, Rule: map[string]interface{}{"category": "windows", "rule": "title: Test\n"}},
+		},
+	}
+}
+
+func TestSaCRUDRequests(t *testing.T) {
+	for _, op := range saFakeOperations(t) {
+		t.Run(op.name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: op.response})
+
+			res, err := op.call(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request := cluster.lastRequest(t); !reflect.DeepEqual(request, op.request) {
+				t.Errorf("expected request %+v, got %+v", op.request, request)
+			}
+			if !reflect.DeepEqual(res, op.expected) {
+				t.Errorf("expected %#v, got %#v", op.expected, res)
+			}
+		})
+	}
+}
+
+func TestSaCRUDErrors(t *testing.T) {
+	for _, op := range saFakeOperations(t) {
+		t.Run(op.name, func(t *testing.T) {
+			_, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"_id": `})
+			if _, err := op.call(conf); err == nil || !strings.Contains(err.Error(), "error unmarshalling") {
+				t.Errorf("expected an unmarshalling error, got %v", err)
+			}
+
+			_, conf = newSaFakeCluster(t, saFakeResponse{Status: http.StatusNotFound, Body: `{"error":{"type":"resource_not_found_exception","reason":"not found"},"status":404}`})
+			var notFound *SaNotFoundError
+			if _, err := op.call(conf); !errors.As(err, &notFound) || !IsSearchNotFound(err) {
+				t.Errorf("expected a not found error, got %v", err)
+			}
+
+			throttled := saFakeResponse{Status: http.StatusTooManyRequests, Body: `{"error":{"type":"rejected_execution_exception","reason":"too many requests"},"status":429}`}
+			cluster, conf := newSaFakeCluster(t, throttled, saFakeResponse{Status: http.StatusOK, Body: op.response})
+			if _, err := op.call(conf); err != nil {
+				t.Errorf("expected a throttled request to be retried, got %v", err)
+			}
+			if len(cluster.requests) != 2 {
+				t.Errorf("expected 2 attempts, got %d", len(cluster.requests))
+			}
+
+			cluster, conf = newSaFakeCluster(t, throttled)
+			var saErr *SaError
+			if _, err := op.call(conf); !errors.As(err, &saErr) || saErr.Status != http.StatusTooManyRequests {
+				t.Errorf("expected a throttling error once retries are exhausted, got %v", err)
+			}
+			if len(cluster.requests) != 2 {
+				t.Errorf("expected 2 attempts, got %d", len(cluster.requests))
+			}
+		})
+	}
+}
+
+func TestSaCustomRuleCategoryEncoding(t *testing.T) {
+	category := "my app/logs"
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"category": category,
+		"body":     "title: Test\n",
+	})
+	if diags := resourceOpenSearchSaDetectorRule().Validate(config); diags.HasError() {
+		t.Fatalf("expected custom log type %q to be a valid category, got %+v", category, diags)
+	}
+	blank := terraform.NewResourceConfigRaw(map[string]interface{}{"category": " ", "body": "title: Test\n"})
+	if diags := resourceOpenSearchSaDetectorRule().Validate(blank); !diags.HasError() {
+		t.Error("expected a blank category to be rejected")
+	}
+	for name, call := range map[string]func(d *schema.ResourceData, conf *ProviderConf) error{
+		"post": func(d *schema.ResourceData, conf *ProviderConf) error {
+			_, err := resourceOpensearchPostSaDetectorRule(d, conf)
+			return err
+		},
+		"put": func(d *schema.ResourceData, conf *ProviderConf) error {
+			_, err := resourceOpensearchPutSaDetectorRule(d, conf)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, saFakeResponse{Status: http.StatusOK, Body: `{"_id":"r1","_version":1,"rule":` + saFakeRule + `}`})
+			d := saFakeRuleData(t)
+			if err := d.Set("category", category); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := call(d, conf); err != nil {
+				t.Fatal(err)
+			}
+			request := cluster.lastRequest(t)
+			if request.Path != "/_plugins/_security_analytics/rules" && request.Path != "/_plugins/_security_analytics/rules/r1" {
+				t.Errorf("expected the category to stay out of the path, got %s", request.Path)
+			}
+			if got := request.Query["category"]; len(got) != 1 || got[0] != category {
+				t.Errorf("expected category %q, got %v", category, request.Query)
+			}
+		})
+	}
+}
+
+func TestSaRouting(t *testing.T) {
+	detector := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"d1","_version":3,"_source":{"detector":` + saFakeDetector + `}}]}}`}
+	rule := saFakeResponse{Status: http.StatusOK, Body: `{"hits":{"total":{"value":1},"hits":[{"_id":"r1","_version":2,"_source":{"rule":` + saFakeRule + `}}]}}`}
+
+	cases := []struct {
+		name     string
+		response saFakeResponse
+		call     func(conf *ProviderConf) error
+		expected string
+	}{
+		{
+			name:     "detector",
+			response: detector,
+			call: func(conf *ProviderConf) error {
+				_, err := resourceOpensearchSaDetectorSearch("d1", conf)
+				return err
+			},
+			expected: `{"query":{"bool":{"filter":[{"ids":{"values":["d1"]}},{"term":{"_routing":"tenant-a"}}]}},"size":2,"version":true}`,
+		},
+		{
+			name:     "detector search_routing",
+			response: detector,
+			call: func(conf *ProviderConf) error {
+				_, err := resourceOpensearchSaDetectorSearchWithOptions("d1", saDetectorSearchOptions{Routing: "tenant-b"}, conf)
+				return err
+			},
+			expected: `{"query":{"bool":{"filter":[{"ids":{"values":["d1"]}},{"term":{"_routing":"tenant-b"}}]}},"size":2,"version":true}`,
+		},
+		{
+			name:     "rule",
+			response: rule,
+			call: func(conf *ProviderConf) error {
+				_, err := resourceOpensearchSaDetectorRuleGet("r1", conf)
+				return err
+			},
+			expected: `{"query":{"bool":{"filter":[{"ids":{"values":["r1"]}},{"term":{"_routing":"tenant-a"}}]}},"size":2}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster, conf := newSaFakeCluster(t, c.response)
+			conf.saRouting = "tenant-a"
+			if err := c.call(conf); err != nil {
+				t.Fatal(err)
+			}
+			if body := cluster.lastRequest(t).Body; body != c.expected {
+				t.Errorf("expected search %s, got %s", c.expected, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+// SaError holds what the cluster reported about a failed security analytics
+// request. Status is zero for errors detected by the provider itself, such as
+// a search matching no document.
+type SaError struct {
+	// the ID of the object the request was about, when known
+	ID     string
+	Status int
+	// the type and reason of the error parsed from the response body
+	Type   string
+	Reason string
+
+	err error
+}
+
+func (e *SaError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	if e.ID != "" {
+		return fmt.Sprintf("%s for ID: %s", e.Reason, e.ID)
+	}
+	return e.Reason
+}
+
+// Unwrap gives access to the underlying *elastic7.Error.
+func (e *SaError) Unwrap() error {
+	return e.err
+}
+
+// SaNotFoundError is returned when the object of a request does not exist,
+// including when the system index holding it has not been created yet.
+type SaNotFoundError struct{ SaError }
+
+// SaConflictError is returned when a write conflicts with the current state
+// of the object, for example a concurrent update of the same version.
+type SaConflictError struct{ SaError }
+
+// SaValidationError is returned when the cluster rejects the request, most
+// often because of an invalid detector or rule document.
+type SaValidationError struct{ SaError }
+
+func (e *SaNotFoundError) Unwrap() error   { return &e.SaError }
+func (e *SaConflictError) Unwrap() error   { return &e.SaError }
+func (e *SaValidationError) Unwrap() error { return &e.SaError }
+
+// searchNotFoundError returns the error reported when no document matches id.
+func searchNotFoundError(id string) error {
+	return &SaNotFoundError{SaError{ID: id, Reason: "no search results found"}}
+}
+
+// saRequestTooLarge reports whether a request was rejected for the size of
+// its body.
+func saRequestTooLarge(err error) bool {
+	var saErr *SaError
+	return errors.As(err, &saErr) && saErr.Status == http.StatusRequestEntityTooLarge
+}
+
+// newSaError classifies an error returned for a security analytics request
+// by the status and type the cluster responded with. Other errors, such as
+// network errors, are returned as is.
+func newSaError(err error) error {
+	var osErr *elastic7.Error
+	if !errors.As(err, &osErr) {
+		return err
+	}
+
+	saErr := SaError{Status: osErr.Status, err: err}
+	if osErr.Details != nil {
+		saErr.Type = osErr.Details.Type
+		saErr.Reason = osErr.Details.Reason
+	}
+
+	switch {
+	case osErr.Status == http.StatusNotFound || saErr.Type == "index_not_found_exception":
+		return &SaNotFoundError{saErr}
+	case osErr.Status == http.StatusConflict || saErr.Type == "version_conflict_engine_exception":
+		return &SaConflictError{saErr}
+	case osErr.Status == http.StatusBadRequest:
+		return &SaValidationError{saErr}
+	default:
+		return &saErr
+	}
+}
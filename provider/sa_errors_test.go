@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+func TestNewSaError(t *testing.T) {
+	cases := []struct {
+		name  string
+		err   error
+		check func(err error) bool
+	}{
+		{name: "nil", err: nil, check: func(err error) bool { return err == nil }},
+		{name: "not found", err: &elastic7.Error{Status: 404}, check: func(err error) bool {
+			var target *SaNotFoundError
+			return errors.As(err, &target) && target.Status == 404
+		}},
+		{name: "missing index", err: &elastic7.Error{Status: 500, Details: &elastic7.ErrorDetails{Type: "index_not_found_exception"}}, check: func(err error) bool {
+			var target *SaNotFoundError
+			return errors.As(err, &target)
+		}},
+		{name: "conflict", err: &elastic7.Error{Status: 409, Details: &elastic7.ErrorDetails{Type: "version_conflict_engine_exception", Reason: "version conflict"}}, check: func(err error) bool {
+			var target *SaConflictError
+			return errors.As(err, &target) && target.Reason == "version conflict"
+		}},
+		{name: "validation", err: &elastic7.Error{Status: 400, Details: &elastic7.ErrorDetails{Type: "security_analytics_exception", Reason: "invalid detector"}}, check: func(err error) bool {
+			var target *SaValidationError
+			var osErr *elastic7.Error
+			return errors.As(err, &target) && target.Type == "security_analytics_exception" && errors.As(err, &osErr)
+		}},
+		{name: "server error", err: &elastic7.Error{Status: 500}, check: func(err error) bool {
+			var target *SaError
+			var notFound *SaNotFoundError
+			return errors.As(err, &target) && target.Status == 500 && !errors.As(err, &notFound)
+		}},
+		{name: "network error", err: fmt.Errorf("connection refused"), check: func(err error) bool {
+			var target *SaError
+			return err.Error() == "connection refused" && !errors.As(err, &target)
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := newSaError(c.err); !c.check(err) {
+				t.Errorf("unexpected error %#v", err)
+			}
+		})
+	}
+
+	if err := newSaError(&elastic7.Error{Status: 404}); err.Error() != (&elastic7.Error{Status: 404}).Error() {
+		t.Errorf("expected the message of the cluster error, got %q", err.Error())
+	}
+	if err := searchNotFoundError("abc"); err.Error() != "no search results found for ID: abc" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
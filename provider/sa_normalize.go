@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// saNormalizer canonicalizes security analytics documents so that documents
+// sent by the provider compare equal to the ones the cluster returns. Paths
+// are dot separated field names, where `*` stands for every element of a
+// list, for example `triggers.*.id`.
+type saNormalizer struct {
+	// server-managed fields removed from the document
+	stripFields []string
+	// lists whose order is not significant, sorted by the value of the given
+	// field of their elements, or by the elements themselves when empty
+	sortLists map[string]string
+}
+
+var saDetectorNormalizer = &saNormalizer{
+	stripFields: []string{
+		"type",
+		"user",
+		"last_update_time",
+		"enabled_time",
+		"threat_intel_enabled",
+		// trigger IDs are generated by the server
+		"triggers.*.id",
+		// search metadata
+		"alert_history_index",
+		"alert_history_index_pattern",
+		"alert_index",
+		"findings_index",
+		"findings_index_pattern",
+		"monitor_id",
+		"rule_topic_index",
+		"workflow_ids",
+		"bucket_monitor_id_rule_id",
+	},
+	sortLists: map[string]string{
+		"inputs.*.detector_input.custom_rules":       "id",
+		"inputs.*.detector_input.pre_packaged_rules": "id",
+		// the cluster may return the triggers in another order after one is
+		// added, their generated IDs are stripped so they are sorted by name
+		"triggers": "name",
+	},
+}
+
+// saDetectorServerFields returns the top-level fields stripped from
+// detectors, the ones the keep_server_fields provider option accepts.
+func saDetectorServerFields() []string {
+	fields := make([]string, 0, len(saDetectorNormalizer.stripFields))
+	for _, path := range saDetectorNormalizer.stripFields {
+		if !strings.Contains(path, ".") {
+			fields = append(fields, path)
+		}
+	}
+	return fields
+}
+
+// Sigma rule bodies are stored as sent, so only their YAML is canonicalized.
+var saRuleNormalizer = &saNormalizer{}
+
+// normalize strips and sorts the configured fields of doc in place.
+func (n *saNormalizer) normalize(doc interface{}) {
+	for _, path := range n.stripFields {
+		saWalkPath(doc, strings.Split(path, "."), func(obj map[string]interface{}, field string) {
+			delete(obj, field)
+		})
+	}
+	for path, key := range n.sortLists {
+		saWalkPath(doc, strings.Split(path, "."), func(obj map[string]interface{}, field string) {
+			if list, ok := obj[field].([]interface{}); ok {
+				sort.SliceStable(list, func(i, j int) bool {
+					return saSortKey(list[i], key) < saSortKey(list[j], key)
+				})
+			}
+		})
+	}
+}
+
+// normalizeJSON decodes and normalizes a JSON document, see
+// unmarshalCoercingNumbers for the representation of numbers.
+func (n *saNormalizer) normalizeJSON(data string) (interface{}, error) {
+	doc, err := unmarshalCoercingNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+	n.normalize(doc)
+	return doc, nil
+}
+
+// normalizeYAML decodes and normalizes a YAML document. Mappings are decoded
+// into the same map type as JSON objects.
+func (n *saNormalizer) normalizeYAML(data string) (interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+	doc = saStringKeys(doc)
+	n.normalize(doc)
+	return doc, nil
+}
+
+// equalJSON reports whether two JSON documents are the same once
+// normalized. Documents that cannot be decoded are only equal to themselves.
+func (n *saNormalizer) equalJSON(a, b string) bool {
+	return n.equal(a, b, n.normalizeJSON)
+}
+
+// equalYAML is equalJSON for YAML documents.
+func (n *saNormalizer) equalYAML(a, b string) bool {
+	return n.equal(a, b, n.normalizeYAML)
+}
+
+func (n *saNormalizer) equal(a, b string, decode func(string) (interface{}, error)) bool {
+	if a == b {
+		return true
+	}
+	ad, err := decode(a)
+	if err != nil {
+		return false
+	}
+	bd, err := decode(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(ad, bd)
+}
+
+// changedPathsJSON returns the paths, in the dot separated form of
+// stripFields with list indices, at which two JSON documents differ once
+// normalized. Lists of different lengths are reported as a whole. An empty
+// document a counts as no change, as when a resource is created.
+func (n *saNormalizer) changedPathsJSON(a, b string) ([]string, error) {
+	if a == "" {
+		return []string{}, nil
+	}
+	ad, err := n.normalizeJSON(a)
+	if err != nil {
+		return nil, err
+	}
+	bd, err := n.normalizeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+	saChangedPaths(ad, bd, "", &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func saChangedPaths(a, b interface{}, prefix string, paths *[]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			for key, value := range a {
+				saChangedPaths(value, b[key], join(key), paths)
+			}
+			for key, value := range b {
+				if _, ok := a[key]; !ok {
+					saChangedPaths(nil, value, join(key), paths)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok && len(a) == len(b) {
+			for i := range a {
+				saChangedPaths(a[i], b[i], join(fmt.Sprint(i)), paths)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if prefix == "" {
+			prefix = "."
+		}
+		*paths = append(*paths, prefix)
+	}
+}
+
+// saWalkPath calls fn with the object holding the last field of path for
+// every match of path in v.
+func saWalkPath(v interface{}, path []string, fn func(obj map[string]interface{}, field string)) {
+	if len(path) == 0 {
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			fn(v, path[0])
+			return
+		}
+		if child, ok := v[path[0]]; ok {
+			saWalkPath(child, path[1:], fn)
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for _, element := range v {
+				saWalkPath(element, path[1:], fn)
+			}
+		}
+	}
+}
+
+func saSortKey(element interface{}, key string) string {
+	if obj, ok := element.(map[string]interface{}); ok && key != "" {
+		return fmt.Sprint(obj[key])
+	}
+	return fmt.Sprint(element)
+}
+
+// saStringKeys converts the map[interface{}]interface{} mappings decoded by
+// yaml.v2 into map[string]interface{}.
+func saStringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			obj[fmt.Sprint(key)] = saStringKeys(value)
+		}
+		return obj
+	case []interface{}:
+		for i, value := range v {
+			v[i] = saStringKeys(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSaNormalizer(t *testing.T) {
+	normalizer := &saNormalizer{
+		stripFields: []string{"user", "triggers.*.id", "inputs.*.missing.field"},
+		sortLists: map[string]string{
+			"inputs.*.rules": "id",
+			"tags":           "",
+		},
+	}
+
+	cases := []struct {
+		name     string
+		doc      string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "strips top level fields",
+			doc:      `{"name": "a", "user": {"name": "admin"}}`,
+			expected: map[string]interface{}{"name": "a"},
+		},
+		{
+			name: "strips fields of list elements",
+			doc:  `{"triggers": [{"id": "x", "name": "t1"}, {"name": "t2"}]}`,
+			expected: map[string]interface{}{"triggers": []interface{}{
+				map[string]interface{}{"name": "t1"},
+				map[string]interface{}{"name": "t2"},
+			}},
+		},
+		{
+			name: "sorts lists by key",
+			doc:  `{"inputs": [{"rules": [{"id": "b"}, {"id": "a"}]}]}`,
+			expected: map[string]interface{}{"inputs": []interface{}{
+				map[string]interface{}{"rules": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+				}},
+			}},
+		},
+		{
+			name:     "sorts scalar lists",
+			doc:      `{"tags": ["b", "c", "a"]}`,
+			expected: map[string]interface{}{"tags": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name:     "ignores paths that do not match",
+			doc:      `{"inputs": "x", "tags": "y"}`,
+			expected: map[string]interface{}{"inputs": "x", "tags": "y"},
+		},
+		{
+			name:     "coerces numbers",
+			doc:      `{"interval": 5.0}`,
+			expected: map[string]interface{}{"interval": int64(5)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := normalizer.normalizeJSON(c.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(doc, c.expected) {
+				t.Errorf("expected %#v, got %#v", c.expected, doc)
+			}
+		})
+	}
+}
+
+func TestSaNormalizerEqual(t *testing.T) {
+	cases := []struct {
+		name       string
+		normalizer *saNormalizer
+		yaml       bool
+		a, b       string
+		expected   bool
+	}{
+		{name: "detector rule order", normalizer: saDetectorNormalizer, a: `{"inputs": [{"detector_input": {"custom_rules": [{"id": "a"}, {"id": "b"}]}}]}`, b: `{"inputs": [{"detector_input": {"custom_rules": [{"id": "b"}, {"id": "a"}]}}]}`, expected: true},
+		{name: "detector server fields", normalizer: saDetectorNormalizer, a: `{"name": "a"}`, b: `{"name": "a", "monitor_id": ["m"], "last_update_time": 1}`, expected: true},
+		{name: "detector trigger ids", normalizer: saDetectorNormalizer, a: `{"triggers": [{"name": "t"}]}`, b: `{"triggers": [{"id": "x", "name": "t"}]}`, expected: true},
+		{
+			name:       "detector appended trigger",
+			normalizer: saDetectorNormalizer,
+			a:          `{"triggers": [{"name": "a", "severity": "1"}, {"name": "b", "severity": "2"}, {"name": "c", "severity": "3"}]}`,
+			b:          `{"triggers": [{"id": "z", "name": "c", "severity": "3"}, {"id": "x", "name": "a", "severity": "1"}, {"id": "y", "name": "b", "severity": "2"}]}`,
+			expected:   true,
+		},
+		{name: "detector trigger change", normalizer: saDetectorNormalizer, a: `{"triggers": [{"name": "a", "severity": "1"}, {"name": "b", "severity": "2"}]}`, b: `{"triggers": [{"name": "b", "severity": "1"}, {"name": "a", "severity": "1"}]}`, expected: false},
+		{name: "detector index order", normalizer: saDetectorNormalizer, a: `{"inputs": [{"detector_input": {"indices": ["a", "b"]}}]}`, b: `{"inputs": [{"detector_input": {"indices": ["b", "a"]}}]}`, expected: false},
+		{name: "invalid json", normalizer: saDetectorNormalizer, a: `{`, b: `{}`, expected: false},
+		{name: "rule formatting", normalizer: saRuleNormalizer, yaml: true, a: "title: a\nlevel: high\n", b: "# comment\nlevel: high\ntitle: 'a'\n", expected: true},
+		{name: "rule change", normalizer: saRuleNormalizer, yaml: true, a: "title: a\n", b: "title: b\n", expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			equal := c.normalizer.equalJSON(c.a, c.b)
+			if c.yaml {
+				equal = c.normalizer.equalYAML(c.a, c.b)
+			}
+			if equal != c.expected {
+				t.Errorf("expected equal to be %t, got %t", c.expected, equal)
+			}
+		})
+	}
+}
+
+func TestSaNormalizerChangedPaths(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     string
+		expected []string
+	}{
+		{name: "created", a: "", b: `{"name": "t"}`, expected: []string{}},
+		{name: "same", a: `{"name": "t", "last_update_time": 1}`, b: `{"name": "t"}`, expected: []string{}},
+		{
+			name:     "nested fields",
+			a:        `{"name": "t", "schedule": {"period": {"interval": 1, "unit": "MINUTES"}}, "triggers": [{"name": "a", "severity": "1"}]}`,
+			b:        `{"name": "t", "schedule": {"period": {"interval": 5, "unit": "MINUTES"}}, "triggers": [{"name": "a", "severity": "2"}], "enabled": true}`,
+			expected: []string{"enabled", "schedule.period.interval", "triggers.0.severity"},
+		},
+		{
+			name:     "reordered triggers",
+			a:        `{"triggers": [{"name": "a", "severity": "1"}, {"name": "b", "severity": "2"}]}`,
+			b:        `{"triggers": [{"id": "y", "name": "b", "severity": "3"}, {"id": "x", "name": "a", "severity": "1"}]}`,
+			expected: []string{"triggers.1.severity"},
+		},
+		{name: "list length", a: `{"triggers": [{"name": "a"}]}`, b: `{"triggers": []}`, expected: []string{"triggers"}},
+		{name: "removed field", a: `{"name": "t", "enabled": true}`, b: `{"name": "t"}`, expected: []string{"enabled"}},
+		{name: "document type", a: `{"name": "t"}`, b: `[]`, expected: []string{"."}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			paths, err := saDetectorNormalizer.changedPathsJSON(c.a, c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(paths, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+// saAPIPath is the path of the security analytics REST API.
+const saAPIPath = "/_plugins/_security_analytics"
+
+// joinURLPath joins URL path segments, leaving a single slash between them
+// however the segments start or end. The path is absolute and has no
+// trailing slash. A query string in the last segment is kept as is.
+func joinURLPath(elem ...string) string {
+	path := strings.Join(elem, "/")
+	query := ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+
+	segments := make([]string, 0, len(elem))
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return "/" + strings.Join(segments, "/") + query
+}
+
+// saRequestOptions returns the options of a security analytics request.
+// Query parameters are kept apart from the path so that PerformRequest
+// encodes them, rather than being expanded into it.
+func saRequestOptions(method, path string, params url.Values, body string) elastic7.PerformRequestOptions {
+	opts := elastic7.PerformRequestOptions{
+		Method: method,
+		Path:   path,
+		Params: params,
+	}
+	if body != "" {
+		opts.Body = body
+		opts.ContentType = "application/json"
+	}
+	return opts
+}
+
+// saOperation is the kind of operation a security analytics request is part
+// of, which selects the timeout and retries applied to it.
+type saOperation string
+
+const (
+	saOperationCreate saOperation = "create"
+	saOperationRead   saOperation = "read"
+	saOperationUpdate saOperation = "update"
+	saOperationDelete saOperation = "delete"
+)
+
+var saOperations = []saOperation{saOperationCreate, saOperationRead, saOperationUpdate, saOperationDelete}
+
+// saDefaultMaxRetries is the number of retries of a security analytics
+// request unless sa_request_max_retries says otherwise.
+const saDefaultMaxRetries = 8
+
+// saRequestSettings bound the time a security analytics request may take and
+// the number of times it is retried.
+type saRequestSettings struct {
+	// no limit besides the one of the HTTP client when zero
+	timeout    time.Duration
+	maxRetries int
+}
+
+// saRequestSettingsFor returns the settings configured for an operation, or
+// the defaults when the provider was not configured with any.
+func (conf *ProviderConf) saRequestSettingsFor(op saOperation) saRequestSettings {
+	if settings, ok := conf.saRequestSettings[op]; ok {
+		return settings
+	}
+	return saRequestSettings{maxRetries: saDefaultMaxRetries}
+}
+
+// performSaRequest sends a security analytics request with the timeout and
+// retries configured for op and logs its method, path, response status and
+// duration, retries included, at DEBUG level. The fields are logged as JSON
+// when sa_structured_request_logs is set. Only creates are treated as not
+// idempotent by the retrier. A request whose gzip_requests compressed body is
+// rejected is sent again uncompressed. Errors returned by the cluster are
+// classified by newSaError.
+func performSaRequest(ctx context.Context, conf *ProviderConf, osClient *elastic7.Client, op saOperation, opts elastic7.PerformRequestOptions) (*elastic7.Response, error) {
+	settings := conf.saRequestSettingsFor(op)
+	if settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+	opts.Retrier = newSaRetrier(op != saOperationCreate, settings.maxRetries)
+	opts.RetryStatusCodes = saRetryStatusCodes
+
+	start := time.Now()
+	res, err := osClient.PerformRequest(ctx, opts)
+	if opts.Body != nil && conf.gzipRequests && saGzipRejected(err) {
+		if conf.gzipRejected.CompareAndSwap(false, true) {
+			log.Printf("[WARN] The cluster rejected a compressed request body, sending request bodies uncompressed from now on: %+v", err)
+		}
+		if osClient, err = getClient(conf); err == nil {
+			res, err = osClient.PerformRequest(ctx, opts)
+		}
+	}
+	elapsed := time.Since(start)
+
+	path := opts.Path
+	if len(opts.Params) > 0 {
+		path += "?" + opts.Params.Encode()
+	}
+
+	status := 0
+	var osErr *elastic7.Error
+	if res != nil {
+		status = res.StatusCode
+	} else if errors.As(err, &osErr) {
+		status = osErr.Status
+	}
+
+	if conf.saRequestLogger != nil {
+		conf.saRequestLogger.Debug("security analytics request",
+			"operation", string(op),
+			"method", opts.Method,
+			"path", path,
+			"status", status,
+			"elapsed_ms", elapsed.Milliseconds(),
+		)
+	} else {
+		log.Printf("[DEBUG] Security analytics %s request %s %s returned status %d in %s", op, opts.Method, path, status, elapsed)
+	}
+
+	return res, newSaError(err)
+}
+
+// saGzipRejected reports whether a request failed because its body was
+// compressed: the media type was refused, or the body was read as is and did
+// not parse as content.
+func saGzipRejected(err error) bool {
+	var osErr *elastic7.Error
+	if !errors.As(err, &osErr) {
+		return false
+	}
+	if osErr.Status == http.StatusUnsupportedMediaType {
+		return true
+	}
+	return osErr.Details != nil && osErr.Details.Type == "not_x_content_exception"
+}
+
+// saResponseWarnings returns the messages of the Warning headers of a
+// response. OpenSearch sends them in the warn-code warn-agent "warn-text"
+// format of RFC 7234, optionally followed by a date.
+func saResponseWarnings(header http.Header) []string {
+	var warnings []string
+	for _, value := range header.Values("Warning") {
+		text := value
+		if start := strings.Index(value, `"`); start >= 0 {
+			if quoted, err := strconv.QuotedPrefix(value[start:]); err == nil {
+				text, _ = strconv.Unquote(quoted)
+			}
+		}
+		warnings = append(warnings, text)
+	}
+	return warnings
+}
@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	elastic7 "github.com/olivere/elastic/v7"
+)
+
+func TestPerformSaRequestLogsStructuredFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"type":"resource_not_found_exception"},"status":404}`))
+	}))
+	defer server.Close()
+
+	osClient, err := elastic7.NewClient(elastic7.SetURL(server.URL), elastic7.SetSniff(false), elastic7.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	conf := &ProviderConf{saRequestLogger: hclog.New(&hclog.LoggerOptions{
+		Level:      hclog.Debug,
+		Output:     &out,
+		JSONFormat: true,
+	})}
+
+	_, err = performSaRequest(context.TODO(), conf, osClient, saOperationRead, elastic7.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/_plugins/_security_analytics/detectors/abc",
+	})
+	var notFound *SaNotFoundError
+	if !errors.As(err, &notFound) || notFound.Status != http.StatusNotFound || notFound.Type != "resource_not_found_exception" {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON log entry, got %q: %v", out.String(), err)
+	}
+	if entry["method"] != "GET" || entry["path"] != "/_plugins/_security_analytics/detectors/abc" || entry["status"] != float64(404) {
+		t.Errorf("unexpected log entry %v", entry)
+	}
+	if _, ok := entry["elapsed_ms"]; !ok {
+		t.Errorf("expected elapsed_ms in log entry %v", entry)
+	}
+}
+
+func TestPerformSaRequestGzip(t *testing.T) {
+	type received struct {
+		encoding string
+		body     string
+	}
+	newServer := func(t *testing.T, rejectGzip bool) (*[]received, *ProviderConf) {
+		requests := &[]received{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body io.Reader = r.Body
+			encoding := r.Header.Get("Content-Encoding")
+			if encoding == "gzip" {
+				reader, err := gzip.NewReader(r.Body)
+				if err != nil {
+					t.Errorf("expected a gzip body: %v", err)
+					return
+				}
+				body = reader
+			}
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Errorf("error reading body: %v", err)
+			}
+			*requests = append(*requests, received{encoding: encoding, body: string(b)})
+
+			w.Header().Set("Content-Type", "application/json")
+			if rejectGzip && encoding == "gzip" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				_, _ = w.Write([]byte(`{"error":{"type":"unsupported_media_type"},"status":415}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"hits":{"total":{"value":0},"hits":[]}}`))
+		}))
+		t.Cleanup(server.Close)
+
+		parsedUrl, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return requests, &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0", gzipRequests: true}
+	}
+	send := func(t *testing.T, conf *ProviderConf) {
+		osClient, err := getClient(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = performSaRequest(context.TODO(), conf, osClient, saOperationRead, saRequestOptions("POST", "/_plugins/_security_analytics/rules/_search", nil, `{"size":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("compressed", func(t *testing.T) {
+		requests, conf := newServer(t, false)
+		send(t, conf)
+		if len(*requests) != 1 || (*requests)[0] != (received{encoding: "gzip", body: `{"size":1}`}) {
+			t.Errorf("expected a single compressed request, got %+v", *requests)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		requests, conf := newServer(t, true)
+		send(t, conf)
+		send(t, conf)
+		expected := []received{
+			{encoding: "gzip", body: `{"size":1}`},
+			{body: `{"size":1}`},
+			{body: `{"size":1}`},
+		}
+		if !reflect.DeepEqual(*requests, expected) {
+			t.Errorf("expected the rejected request to be sent again uncompressed, and compression to stay off, got %+v", *requests)
+		}
+	})
+}
+
+func TestSaRequestSettingsFromConfig(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"sa_request_timeout":     30,
+		"sa_request_max_retries": 3,
+		"sa_request_overrides": []interface{}{
+			map[string]interface{}{"operation": "create", "timeout": 300},
+			map[string]interface{}{"operation": "read", "max_retries": 0},
+		},
+	})
+
+	settings := saRequestSettingsFromConfig(d)
+	expected := map[saOperation]saRequestSettings{
+		saOperationCreate: {timeout: 300 * time.Second, maxRetries: 3},
+		saOperationRead:   {timeout: 30 * time.Second, maxRetries: 0},
+		saOperationUpdate: {timeout: 30 * time.Second, maxRetries: 3},
+		saOperationDelete: {timeout: 30 * time.Second, maxRetries: 3},
+	}
+	for op, want := range expected {
+		if settings[op] != want {
+			t.Errorf("expected %s settings %+v, got %+v", op, want, settings[op])
+		}
+	}
+}
+
+func TestSaCreateGatewayTimeoutNotRetried(t *testing.T) {
+	timeout := saFakeResponse{Status: http.StatusGatewayTimeout, Body: ``}
+	created := saFakeResponse{Status: http.StatusOK, Body: `{"_id":"d1","_version":1,"detector":` + saFakeDetector + `}`}
+
+	// generous retries of creates must not send the create again
+	cluster, conf := newSaFakeCluster(t, timeout, created)
+	conf.saRequestSettings[saOperationCreate] = saRequestSettings{timeout: time.Minute, maxRetries: 8}
+	if _, err := resourceOpensearchPostSaDetector(saFakeDetectorData(t), conf); err == nil {
+		t.Error("expected the gateway timeout of the create to be returned")
+	}
+	if len(cluster.requests) != 1 {
+		t.Errorf("expected a single create request, got %+v", cluster.requests)
+	}
+
+	cluster, conf = newSaFakeCluster(t, timeout, created)
+	if _, err := resourceOpensearchPutSaDetector(saFakeDetectorData(t), conf); err != nil {
+		t.Fatalf("expected the update to be retried, got %v", err)
+	}
+	if len(cluster.requests) != 2 {
+		t.Errorf("expected the update to be sent again, got %+v", cluster.requests)
+	}
+}
+
+func TestSaResponseWarnings(t *testing.T) {
+	header := http.Header{}
+	header.Add("Warning", `299 OpenSearch-2.15.0-abc "no index matches [logs-*]" "Mon, 14 Oct 2024 10:00:00 GMT"`)
+	header.Add("Warning", `299 OpenSearch-2.15.0-abc "field \"x\" is deprecated"`)
+	header.Add("Warning", `not an RFC 7234 warning`)
+
+	warnings := saResponseWarnings(header)
+	expected := []string{"no index matches [logs-*]", `field "x" is deprecated`, "not an RFC 7234 warning"}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected warnings %q, got %q", expected, warnings)
+	}
+	for i := range expected {
+		if warnings[i] != expected[i] {
+			t.Errorf("expected warning %q, got %q", expected[i], warnings[i])
+		}
+	}
+}
+
+func TestJoinURLPath(t *testing.T) {
+	cases := []struct {
+		elem     []string
+		expected string
+	}{
+		{elem: []string{saAPIPath, "detectors"}, expected: "/_plugins/_security_analytics/detectors"},
+		{elem: []string{"/gateway/", "/_plugins/_security_analytics/", "/detectors/abc"}, expected: "/gateway/_plugins/_security_analytics/detectors/abc"},
+		{elem: []string{"/gateway", "_plugins//_security_analytics", "detectors/"}, expected: "/gateway/_plugins/_security_analytics/detectors"},
+		{elem: []string{"gateway/", saAPIPath, "rules/_search?pre_packaged=true"}, expected: "/gateway/_plugins/_security_analytics/rules/_search?pre_packaged=true"},
+		{elem: []string{"", "/"}, expected: "/"},
+	}
+
+	for _, c := range cases {
+		if path := joinURLPath(c.elem...); path != c.expected {
+			t.Errorf("expected %v to join into %s, got %s", c.elem, c.expected, path)
+		}
+	}
+}
+
+func TestSaRequestOptionsEncodesParams(t *testing.T) {
+	var query url.Values
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	osClient, err := elastic7.NewClient(elastic7.SetURL(server.URL), elastic7.SetSniff(false), elastic7.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, category := range []string{"windows", "a&forced=false", "win dows/ü", "100%", "x?y#z"} {
+		params := url.Values{
+			"category": []string{category},
+			"forced":   []string{"true"},
+		}
+		opts := saRequestOptions("PUT", "/_plugins/_security_analytics/rules/abc", params, `{"rule": "x"}`)
+		if _, err := performSaRequest(context.TODO(), &ProviderConf{}, osClient, saOperationUpdate, opts); err != nil {
+			t.Fatal(err)
+		}
+		if path != "/_plugins/_security_analytics/rules/abc" {
+			t.Errorf("expected the parameters to stay out of the path, got %s", path)
+		}
+		if query.Get("category") != category || len(query["forced"]) != 1 || query.Get("forced") != "true" {
+			t.Errorf("expected category %q and forced=true, got %v", category, query)
+		}
+	}
+}
+
+func TestSaRequestOptionsBody(t *testing.T) {
+	if opts := saRequestOptions("DELETE", "/x", nil, ""); opts.Body != nil || opts.ContentType != "" {
+		t.Errorf("expected no body, got %+v", opts)
+	}
+	if opts := saRequestOptions("POST", "/x", nil, "{}"); opts.Body != "{}" || opts.ContentType != "application/json" {
+		t.Errorf("expected a JSON body, got %+v", opts)
+	}
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// saSearchPageSize is the number of documents requested per page by
+// saSearchEach.
+const saSearchPageSize = 100
+
+// saHit is a document returned by a security analytics search endpoint, with
+// its source unwrapped.
+type saHit struct {
+	ID      string
+	Index   string
+	Version int
+	Source  map[string]interface{}
+}
+
+// saSearchResult is a decoded page of security analytics search results.
+type saSearchResult struct {
+	Total int
+	Hits  []saHit
+}
+
+// saSearch sends query, any query DSL search body, to the search endpoint at
+// path and decodes the hits. The source of each hit is unwrapped from the key
+// named wrapper, such as "detector" or "rule".
+func saSearch(path string, params url.Values, query map[string]interface{}, wrapper string, m interface{}) (*saSearchResult, error) {
+	queryBody, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling query body: %+v", err)
+	}
+
+	log.Printf("[DEBUG] queryBody=%s", queryBody)
+
+	osClient, err := getClient(m.(*ProviderConf))
+	if err != nil {
+		return nil, err
+	}
+	res, err := performSaRequest(context.TODO(), m.(*ProviderConf), osClient, saOperationRead, saRequestOptions("POST", path, params, string(queryBody)))
+	if err != nil {
+		return nil, err
+	}
+
+	var searchResult querySearchResult
+	if err := json.Unmarshal(res.Body, &searchResult); err != nil {
+		return nil, fmt.Errorf("error unmarshalling search result: %+v", err)
+	}
+
+	result := &saSearchResult{
+		Total: searchResult.Hits.Total.Value,
+		Hits:  make([]saHit, 0, len(searchResult.Hits.Hits)),
+	}
+	for _, hit := range searchResult.Hits.Hits {
+		source, err := unwrapSearchSource(hit.Source, wrapper)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling %s source: %+v", wrapper, err)
+		}
+		result.Hits = append(result.Hits, saHit{
+			ID:      hit.ID,
+			Index:   hit.Index,
+			Version: hit.Version,
+			Source:  source,
+		})
+	}
+	return result, nil
+}
+
+// saIDsQuery builds the query clause matching documents by ID, restricted
+// to the documents of index and indexed with routing when they are set.
+// Without them it is a plain ids query.
+func saIDsQuery(ids []string, index, routing string) map[string]interface{} {
+	idsQuery := map[string]interface{}{
+		"ids": map[string]interface{}{
+			"values": ids,
+		},
+	}
+	if index == "" && routing == "" {
+		return idsQuery
+	}
+
+	filters := []interface{}{idsQuery}
+	if index != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"_index": index},
+		})
+	}
+	if routing != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"_routing": routing},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filters,
+		},
+	}
+}
+
+// saSearchOne returns the single hit of query, or a not found error naming
+// id when the search matches nothing. A search matching several documents,
+// for example through an alias spanning several indices holding copies of
+// the document, is an error rather than a guess. The size of query is
+// overridden.
+func saSearchOne(path string, params url.Values, query map[string]interface{}, wrapper, id string, m interface{}) (*saHit, error) {
+	one := make(map[string]interface{}, len(query)+1)
+	for k, v := range query {
+		one[k] = v
+	}
+	// a second hit is enough to tell that the document is not unique
+	one["size"] = 2
+
+	result, err := saSearch(path, params, one, wrapper, m)
+	if err != nil {
+		return nil, err
+	}
+	if result.Total == 0 || len(result.Hits) == 0 {
+		return nil, searchNotFoundError(id)
+	}
+	if result.Total > 1 || len(result.Hits) > 1 {
+		indices := make([]string, 0, len(result.Hits))
+		for _, hit := range result.Hits {
+			if !containsString(indices, hit.Index) {
+				indices = append(indices, hit.Index)
+			}
+		}
+		return nil, fmt.Errorf("the search for %s %s matched %d documents, in indices %s: check the aliases of the indices searched", wrapper, id, result.Total, strings.Join(indices, ", "))
+	}
+	return &result.Hits[0], nil
+}
+
+// saSearchEach calls fn with every hit of query, requesting saSearchPageSize
+// documents at a time so that a single page is held in memory. The from and
+// size of query are overridden.
+func saSearchEach(path string, params url.Values, query map[string]interface{}, wrapper string, m interface{}, fn func(saHit) error) error {
+	page := make(map[string]interface{}, len(query)+2)
+	for k, v := range query {
+		page[k] = v
+	}
+	page["size"] = saSearchPageSize
+
+	seen := 0
+	for {
+		page["from"] = seen
+		result, err := saSearch(path, params, page, wrapper, m)
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range result.Hits {
+			if err := fn(hit); err != nil {
+				return err
+			}
+		}
+
+		seen += len(result.Hits)
+		if len(result.Hits) < saSearchPageSize || seen >= result.Total {
+			return nil
+		}
+	}
+}
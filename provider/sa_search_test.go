@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newSaSearchTestServer serves total detector documents, nested under the
+// "detector" key, answering searches with the requested from and size. The
+// queries received are recorded.
+func newSaSearchTestServer(t *testing.T, total int, queries *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			t.Errorf("expected a JSON query, got %v", err)
+		}
+		*queries = append(*queries, query)
+
+		from, _ := query["from"].(float64)
+		size, ok := query["size"].(float64)
+		if !ok {
+			size = 10
+		}
+		hits := make([]string, 0)
+		for i := int(from); i < total && i < int(from+size); i++ {
+			hits = append(hits, fmt.Sprintf(`{"_id":"d%d","_version":%d,"_source":{"detector":{"name":"detector-%d"}}}`, i, i+1, i))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"hits":{"total":{"value":%d},"hits":[%s]}}`, total, strings.Join(hits, ","))
+	}))
+}
+
+func newSaSearchTestConf(t *testing.T, server *httptest.Server) *ProviderConf {
+	parsedUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ProviderConf{rawUrl: server.URL, parsedUrl: parsedUrl, osVersion: "2.11.0"}
+}
+
+func TestSaSearch(t *testing.T) {
+	var queries []map[string]interface{}
+	server := newSaSearchTestServer(t, 3, &queries)
+	defer server.Close()
+
+	query := map[string]interface{}{
+		"size": 2,
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"detector.detector_type": "windows"},
+		},
+	}
+	result, err := saSearch(joinURLPath(saAPIPath, "detectors/_search"), nil, query, "detector", newSaSearchTestConf(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || len(result.Hits) != 2 {
+		t.Fatalf("expected 2 of 3 hits, got %+v", result)
+	}
+	if hit := result.Hits[1]; hit.ID != "d1" || hit.Version != 2 || hit.Source["name"] != "detector-1" {
+		t.Errorf("expected the unwrapped detector d1, got %+v", hit)
+	}
+	if _, ok := queries[0]["query"].(map[string]interface{})["term"]; !ok {
+		t.Errorf("expected the query to be sent as is, got %v", queries[0])
+	}
+}
+
+func TestSaSearchOne(t *testing.T) {
+	var queries []map[string]interface{}
+	found := newSaSearchTestServer(t, 1, &queries)
+	defer found.Close()
+	empty := newSaSearchTestServer(t, 0, &queries)
+	defer empty.Close()
+
+	path := joinURLPath(saAPIPath, "detectors/_search")
+	query := map[string]interface{}{"size": 1}
+
+	hit, err := saSearchOne(path, nil, query, "detector", "d0", newSaSearchTestConf(t, found))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit.ID != "d0" || hit.Source["name"] != "detector-0" {
+		t.Errorf("expected detector d0, got %+v", hit)
+	}
+
+	_, err = saSearchOne(path, nil, query, "detector", "missing", newSaSearchTestConf(t, empty))
+	if !IsSearchNotFound(err) || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected a not found error naming the ID, got %v", err)
+	}
+}
+
+func TestSaSearchEach(t *testing.T) {
+	var queries []map[string]interface{}
+	server := newSaSearchTestServer(t, 2*saSearchPageSize+5, &queries)
+	defer server.Close()
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+	}
+	var ids []string
+	err := saSearchEach(joinURLPath(saAPIPath, "detectors/_search"), nil, query, "detector", newSaSearchTestConf(t, server), func(hit saHit) error {
+		ids = append(ids, hit.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2*saSearchPageSize+5 || ids[saSearchPageSize] != fmt.Sprintf("d%d", saSearchPageSize) {
+		t.Errorf("expected every detector once in order, got %d of them", len(ids))
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(queries))
+	}
+	for i, q := range queries {
+		if q["from"] != float64(i*saSearchPageSize) || q["size"] != float64(saSearchPageSize) || q["query"] == nil {
+			t.Errorf("unexpected query for page %d: %v", i, q)
+		}
+	}
+	if _, ok := query["from"]; ok {
+		t.Errorf("expected the query of the caller to be left untouched, got %v", query)
+	}
+}
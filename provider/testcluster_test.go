@@ -0,0 +1,110 @@
+//go:build testcluster
+
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// With the testcluster build tag, acceptance tests run without OPENSEARCH_URL
+// start a single node OpenSearch container with the docker CLI, configured
+// like the docker-compose.yml service. The image defaults to OSS_IMAGE. The
+// container is removed once the tests of the package are done.
+//
+//	TF_ACC=1 go test -tags testcluster ./provider -run TestAccOpensearchSa
+const (
+	testClusterDefaultImage = "opensearchproject/opensearch:2.15.0"
+	testClusterPassword     = "myStrongPassword123@456"
+	testClusterStartTimeout = 3 * time.Minute
+)
+
+var testCluster struct {
+	once        sync.Once
+	containerID string
+	url         string
+	err         error
+}
+
+func init() {
+	testAccStartCluster = startTestCluster
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testCluster.containerID != "" {
+		_ = exec.Command("docker", "rm", "--force", testCluster.containerID).Run()
+	}
+	os.Exit(code)
+}
+
+func startTestCluster() (string, error) {
+	testCluster.once.Do(func() {
+		testCluster.url, testCluster.err = runTestCluster()
+	})
+	return testCluster.url, testCluster.err
+}
+
+func runTestCluster() (string, error) {
+	image := os.Getenv("OSS_IMAGE")
+	if image == "" {
+		image = testClusterDefaultImage
+	}
+
+	out, err := exec.Command("docker", "run", "--detach",
+		"--publish", "127.0.0.1::9200",
+		"--env", "discovery.type=single-node",
+		"--env", "path.repo=/tmp",
+		"--env", "OPENSEARCH_JAVA_OPTS=-Xms1g -Xmx1g",
+		"--env", "OPENSEARCH_INITIAL_ADMIN_PASSWORD="+testClusterPassword,
+		"--env", "plugins.security.ssl.http.enabled=false",
+		image,
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("error starting %s: %+v", image, err)
+	}
+	testCluster.containerID = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", testCluster.containerID, "9200/tcp").Output()
+	if err != nil {
+		return "", fmt.Errorf("error looking up the port of the test cluster: %+v", err)
+	}
+	hostPort := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	url := fmt.Sprintf("http://admin:%s@%s", strings.ReplaceAll(testClusterPassword, "@", "%40"), hostPort)
+
+	if err := waitForTestCluster(url); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// waitForTestCluster polls the cluster until it answers and checks that the
+// security analytics plugin is installed.
+func waitForTestCluster(url string) error {
+	deadline := time.Now().Add(testClusterStartTimeout)
+	for {
+		res, err := http.Get(url + "/_cat/plugins?h=component")
+		if err == nil {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				if !strings.Contains(string(body), "opensearch-security-analytics") {
+					return fmt.Errorf("the test cluster image does not include the security analytics plugin")
+				}
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the test cluster did not come up within %s: %v", testClusterStartTimeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"log"
+	"math"
 	"os"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/mitchellh/go-homedir"
@@ -149,22 +154,127 @@ func normalizeAnomalyDetection(tpl map[string]interface{}) {
 	delete(tpl, "last_update_time")
 }
 
-func normalizeSaDetector(tpl map[string]interface{}) {
-	delete(tpl, "type")
-	delete(tpl, "last_update_time")
-	delete(tpl, "enabled_time")
-	delete(tpl, "threat_intel_enabled")
+// unmarshalCoercingNumbers decodes a JSON document, representing integral
+// numbers as int64 and any other number as float64. Numbers the cluster
+// returns as floats, such as 5.0 for 5, then compare equal to their integer
+// form, while large integers keep their exact value.
+func unmarshalCoercingNumbers(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return coerceJSONNumbers(v), nil
+}
+
+func coerceJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = coerceJSONNumbers(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = coerceJSONNumbers(value)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return int64(f)
+		}
+		return f
+	default:
+		return v
+	}
+}
+
+var bodyVarPlaceholderRegexp = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// renderBodyVars replaces the ${name} placeholders in body with the matching
+// values of vars. Placeholders without a value are left untouched.
+func renderBodyVars(body string, vars map[string]interface{}) string {
+	if len(vars) == 0 {
+		return body
+	}
+
+	return bodyVarPlaceholderRegexp.ReplaceAllStringFunc(body, func(placeholder string) string {
+		name := bodyVarPlaceholderRegexp.FindStringSubmatch(placeholder)[1]
+		if v, ok := vars[name]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return placeholder
+	})
+}
+
+// validateBodyTemplateJSON checks that a body is valid JSON once its ${name}
+// placeholders are filled in.
+func validateBodyTemplateJSON(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be string", k))
+		return warnings, errors
+	}
+
+	if !json.Valid([]byte(bodyVarPlaceholderRegexp.ReplaceAllString(v, "0"))) {
+		errors = append(errors, fmt.Errorf("%q contains an invalid JSON", k))
+	}
+	return warnings, errors
+}
+
+// validateJSONObject checks that a string holds a JSON object, such as a
+// query DSL clause.
+func validateJSONObject(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be string", k))
+		return warnings, errors
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(v), &obj); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a JSON object: %+v", k, err))
+	}
+	return warnings, errors
+}
+
+var cronFieldRegexp = regexp.MustCompile(`^[0-9A-Za-z*?,/#-]+$`)
 
-	// search metadata
-	delete(tpl, "alert_history_index")
-	delete(tpl, "alert_history_index_pattern")
-	delete(tpl, "alert_index")
-	delete(tpl, "findings_index")
-	delete(tpl, "findings_index_pattern")
-	delete(tpl, "monitor_id")
-	delete(tpl, "rule_topic_index")
-	delete(tpl, "workflow_ids")
-	delete(tpl, "bucket_monitor_id_rule_id")
+// validateCronExpression checks that a cron expression has five fields made
+// of the characters allowed by the alerting scheduler.
+func validateCronExpression(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be string", k))
+		return warnings, errors
+	}
+
+	fields := strings.Fields(v)
+	if len(fields) != 5 {
+		errors = append(errors, fmt.Errorf("%q must have 5 fields (minute hour day-of-month month day-of-week), got %d", k, len(fields)))
+		return warnings, errors
+	}
+	for _, f := range fields {
+		if !cronFieldRegexp.MatchString(f) {
+			errors = append(errors, fmt.Errorf("%q contains an invalid cron field %q", k, f))
+		}
+	}
+	return warnings, errors
+}
+
+// resourceGetter is satisfied by both *schema.ResourceData and
+// *schema.ResourceDiff, so body builders can run at plan and apply time.
+type resourceGetter interface {
+	Get(key string) interface{}
 }
 
 func flattenMap(m map[string]interface{}) map[string]interface{} {
@@ -191,6 +301,29 @@ func concatStringSlice(args ...[]string) []string {
 	return merged
 }
 
+// forEachConcurrently calls fn with every index below count, running at most
+// limit calls at the same time, and returns the first error encountered.
+func forEachConcurrently(count int, limit int, fn func(i int) error) error {
+	sem := make(chan struct{}, limit)
+	errs := make(chan error, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
 func containsString(h []string, n string) bool {
 	for _, e := range h {
 		if e == n {
@@ -491,14 +624,36 @@ func readPathOrContent(poc string) (string, bool, error) {
 
 // Checks if the error indicates that a search result was not found.
 // This function is necessary because the search endpoint may not provide an error
-// that is directly compatible with elastic7.IsNotFound. It handles both ElasticSearch's
-// standard "not found" error and a custom error message from OpenSearch that indicates
-// "no search results found for ID:".
+// that is directly compatible with elastic7.IsNotFound. It handles the
+// SaNotFoundError of security analytics requests, ElasticSearch's standard
+// "not found" error and a missing config index (which the security analytics
+// plugin may report with a non-404 status).
 func IsSearchNotFound(err error) bool {
 	if err == nil {
 		return false
 	}
-	return elastic7.IsNotFound(err) || strings.Contains(err.Error(), "no search results found for ID:")
+	var notFound *SaNotFoundError
+	if errors.As(err, &notFound) || elastic7.IsNotFound(err) {
+		return true
+	}
+
+	var osErr *elastic7.Error
+	return errors.As(err, &osErr) && osErr.Details != nil && osErr.Details.Type == "index_not_found_exception"
+}
+
+// unwrapSearchSource decodes the source of a search hit. Documents read from
+// a security analytics system index are nested under a key named after their
+// type, such as "detector", which is removed.
+func unwrapSearchSource(source json.RawMessage, wrapper string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return nil, err
+	}
+
+	if inner, ok := doc[wrapper].(map[string]interface{}); ok && len(doc) == 1 {
+		return inner, nil
+	}
+	return doc, nil
 }
 
 type querySearchResult struct {
@@ -508,8 +663,53 @@ type querySearchResult struct {
 		} `json:"total"`
 		Hits []struct {
 			Version int             `json:"_version"`
+			Index   string          `json:"_index"`
 			ID      string          `json:"_id"`
 			Source  json.RawMessage `json:"_source"`
 		} `json:"hits"`
 	} `json:"hits"`
 }
+
+// setJSONPointer replaces the value at an RFC 6901 JSON pointer, such as
+// /inputs/0/detector_input/indices/0, in a decoded JSON document. The pointer
+// must resolve to an existing object member or array element.
+func setJSONPointer(doc interface{}, pointer string, value interface{}) error {
+	if !strings.HasPrefix(pointer, "/") {
+		return fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	parent := doc
+	for i, token := range tokens {
+		last := i == len(tokens)-1
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return fmt.Errorf("JSON pointer %q does not resolve: no member %q", pointer, token)
+			}
+			if last {
+				node[token] = value
+				return nil
+			}
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) || (token != "0" && strings.HasPrefix(token, "0")) {
+				return fmt.Errorf("JSON pointer %q does not resolve: no element %q in a list of %d", pointer, token, len(node))
+			}
+			if last {
+				node[index] = value
+				return nil
+			}
+			parent = node[index]
+		default:
+			return fmt.Errorf("JSON pointer %q does not resolve: %q is not in an object or list", pointer, token)
+		}
+	}
+	return nil
+}